@@ -9,7 +9,10 @@ import (
 
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey    = contextKey("user")
+	encoderContextKey = contextKey("encoder")
+)
 
 func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
 	ctx := context.WithValue(r.Context(), userContextKey, user)
@@ -24,3 +27,15 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 
 	return user
 }
+
+func (app *application) contextSetEncoder(r *http.Request, enc responseEncoder) *http.Request {
+	ctx := context.WithValue(r.Context(), encoderContextKey, enc)
+	return r.WithContext(ctx)
+}
+
+// contextGetEncoder returns the encoder picked by negotiateResponse, which only
+// picks one for the requests that change data.
+func (app *application) contextGetEncoder(r *http.Request) (responseEncoder, bool) {
+	enc, ok := r.Context().Value(encoderContextKey).(responseEncoder)
+	return enc, ok
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/djudju12/greenlight/internal/data"
+	"github.com/djudju12/greenlight/internal/storage"
+	"github.com/djudju12/greenlight/internal/validator"
+)
+
+type MergeMovieRequest struct {
+	Into int64 `json:"into"`
+}
+
+// mergeMovieHandler folds the movie in the URL into another one, usually a
+// duplicate of it, and deletes it. The data that belongs to the merged movie,
+// like its reviews, credits and list entries, is moved to the other movie,
+// which keeps its own fields and whatever it already has.
+func (app *application) mergeMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input MergeMovieRequest
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Into > 0, "into", "must be a positive integer")
+	v.Check(input.Into != id, "into", "must not be the merged movie")
+
+	view := app.readMovieView(r, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// the poster only moves when the other movie has none, and its files are
+	// copied before the records are merged, so that the poster is never served
+	// without them
+	moved, err := app.copyMergedPoster(id, input.Into)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Movies.Merge(id, input.Into)
+	if err != nil {
+		if moved != nil {
+			app.deletePosterFiles(moved)
+		}
+
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	app.deleteMoviePosters(id)
+
+	movie, err := app.models.Movies.Get(input.Into)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	rendered, err := view.renderOne(app, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"movie": rendered, "merged": id}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// copyMergedPoster copies the poster files of the source movie to the target
+// one when the merge moves the poster, that is when the source has a poster
+// and the target doesn't. It returns the poster the target gets, or nil when
+// the files were not copied.
+func (app *application) copyMergedPoster(sourceID, targetID int64) (*data.Poster, error) {
+	source, err := app.models.Movies.GetPoster(sourceID)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	// the target keeps its own poster
+	_, err = app.models.Movies.GetPoster(targetID)
+	switch {
+	case err == nil:
+		return nil, nil
+	case !errors.Is(err, data.ErrRecordNotFound):
+		return nil, err
+	}
+
+	target := *source
+	target.MovieID = targetID
+
+	for _, size := range posterSizes() {
+		err = app.copyPosterFile(source, &target, size)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &target, nil
+}
+
+// copyPosterFile copies the size of the poster to the other one, reading it
+// from where it was stored before the keys had the version if needed.
+func (app *application) copyPosterFile(from, to *data.Poster, size string) error {
+	err := app.copyFile(posterKey(from, size), posterKey(to, size))
+	if errors.Is(err, storage.ErrNotFound) {
+		err = app.copyFile(legacyPosterKey(from.MovieID, size), posterKey(to, size))
+	}
+
+	return err
+}
+
+func (app *application) copyFile(from, to string) error {
+	file, err := app.storage.Open(from)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	return app.storage.Put(to, file)
+}
+
+// duplicateIDs lists the IDs of the movies for the error messages.
+func duplicateIDs(movies []*data.Movie) string {
+	ids := make([]string, len(movies))
+	for i, movie := range movies {
+		ids[i] = fmt.Sprintf("id %d", movie.ID)
+	}
+
+	return strings.Join(ids, ", ")
+}
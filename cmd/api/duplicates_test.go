@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/djudju12/greenlight/internal/data"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/djudju12/greenlight/internal/storage"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCreateMovieHandlerDuplicates(t *testing.T) {
+	duplicate := &data.Movie{ID: 3, Title: "The Shawshank Redemption", Year: 1994, Runtime: 142, Genres: []string{"drama"}}
+
+	testCases := []struct {
+		name          string
+		url           string
+		buildStubs    func(t *testing.T, mockMovies *mockdb.MockMovieQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test Create Movie Handler - 422 DUPLICATE",
+			url:  "/v1/movies",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().
+					FindDuplicates(gomock.Any()).
+					DoAndReturn(func(movie *data.Movie) ([]*data.Movie, error) {
+						require.Equal(t, "shawshank redemption", movie.Title)
+						require.Equal(t, int32(1994), movie.Year)
+						return []*data.Movie{duplicate}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r)["title"], "id 3")
+			},
+		},
+		{
+			name: "Test Create Movie Handler - 201 CREATED ALLOWING DUPLICATES",
+			url:  "/v1/movies?allow_duplicates=true",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().FindDuplicates(gomock.Any()).Return([]*data.Movie{duplicate}, nil)
+				mockMovies.EXPECT().
+					Insert(gomock.Any()).
+					DoAndReturn(func(movie *data.Movie) error {
+						movie.ID = 4
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, r.Code)
+
+				var envelope struct {
+					Movie      data.Movie    `json:"movie"`
+					Duplicates []*data.Movie `json:"duplicates"`
+				}
+				err := json.NewDecoder(r.Body).Decode(&envelope)
+				require.NoError(t, err)
+
+				require.Equal(t, int64(4), envelope.Movie.ID)
+				require.Len(t, envelope.Duplicates, 1)
+				require.Equal(t, duplicate.ID, envelope.Duplicates[0].ID)
+			},
+		},
+		{
+			name: "Test Create Movie Handler - 422 INVALID ALLOW DUPLICATES",
+			url:  "/v1/movies?allow_duplicates=maybe",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "allow_duplicates")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, tc.url)
+
+			mockMovies := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+			tc.buildStubs(t, mockMovies)
+
+			body, err := toReader(map[string]any{
+				"title":   "shawshank redemption",
+				"year":    1994,
+				"runtime": "142 mins",
+				"genres":  []string{"drama"},
+			})
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, test.url, body)
+
+			// when
+			test.app.createMovieHandler(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestMergeMovieHandler(t *testing.T) {
+	target := &data.Movie{ID: 3, Title: "The Shawshank Redemption", Year: 1994, Runtime: 142, Genres: []string{"drama"}}
+
+	sourcePoster := &data.Poster{MovieID: 7, Checksum: "0123456789abcdef0123456789abcdef"}
+	movedPoster := &data.Poster{MovieID: 3, Checksum: sourcePoster.Checksum}
+
+	testCases := []struct {
+		name          string
+		body          map[string]any
+		buildStubs    func(t *testing.T, mockMovies *mockdb.MockMovieQuerier)
+		checkResponse func(t *testing.T, test test, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test Merge Movie Handler - 200 OK MOVING THE POSTER",
+			body: map[string]any{"into": 3},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				gomock.InOrder(
+					mockMovies.EXPECT().GetPoster(int64(7)).Return(sourcePoster, nil),
+					mockMovies.EXPECT().GetPoster(int64(3)).Return(nil, data.ErrRecordNotFound),
+					mockMovies.EXPECT().Merge(int64(7), int64(3)).Return(nil),
+					mockMovies.EXPECT().Get(int64(3)).Return(target, nil),
+				)
+			},
+			checkResponse: func(t *testing.T, test test, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, target.Title, requireMovieTitle(t, r))
+
+				for _, size := range posterSizes() {
+					_, err := test.app.storage.Open(posterKey(sourcePoster, size))
+					require.ErrorIs(t, err, storage.ErrNotFound)
+
+					f, err := test.app.storage.Open(posterKey(movedPoster, size))
+					require.NoError(t, err)
+
+					contents, err := io.ReadAll(f)
+					require.NoError(t, err)
+					require.Equal(t, size+" poster of 7", string(contents))
+					require.NoError(t, f.Close())
+				}
+			},
+		},
+		{
+			name: "Test Merge Movie Handler - 200 OK KEEPING THE POSTER OF THE TARGET",
+			body: map[string]any{"into": 3},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().GetPoster(int64(7)).Return(sourcePoster, nil)
+				mockMovies.EXPECT().GetPoster(int64(3)).Return(&data.Poster{MovieID: 3, Checksum: "fedcba9876543210fedcba9876543210"}, nil)
+				mockMovies.EXPECT().Merge(int64(7), int64(3)).Return(nil)
+				mockMovies.EXPECT().Get(int64(3)).Return(target, nil)
+			},
+			checkResponse: func(t *testing.T, test test, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				_, err := test.app.storage.Open(posterKey(movedPoster, posterOriginal))
+				require.ErrorIs(t, err, storage.ErrNotFound)
+			},
+		},
+		{
+			name: "Test Merge Movie Handler - 404 MOVIE NOT FOUND",
+			body: map[string]any{"into": 3},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().GetPoster(int64(7)).Return(sourcePoster, nil)
+				mockMovies.EXPECT().GetPoster(int64(3)).Return(nil, data.ErrRecordNotFound)
+				mockMovies.EXPECT().Merge(int64(7), int64(3)).Return(data.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, test test, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, r.Code)
+
+				// the copied files are removed, and the poster of the merged movie stays
+				_, err := test.app.storage.Open(posterKey(movedPoster, posterOriginal))
+				require.ErrorIs(t, err, storage.ErrNotFound)
+
+				f, err := test.app.storage.Open(posterKey(sourcePoster, posterOriginal))
+				require.NoError(t, err)
+				require.NoError(t, f.Close())
+			},
+		},
+		{
+			name: "Test Merge Movie Handler - 422 INTO ITSELF",
+			body: map[string]any{"into": 7},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, test test, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "into")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, "/v1/movies/7/merge")
+
+			for _, size := range posterSizes() {
+				err := test.app.storage.Put(posterKey(sourcePoster, size), bytes.NewReader([]byte(size+" poster of 7")))
+				require.NoError(t, err)
+			}
+
+			mockMovies := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+			tc.buildStubs(t, mockMovies)
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodPost, "/v1/movies/:id/merge", test.app.mergeMovieHandler)
+
+			body, err := toReader(tc.body)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, test.url, body)
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test, test.recorder)
+			test.close()
+		})
+	}
+}
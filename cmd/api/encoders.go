@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/djudju12/greenlight/internal/msgpack"
+)
+
+const (
+	mediaTypeJSON    = "application/json"
+	mediaTypeMsgpack = "application/msgpack"
+
+	// the media type used for MessagePack before it was registered
+	mediaTypeXMsgpack = "application/x-msgpack"
+)
+
+// responseEncoder writes response bodies in a media type. canEncode reports
+// whether a response can be written in it, when not every response can.
+type responseEncoder struct {
+	mediaType string
+	encode    func(w io.Writer, r *http.Request, data envelope) error
+	canEncode func(data envelope) bool
+}
+
+// responseEncoders are the formats the responses can be sent in, picked by the
+// Accept header of the request. The first one is used when the client accepts
+// any of them equally.
+var responseEncoders = []responseEncoder{
+	{mediaType: mediaTypeJSON, encode: encodeJSON},
+	{mediaType: mediaTypeMsgpack, encode: encodeMsgpack},
+	{mediaType: mediaTypeXMsgpack, encode: encodeMsgpack},
+	{mediaType: mediaTypeCSV, encode: encodeCSV, canEncode: hasCSVTable},
+}
+
+var jsonEncoder = responseEncoders[0]
+
+// writeResponse sends data in the format negotiated with the Accept header of
+// the request, or a 406 Not Acceptable response if none of the formats that
+// data can be sent in is accepted. The requests that change data already had
+// their format picked by negotiateResponse, before the changes were made.
+func (app *application) writeResponse(
+	w http.ResponseWriter, r *http.Request, status int, data envelope, headers http.Header) error {
+	enc, ok := app.contextGetEncoder(r)
+	if !ok {
+		enc, ok = app.responseEncoder(r, data)
+	}
+
+	if !ok {
+		app.notAcceptableResponse(w, r, offeredMediaTypes(data)...)
+		return nil
+	}
+
+	return writeEncoded(w, r, enc, status, data, headers)
+}
+
+func (app *application) responseEncoder(r *http.Request, data envelope) (responseEncoder, bool) {
+	mediaType := app.negotiate(r, offeredMediaTypes(data)...)
+
+	for _, enc := range responseEncoders {
+		if enc.mediaType == mediaType {
+			return enc, true
+		}
+	}
+
+	return responseEncoder{}, false
+}
+
+func offeredMediaTypes(data envelope) []string {
+	var offers []string
+	for _, enc := range responseEncoders {
+		if enc.canEncode == nil || enc.canEncode(data) {
+			offers = append(offers, enc.mediaType)
+		}
+	}
+
+	return offers
+}
+
+// writeEncoded encodes the whole body before anything is sent, so that an
+// encoding error can still be answered with an error response.
+func writeEncoded(
+	w http.ResponseWriter, r *http.Request, enc responseEncoder, status int, data envelope, headers http.Header) error {
+	var body bytes.Buffer
+
+	err := enc.encode(&body, r, data)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range headers {
+		w.Header()[key] = value
+	}
+
+	w.Header().Add("Vary", "Accept")
+	w.Header().Set("Content-Type", enc.mediaType)
+	w.WriteHeader(status)
+
+	_, err = w.Write(body.Bytes())
+	return err
+}
+
+// encodeJSON writes compact JSON, or indented JSON when the request has the
+// pretty query parameter.
+func encodeJSON(w io.Writer, r *http.Request, data envelope) error {
+	var (
+		js  []byte
+		err error
+	)
+
+	if wantsPretty(r) {
+		js, err = json.MarshalIndent(data, "", "\t")
+	} else {
+		js, err = json.Marshal(data)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	js = append(js, '\n')
+
+	_, err = w.Write(js)
+	return err
+}
+
+func wantsPretty(r *http.Request) bool {
+	values, ok := r.URL.Query()["pretty"]
+	if !ok {
+		return false
+	}
+
+	// a bare ?pretty counts as true
+	pretty, err := strconv.ParseBool(values[0])
+	return values[0] == "" || (err == nil && pretty)
+}
+
+func encodeMsgpack(w io.Writer, r *http.Request, data envelope) error {
+	mp, err := msgpack.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(mp)
+	return err
+}
+
+// csvTable is a list response that can also be sent as CSV, with a row for
+// each element. In the other formats it's sent as the plain list.
+type csvTable interface {
+	json.Marshaler
+	csvHeader() []string
+	csvRecords() ([][]string, error)
+}
+
+// hasCSVTable reports whether data holds a list that can be sent as CSV.
+func hasCSVTable(data envelope) bool {
+	_, ok := findCSVTable(data)
+	return ok
+}
+
+func findCSVTable(data envelope) (csvTable, bool) {
+	for _, value := range data {
+		if table, ok := value.(csvTable); ok {
+			return table, true
+		}
+	}
+
+	return nil, false
+}
+
+// encodeCSV writes only the list of the response, everything else in it, such
+// as the pagination metadata, is left out.
+func encodeCSV(w io.Writer, r *http.Request, data envelope) error {
+	table, _ := findCSVTable(data)
+
+	records, err := table.csvRecords()
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+
+	err = cw.Write(table.csvHeader())
+	if err != nil {
+		return err
+	}
+
+	err = cw.WriteAll(records)
+	if err != nil {
+		return err
+	}
+
+	return cw.Error()
+}
+
+// requestMediaType returns the media type of the request body, without its
+// parameters.
+func requestMediaType(r *http.Request) string {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+
+	return mediaType
+}
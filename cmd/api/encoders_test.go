@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/djudju12/greenlight/internal/data"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/djudju12/greenlight/internal/msgpack"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestListMoviesHandlerEncoding(t *testing.T) {
+	movies := []*data.Movie{randomMovie(), randomMovie()}
+	movies[0].Genres = []string{"drama", "romance"}
+
+	testCases := []struct {
+		name          string
+		query         string
+		accept        string
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test List Movies Handler - 200 OK COMPACT JSON",
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, mediaTypeJSON, r.Header().Get("Content-Type"))
+				require.Contains(t, r.Header().Values("Vary"), "Accept")
+				require.NotContains(t, r.Body.String(), "\n\t")
+			},
+		},
+		{
+			name:  "Test List Movies Handler - 200 OK PRETTY JSON",
+			query: "?pretty",
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Contains(t, r.Body.String(), "\n\t")
+			},
+		},
+		{
+			name:   "Test List Movies Handler - 200 OK MSGPACK",
+			accept: "application/msgpack",
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, mediaTypeMsgpack, r.Header().Get("Content-Type"))
+
+				js, err := msgpack.ToJSON(r.Body.Bytes())
+				require.NoError(t, err)
+
+				var envelope struct {
+					Movies []*data.Movie `json:"movies"`
+				}
+				err = json.Unmarshal(js, &envelope)
+				require.NoError(t, err)
+				require.Len(t, envelope.Movies, len(movies))
+				for i, movie := range envelope.Movies {
+					require.Equal(t, movies[i].ID, movie.ID)
+					require.Equal(t, movies[i].Title, movie.Title)
+					require.Equal(t, movies[i].Runtime, movie.Runtime)
+					require.Equal(t, movies[i].Genres, movie.Genres)
+				}
+			},
+		},
+		{
+			name:   "Test List Movies Handler - 200 OK CSV",
+			query:  "?fields=title,runtime,genres",
+			accept: "text/csv, application/json;q=0.5",
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, mediaTypeCSV, r.Header().Get("Content-Type"))
+
+				records, err := csv.NewReader(r.Body).ReadAll()
+				require.NoError(t, err)
+
+				require.Equal(t, [][]string{
+					{"title", "runtime", "genres"},
+					{movies[0].Title, fmt.Sprint(movies[0].Runtime), "drama|romance"},
+					{movies[1].Title, fmt.Sprint(movies[1].Runtime), strings.Join(movies[1].Genres, "|")},
+				}, records)
+			},
+		},
+		{
+			name:   "Test List Movies Handler - 406 NOT ACCEPTABLE",
+			accept: "application/xml",
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotAcceptable, r.Code)
+				require.Equal(t, mediaTypeJSON, r.Header().Get("Content-Type"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, "/v1/movies"+tc.query)
+
+			mockMovies, ok := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+			require.True(t, ok)
+
+			mockMovies.EXPECT().
+				GetAll(gomock.Any(), gomock.Any()).
+				Return(movies, data.Metadata{}, nil).
+				MaxTimes(1)
+
+			request := httptest.NewRequest(http.MethodGet, test.url, nil)
+			if tc.accept != "" {
+				request.Header.Set("Accept", tc.accept)
+			}
+
+			// when
+			test.app.listMoviesHandles(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestShowMovieHandlerNotAcceptableCSV(t *testing.T) {
+	// given
+	movie := randomMovie()
+	test := newMovieTest(t, fmt.Sprintf("/v1/movies/%d", movie.ID))
+
+	router := httprouter.New()
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id", test.app.showMovieHandler)
+
+	mockMovies, ok := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+	require.True(t, ok)
+
+	mockMovies.EXPECT().GetFields(movie.ID, nil).Return(movie, nil)
+
+	request := httptest.NewRequest(http.MethodGet, test.url, nil)
+	request.Header.Set("Accept", "text/csv")
+
+	// when
+	router.ServeHTTP(test.recorder, request)
+
+	// then
+	require.Equal(t, http.StatusNotAcceptable, test.recorder.Code)
+	require.Equal(t, mediaTypeJSON, test.recorder.Header().Get("Content-Type"))
+	require.NotContains(t, test.recorder.Body.String(), mediaTypeCSV)
+
+	test.close()
+}
+
+func TestCreateMovieHandlerNotAcceptable(t *testing.T) {
+	movie := randomMovie()
+
+	testCases := []struct {
+		name          string
+		accept        string
+		buildStubs    func(t *testing.T, mockMovies *mockdb.MockMovieQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Test Create Movie Handler - 406 NOT ACCEPTABLE BEFORE INSERTING",
+			accept: "text/csv",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().Insert(gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotAcceptable, r.Code)
+				require.Equal(t, mediaTypeJSON, r.Header().Get("Content-Type"))
+				require.NotContains(t, r.Body.String(), mediaTypeCSV)
+			},
+		},
+		{
+			name:   "Test Create Movie Handler - 201 CREATED MSGPACK",
+			accept: "text/csv, application/msgpack;q=0.5",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().FindDuplicates(gomock.Any()).Return(nil, nil)
+				mockMovies.EXPECT().Insert(gomock.Any()).Return(nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, r.Code)
+				require.Equal(t, mediaTypeMsgpack, r.Header().Get("Content-Type"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, "/v1/movies")
+
+			mockMovies, ok := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+			require.True(t, ok)
+			tc.buildStubs(t, mockMovies)
+
+			body, err := toReader(CreateMovieRequest{
+				Title:   movie.Title,
+				Year:    movie.Year,
+				Runtime: movie.Runtime,
+				Genres:  movie.Genres,
+			})
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, test.url, body)
+			request.Header.Set("Accept", tc.accept)
+
+			// when
+			test.app.negotiateResponse(http.HandlerFunc(test.app.createMovieHandler)).ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestReadJSONMsgpack(t *testing.T) {
+	js := `{"title": "Casablanca", "year": 1942, "runtime": "102 mins", "genres": ["drama"]}`
+	mp, err := msgpack.FromJSON([]byte(js))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		contentType string
+		body        []byte
+		checkResult func(t *testing.T, input CreateMovieRequest, err error)
+	}{
+		{
+			name:        "Test Read JSON - MSGPACK BODY",
+			contentType: mediaTypeMsgpack,
+			body:        mp,
+			checkResult: func(t *testing.T, input CreateMovieRequest, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "Casablanca", input.Title)
+				require.Equal(t, data.Runtime(102), input.Runtime)
+			},
+		},
+		{
+			name:        "Test Read JSON - X-MSGPACK BODY",
+			contentType: mediaTypeXMsgpack,
+			body:        mp,
+			checkResult: func(t *testing.T, input CreateMovieRequest, err error) {
+				require.NoError(t, err)
+				require.Equal(t, int32(1942), input.Year)
+			},
+		},
+		{
+			name:        "Test Read JSON - BADLY-FORMED MSGPACK",
+			contentType: mediaTypeMsgpack,
+			body:        mp[:len(mp)-1],
+			checkResult: func(t *testing.T, input CreateMovieRequest, err error) {
+				require.EqualError(t, err, "body contains badly-formed MessagePack")
+			},
+		},
+		{
+			name:        "Test Read JSON - EMPTY MSGPACK",
+			contentType: mediaTypeMsgpack,
+			checkResult: func(t *testing.T, input CreateMovieRequest, err error) {
+				require.EqualError(t, err, "body must not be empty")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			app := &application{}
+			request := httptest.NewRequest(http.MethodPost, "/v1/movies", bytes.NewReader(tc.body))
+			request.Header.Set("Content-Type", tc.contentType)
+
+			// when
+			var input CreateMovieRequest
+			err := app.readJSON(httptest.NewRecorder(), request, &input)
+
+			// then
+			tc.checkResult(t, input, err)
+		})
+	}
+}
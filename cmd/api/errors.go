@@ -3,10 +3,9 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"strings"
 )
 
-
-
 func (app *application) logError(r *http.Request, err error) {
 	app.logger.PrintError(err, map[string]string{
 		"request_method": r.Method,
@@ -21,7 +20,15 @@ func (app *application) errorResponse(
 	message any,
 ) {
 	envlp := envelope{"error": message}
-	err := app.writeJSON(w, status, envlp, nil)
+
+	// the error is still sent, as JSON, when the client accepts none of the
+	// formats, since the status tells what went wrong anyway
+	enc, ok := app.responseEncoder(r, envlp)
+	if !ok {
+		enc = jsonEncoder
+	}
+
+	err := writeEncoded(w, r, enc, status, envlp, nil)
 
 	if err != nil {
 		app.logError(r, err)
@@ -94,3 +101,35 @@ func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Requ
 	message := "your user account doesn't have the necessary permissions to access this resource"
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
+
+func (app *application) unsupportedMediaTypeResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	supported ...string,
+) {
+	message := fmt.Sprintf("the request content type must be one of: %s", strings.Join(supported, ", "))
+	app.errorResponse(w, r, http.StatusUnsupportedMediaType, message)
+}
+
+func (app *application) notAcceptableResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	supported ...string,
+) {
+	message := fmt.Sprintf("the resource can only be represented as: %s", strings.Join(supported, ", "))
+	app.errorResponse(w, r, http.StatusNotAcceptable, message)
+}
+
+func (app *application) idempotencyKeyMismatchResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the idempotency key was already used with a different request body"
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, message)
+}
+
+func (app *application) idempotencyKeyInProgressResponse(w http.ResponseWriter, r *http.Request) {
+	message := "a request with the same idempotency key is still being processed, please try again later"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (app *application) patchTestFailedResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.errorResponse(w, r, http.StatusConflict, err.Error())
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/data"
+	"github.com/lib/pq"
+)
+
+const (
+	mediaTypeEventStream = "text/event-stream"
+	lastEventIDHeader    = "Last-Event-ID"
+)
+
+// movieEventsBuffer is how many events a client can fall behind before it's
+// disconnected. It can then resume from the event log with Last-Event-ID.
+var movieEventsBuffer = 64
+
+// movieEventsReplayBatch is how many logged events are read at a time when a
+// client resumes the stream, or when the committed events are published.
+var movieEventsReplayBatch = 100
+
+// movieEventBroker fans out the movie events to the clients streaming them.
+type movieEventBroker struct {
+	mu          sync.Mutex
+	subscribers map[chan *data.MovieEvent]struct{}
+	closed      bool
+}
+
+func newMovieEventBroker() *movieEventBroker {
+	return &movieEventBroker{subscribers: make(map[chan *data.MovieEvent]struct{})}
+}
+
+// subscribe returns a channel with the events published from now on. The
+// channel is closed when the subscriber falls behind or the broker is closed.
+func (b *movieEventBroker) subscribe() chan *data.MovieEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	events := make(chan *data.MovieEvent, movieEventsBuffer)
+	if b.closed {
+		close(events)
+		return events
+	}
+
+	b.subscribers[events] = struct{}{}
+	return events
+}
+
+func (b *movieEventBroker) unsubscribe(events chan *data.MovieEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[events]; ok {
+		delete(b.subscribers, events)
+		close(events)
+	}
+}
+
+// publish never blocks: a subscriber that can't take the event is dropped.
+func (b *movieEventBroker) publish(event *data.MovieEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for events := range b.subscribers {
+		select {
+		case events <- event:
+		default:
+			delete(b.subscribers, events)
+			close(events)
+		}
+	}
+}
+
+// close ends every stream, so that they don't hold up the server shutdown.
+func (b *movieEventBroker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for events := range b.subscribers {
+		delete(b.subscribers, events)
+		close(events)
+	}
+}
+
+// listenMovieEvents publishes the events the database notifies. Every instance
+// of the API listens, so each one sees the changes made through the others.
+// The notifications only tell that events were committed, and the events are
+// then read back by position, in the order they were committed.
+func (app *application) listenMovieEvents(listener *pq.Listener) {
+	// the events committed before the API started are not published
+	position := app.publishMovieEvents(-1)
+
+	for {
+		select {
+		case _, ok := <-listener.NotificationChannel():
+			if !ok {
+				return
+			}
+
+			// a nil notification means that the connection was lost and
+			// established again, and the events committed meanwhile are read
+			// back the same way
+			position = app.publishMovieEvents(position)
+
+		case <-time.After(90 * time.Second):
+			// the connection is checked when nothing arrives for a while, so a
+			// dead one is noticed and the listener reconnects
+			go listener.Ping()
+		}
+	}
+}
+
+// publishMovieEvents sequences the committed events, publishes the ones after
+// the position and returns the position of the last one published. With a
+// negative position nothing is published, and only the last position is found.
+func (app *application) publishMovieEvents(position int64) int64 {
+	last, err := app.models.MovieEvents.Sequence()
+	if err != nil {
+		app.logger.PrintError(err, nil)
+		return position
+	}
+
+	if position < 0 {
+		return last
+	}
+
+	for position < last {
+		events, err := app.models.MovieEvents.GetAfter(position, movieEventsReplayBatch)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			return position
+		}
+
+		for _, event := range events {
+			app.events.publish(event)
+			position = event.Position
+		}
+
+		if len(events) < movieEventsReplayBatch {
+			break
+		}
+	}
+
+	return position
+}
+
+// purgeMovieEvents deletes the events older than the retention every interval.
+func (app *application) purgeMovieEvents(retention, interval time.Duration) {
+	for {
+		time.Sleep(interval)
+
+		deleted, err := app.models.MovieEvents.DeleteOlderThan(time.Now().Add(-retention))
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			continue
+		}
+
+		if deleted > 0 {
+			app.logger.PrintInfo("old movie events deleted", map[string]string{
+				"deleted": strconv.FormatInt(deleted, 10),
+			})
+		}
+	}
+}
+
+// movieEventsHandler streams the movie events as Server-Sent Events, with
+// their position as the event ID. A client that sends the Last-Event-ID header
+// first gets the events committed after that one. While there are no events, a comment is sent every heartbeat interval to
+// keep the connection open.
+func (app *application) movieEventsHandler(w http.ResponseWriter, r *http.Request) {
+	var position int64
+
+	if s := r.Header.Get(lastEventIDHeader); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || id < 0 {
+			app.badRequestResponse(w, r, fmt.Errorf("the %s header must be an event ID", lastEventIDHeader))
+			return
+		}
+
+		position = id
+	}
+
+	// subscribe before reading the log, so the events logged in between are
+	// not missed. The ones that are both read and published are skipped, as
+	// the events are published in the order of their positions.
+	events := app.events.subscribe()
+	defer app.events.unsubscribe(events)
+
+	w.Header().Set("Content-Type", mediaTypeEventStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// the stream is open for longer than the server's WriteTimeout, so the
+	// deadline is moved forward before every write
+	rc := http.NewResponseController(w)
+	send := func(write func(w io.Writer) error) error {
+		_ = rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+		if err := write(w); err != nil {
+			return err
+		}
+
+		return rc.Flush()
+	}
+
+	err := send(writeSSEComment("connected"))
+	if err != nil {
+		return
+	}
+
+	for resume := position > 0; resume; {
+		logged, err := app.models.MovieEvents.GetAfter(position, movieEventsReplayBatch)
+		if err != nil {
+			// the status line was already sent, so the stream is just ended
+			// and the client can retry from the last event it got
+			app.logError(r, err)
+			return
+		}
+
+		for _, event := range logged {
+			if err := send(writeSSEEvent(event)); err != nil {
+				return
+			}
+
+			position = event.Position
+		}
+
+		resume = len(logged) == movieEventsReplayBatch
+	}
+
+	replayed := position
+
+	heartbeat := time.NewTicker(app.config.events.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			if event.Position <= replayed {
+				continue
+			}
+
+			err = send(writeSSEEvent(event))
+
+		case <-heartbeat.C:
+			err = send(writeSSEComment("heartbeat"))
+		}
+
+		if err != nil {
+			// the client is gone
+			return
+		}
+	}
+}
+
+func writeSSEEvent(event *data.MovieEvent) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		js, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Position, event.Type, js)
+		return err
+	}
+}
+
+func writeSSEComment(comment string) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, ": %s\n\n", comment)
+		return err
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/data"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func randomMovieEvent(id int64, eventType string) *data.MovieEvent {
+	return &data.MovieEvent{
+		ID:        id,
+		Position:  id,
+		Type:      eventType,
+		Movie:     randomMovie(),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func TestMovieEventsHandler(t *testing.T) {
+	logged := []*data.MovieEvent{randomMovieEvent(4, data.MovieUpdated), randomMovieEvent(5, data.MovieDeleted)}
+	live := randomMovieEvent(6, data.MovieCreated)
+
+	testCases := []struct {
+		name          string
+		lastEventID   string
+		heartbeat     time.Duration
+		buildStubs    func(t *testing.T, mockEvents *mockdb.MockMovieEventQuerier)
+		publish       []*data.MovieEvent
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test Movie Events Handler - 200 OK LIVE EVENTS",
+			buildStubs: func(t *testing.T, mockEvents *mockdb.MockMovieEventQuerier) {
+				t.Log("no stubs for this test")
+			},
+			publish: []*data.MovieEvent{live},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, mediaTypeEventStream, r.Header().Get("Content-Type"))
+				require.Equal(t, []int64{live.ID}, sentEventIDs(t, r.Body.String()))
+				require.Contains(t, r.Body.String(), "event: created\n")
+			},
+		},
+		{
+			name:        "Test Movie Events Handler - 200 OK RESUME FROM LAST EVENT ID",
+			lastEventID: "3",
+			buildStubs: func(t *testing.T, mockEvents *mockdb.MockMovieEventQuerier) {
+				mockEvents.EXPECT().
+					GetAfter(int64(3), movieEventsReplayBatch).
+					Return(logged, nil)
+			},
+			// the last logged event is also published, and must not be sent twice
+			publish: []*data.MovieEvent{logged[1], live},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, []int64{4, 5, 6}, sentEventIDs(t, r.Body.String()))
+			},
+		},
+		{
+			name:      "Test Movie Events Handler - 200 OK HEARTBEAT",
+			heartbeat: time.Millisecond,
+			buildStubs: func(t *testing.T, mockEvents *mockdb.MockMovieEventQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Contains(t, r.Body.String(), ": heartbeat\n\n")
+			},
+		},
+		{
+			name:        "Test Movie Events Handler - 400 INVALID LAST EVENT ID",
+			lastEventID: "abc",
+			buildStubs: func(t *testing.T, mockEvents *mockdb.MockMovieEventQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, r.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, "/v1/movies/events")
+
+			mockEvents := mockdb.NewMockMovieEventQuerier(gomock.NewController(t))
+			test.app.models.MovieEvents = mockEvents
+			tc.buildStubs(t, mockEvents)
+
+			test.app.events = newMovieEventBroker()
+			test.app.config.events.heartbeat = time.Hour
+			if tc.heartbeat != 0 {
+				test.app.config.events.heartbeat = tc.heartbeat
+			}
+
+			request := httptest.NewRequest(http.MethodGet, test.url, nil)
+			if tc.lastEventID != "" {
+				request.Header.Set(lastEventIDHeader, tc.lastEventID)
+			}
+
+			// when
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				test.app.movieEventsHandler(test.recorder, request)
+			}()
+
+			if tc.lastEventID != "abc" {
+				waitForSubscriber(t, test.app.events)
+			}
+
+			for _, event := range tc.publish {
+				test.app.events.publish(event)
+			}
+
+			if tc.heartbeat != 0 {
+				time.Sleep(20 * tc.heartbeat)
+			}
+
+			// closing the broker ends the stream once the published events are sent
+			test.app.events.close()
+			<-done
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestPublishMovieEvents(t *testing.T) {
+	committed := []*data.MovieEvent{randomMovieEvent(7, data.MovieCreated), randomMovieEvent(6, data.MovieUpdated)}
+	committed[0].Position = 4
+	committed[1].Position = 5
+
+	testCases := []struct {
+		name       string
+		position   int64
+		buildStubs func(t *testing.T, mockEvents *mockdb.MockMovieEventQuerier)
+		want       int64
+		published  []*data.MovieEvent
+	}{
+		{
+			name:     "Test Publish Movie Events - IN COMMIT ORDER",
+			position: 3,
+			buildStubs: func(t *testing.T, mockEvents *mockdb.MockMovieEventQuerier) {
+				mockEvents.EXPECT().Sequence().Return(int64(5), nil)
+				mockEvents.EXPECT().
+					GetAfter(int64(3), movieEventsReplayBatch).
+					Return(committed, nil)
+			},
+			want:      5,
+			published: committed,
+		},
+		{
+			name:     "Test Publish Movie Events - NOTHING NEW",
+			position: 5,
+			buildStubs: func(t *testing.T, mockEvents *mockdb.MockMovieEventQuerier) {
+				mockEvents.EXPECT().Sequence().Return(int64(5), nil)
+			},
+			want: 5,
+		},
+		{
+			name:     "Test Publish Movie Events - UNKNOWN POSITION",
+			position: -1,
+			buildStubs: func(t *testing.T, mockEvents *mockdb.MockMovieEventQuerier) {
+				mockEvents.EXPECT().Sequence().Return(int64(5), nil)
+			},
+			want: 5,
+		},
+		{
+			name:     "Test Publish Movie Events - SEQUENCE FAILS",
+			position: 3,
+			buildStubs: func(t *testing.T, mockEvents *mockdb.MockMovieEventQuerier) {
+				mockEvents.EXPECT().Sequence().Return(int64(0), sql.ErrConnDone)
+			},
+			want: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, "/v1/movies/events")
+
+			mockEvents := mockdb.NewMockMovieEventQuerier(gomock.NewController(t))
+			test.app.models.MovieEvents = mockEvents
+			tc.buildStubs(t, mockEvents)
+
+			test.app.events = newMovieEventBroker()
+			events := test.app.events.subscribe()
+
+			// when
+			position := test.app.publishMovieEvents(tc.position)
+			test.app.events.close()
+
+			// then
+			require.Equal(t, tc.want, position)
+
+			var published []*data.MovieEvent
+			for event := range events {
+				published = append(published, event)
+			}
+
+			require.Equal(t, tc.published, published)
+			test.close()
+		})
+	}
+}
+
+func TestMovieEventBrokerDropsSlowSubscribers(t *testing.T) {
+	// given
+	broker := newMovieEventBroker()
+	events := broker.subscribe()
+
+	// when
+	for i := 0; i <= movieEventsBuffer; i++ {
+		broker.publish(randomMovieEvent(int64(i+1), data.MovieUpdated))
+	}
+
+	// then
+	received := 0
+	for range events {
+		received++
+	}
+
+	require.Equal(t, movieEventsBuffer, received)
+
+	// unsubscribing a dropped subscriber does nothing
+	broker.unsubscribe(events)
+}
+
+func waitForSubscriber(t *testing.T, broker *movieEventBroker) {
+	require.Eventually(t, func() bool {
+		broker.mu.Lock()
+		defer broker.mu.Unlock()
+
+		return len(broker.subscribers) > 0
+	}, time.Second, time.Millisecond)
+}
+
+func sentEventIDs(t *testing.T, stream string) []int64 {
+	var ids []int64
+	for _, line := range strings.Split(stream, "\n") {
+		if !strings.HasPrefix(line, "id: ") {
+			continue
+		}
+
+		var id int64
+		_, err := fmt.Sscanf(line, "id: %d", &id)
+		require.NoError(t, err)
+
+		ids = append(ids, id)
+	}
+
+	return ids
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/djudju12/greenlight/internal/data"
+	"github.com/djudju12/greenlight/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+type SetMovieExternalIDRequest struct {
+	ID string `json:"id"`
+}
+
+// setMovieExternalIDHandler sets the ID of the movie in the catalog of the
+// provider of the URL, replacing the one it had.
+func (app *application) setMovieExternalIDHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input SetMovieExternalIDRequest
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	externalID := &data.ExternalID{
+		MovieID:  id,
+		Provider: httprouter.ParamsFromContext(r.Context()).ByName("provider"),
+		ID:       input.ID,
+	}
+
+	v := validator.New()
+	if data.ValidateExternalID(v, externalID); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Movies.SetExternalID(externalID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateExternalID):
+			v.AddError("id", "is already the ID of another movie")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"external_id": externalID}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteMovieExternalIDHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	provider := httprouter.ParamsFromContext(r.Context()).ByName("provider")
+
+	err = app.models.Movies.DeleteExternalID(id, provider)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"message": "movie external id successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
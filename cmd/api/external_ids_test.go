@@ -0,0 +1,178 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/djudju12/greenlight/internal/data"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSetMovieExternalIDHandler(t *testing.T) {
+	testCases := []struct {
+		name          string
+		url           string
+		body          map[string]any
+		buildStubs    func(t *testing.T, mockMovies *mockdb.MockMovieQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test Set Movie External ID Handler - 200 OK",
+			url:  "/v1/movies/7/external_ids/imdb",
+			body: map[string]any{"id": "tt0111161"},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().
+					SetExternalID(&data.ExternalID{MovieID: 7, Provider: data.ProviderIMDb, ID: "tt0111161"}).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.JSONEq(t, `{"external_id": {"provider": "imdb", "id": "tt0111161"}}`, r.Body.String())
+			},
+		},
+		{
+			name: "Test Set Movie External ID Handler - 404 MOVIE NOT FOUND",
+			url:  "/v1/movies/7/external_ids/tmdb",
+			body: map[string]any{"id": "278"},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().SetExternalID(gomock.Any()).Return(data.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, r.Code)
+			},
+		},
+		{
+			name: "Test Set Movie External ID Handler - 422 ID OF ANOTHER MOVIE",
+			url:  "/v1/movies/7/external_ids/tmdb",
+			body: map[string]any{"id": "278"},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().SetExternalID(gomock.Any()).Return(data.ErrDuplicateExternalID)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "id")
+			},
+		},
+		{
+			name: "Test Set Movie External ID Handler - 422 INVALID IMDB ID",
+			url:  "/v1/movies/7/external_ids/imdb",
+			body: map[string]any{"id": "0111161"},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "id")
+			},
+		},
+		{
+			name: "Test Set Movie External ID Handler - 422 UNKNOWN PROVIDER",
+			url:  "/v1/movies/7/external_ids/letterboxd",
+			body: map[string]any{"id": "the-shawshank-redemption"},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "provider")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, tc.url)
+
+			mockMovies := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+			tc.buildStubs(t, mockMovies)
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodPut, "/v1/movies/:id/external_ids/:provider", test.app.setMovieExternalIDHandler)
+
+			body, err := toReader(tc.body)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPut, test.url, body)
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestListMoviesHandlerExternalIDFilter(t *testing.T) {
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(t *testing.T, mockMovies *mockdb.MockMovieQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "Test List Movies Handler - 200 OK FILTER BY EXTERNAL ID",
+			query: "?external_id=imdb:tt0111161",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().
+					GetAll(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(mf data.MovieFilters, f data.Filters) ([]*data.Movie, data.Metadata, error) {
+						require.Equal(t, data.ProviderIMDb, mf.ExternalProvider)
+						require.Equal(t, "tt0111161", mf.ExternalID)
+						return []*data.Movie{}, data.Metadata{}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+			},
+		},
+		{
+			name:  "Test List Movies Handler - 422 EXTERNAL ID WITHOUT PROVIDER",
+			query: "?external_id=tt0111161",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "external_id")
+			},
+		},
+		{
+			name:  "Test List Movies Handler - 422 INVALID TMDB ID",
+			query: "?external_id=tmdb:tt0111161",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "external_id")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, "/v1/movies"+tc.query)
+
+			mockMovies, ok := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+			require.True(t, ok)
+			tc.buildStubs(t, mockMovies)
+
+			request := httptest.NewRequest(http.MethodGet, test.url, nil)
+
+			// when
+			test.app.listMoviesHandles(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
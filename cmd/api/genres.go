@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/djudju12/greenlight/internal/data"
+	"github.com/djudju12/greenlight/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (app *application) listGenresHandler(w http.ResponseWriter, r *http.Request) {
+	genres, err := app.models.Genres.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"genres": genres}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+type CreateGenreRequest struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+func (app *application) createGenreHandler(w http.ResponseWriter, r *http.Request) {
+	var input CreateGenreRequest
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	genre := &data.Genre{Name: input.Name, Aliases: input.Aliases}
+
+	v := validator.New()
+	if data.ValidateGenre(v, genre); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Genres.Insert(genre)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateGenre):
+			v.AddError("name", "the name or one of the aliases already stands for a genre")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusCreated, envelope{"genre": genre}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+type MergeGenreRequest struct {
+	Into string `json:"into"`
+}
+
+// mergeGenreHandler folds the genre in the URL into another one, like merging
+// 'science-fiction' into 'sci-fi'. The merged genre becomes an alias, so it
+// can still be used to write and filter movies.
+func (app *application) mergeGenreHandler(w http.ResponseWriter, r *http.Request) {
+	source := httprouter.ParamsFromContext(r.Context()).ByName("slug")
+
+	var input MergeGenreRequest
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Into != "", "into", "must be provided")
+	v.Check(input.Into != source, "into", "must not be the merged genre")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	moved, err := app.models.Genres.Merge(source, input.Into)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"merged": source, "into": input.Into, "movies": moved}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// resolveGenres returns the slugs of every genre of the movies, to be given to
+// data.NormalizeGenres.
+func resolveGenres(genres data.GenreQuerier, movies ...*data.Movie) (map[string]string, error) {
+	var names []string
+	for _, movie := range movies {
+		names = append(names, movie.Genres...)
+	}
+
+	if len(names) == 0 {
+		return map[string]string{}, nil
+	}
+
+	return genres.Resolve(names)
+}
+
+// normalizeGenres replaces the genres of the movie with their slugs. The genres
+// that are not in the catalog are added to v.
+func normalizeGenres(genres data.GenreQuerier, v *validator.Validator, movie *data.Movie) error {
+	slugs, err := resolveGenres(genres, movie)
+	if err != nil {
+		return err
+	}
+
+	data.NormalizeGenres(v, movie, slugs)
+	return nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/djudju12/greenlight/internal/data"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/djudju12/greenlight/internal/validator"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newGenreTest(t *testing.T, url string) (test, *mockdb.MockGenreQuerier) {
+	test := newMovieTest(t, url)
+
+	mockGenres := mockdb.NewMockGenreQuerier(gomock.NewController(t))
+	test.app.models.Genres = mockGenres
+
+	return test, mockGenres
+}
+
+func TestMergeGenreHandler(t *testing.T) {
+	testCases := []struct {
+		name          string
+		body          any
+		buildStubs    func(t *testing.T, mockGenres *mockdb.MockGenreQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test Merge Genre Handler - 200 OK",
+			body: map[string]any{"into": "sci-fi"},
+			buildStubs: func(t *testing.T, mockGenres *mockdb.MockGenreQuerier) {
+				mockGenres.EXPECT().
+					Merge("science-fiction", "sci-fi").
+					Return(int64(12), nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				var envelope struct {
+					Movies int64 `json:"movies"`
+				}
+				err := json.NewDecoder(r.Body).Decode(&envelope)
+				require.NoError(t, err)
+				require.Equal(t, int64(12), envelope.Movies)
+			},
+		},
+		{
+			name: "Test Merge Genre Handler - 404 UNKNOWN GENRE",
+			body: map[string]any{"into": "sci-fi"},
+			buildStubs: func(t *testing.T, mockGenres *mockdb.MockGenreQuerier) {
+				mockGenres.EXPECT().
+					Merge("science-fiction", "sci-fi").
+					Return(int64(0), data.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, r.Code)
+			},
+		},
+		{
+			name: "Test Merge Genre Handler - 422 INTO ITSELF",
+			body: map[string]any{"into": "science-fiction"},
+			buildStubs: func(t *testing.T, mockGenres *mockdb.MockGenreQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "into")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test, mockGenres := newGenreTest(t, "/v1/genres/science-fiction/merge")
+			tc.buildStubs(t, mockGenres)
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodPost, "/v1/genres/:slug/merge", test.app.mergeGenreHandler)
+
+			body, err := toReader(tc.body)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, test.url, body)
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestCreateMovieHandlerGenres(t *testing.T) {
+	testCases := []struct {
+		name          string
+		genres        []string
+		buildStubs    func(t *testing.T, test test, mockGenres *mockdb.MockGenreQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Test Create Movie Handler - 201 CREATED WITH THE SLUGS OF THE ALIASES",
+			genres: []string{"Science Fiction", "drama"},
+			buildStubs: func(t *testing.T, test test, mockGenres *mockdb.MockGenreQuerier) {
+				mockGenres.EXPECT().
+					Resolve([]string{"Science Fiction", "drama"}).
+					Return(map[string]string{"Science Fiction": "sci-fi", "drama": "drama"}, nil)
+
+				mockMovies := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+				mockMovies.EXPECT().FindDuplicates(gomock.Any()).Return([]*data.Movie{}, nil)
+				mockMovies.EXPECT().
+					Insert(gomock.Any()).
+					DoAndReturn(func(movie *data.Movie) error {
+						require.Equal(t, []string{"sci-fi", "drama"}, movie.Genres)
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, r.Code)
+			},
+		},
+		{
+			name:   "Test Create Movie Handler - 422 UNKNOWN GENRE",
+			genres: []string{"drama", "mockumentary"},
+			buildStubs: func(t *testing.T, test test, mockGenres *mockdb.MockGenreQuerier) {
+				mockGenres.EXPECT().
+					Resolve(gomock.Any()).
+					Return(map[string]string{"drama": "drama"}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Equal(t, `unknown genre "mockumentary"`, requireErrorMap(t, r)["genres"])
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test, mockGenres := newGenreTest(t, "/v1/movies")
+			tc.buildStubs(t, test, mockGenres)
+
+			body, err := toReader(map[string]any{
+				"title":   "Alien",
+				"year":    1979,
+				"runtime": "117 mins",
+				"genres":  tc.genres,
+			})
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, test.url, body)
+
+			// when
+			test.app.createMovieHandler(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestNormalizeGenres(t *testing.T) {
+	slugs := map[string]string{"Sci-Fi": "sci-fi", "science fiction": "sci-fi", "drama": "drama"}
+
+	testCases := []struct {
+		name     string
+		genres   []string
+		expected []string
+		valid    bool
+	}{
+		{
+			name:     "aliases are replaced by their slug",
+			genres:   []string{"science fiction", "drama"},
+			expected: []string{"sci-fi", "drama"},
+			valid:    true,
+		},
+		{
+			name:   "two aliases of the same genre",
+			genres: []string{"Sci-Fi", "science fiction"},
+		},
+		{
+			name:   "a genre out of the catalog",
+			genres: []string{"drama", "mockumentary"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := validator.New()
+			movie := &data.Movie{Genres: tc.genres}
+
+			data.NormalizeGenres(v, movie, slugs)
+
+			require.Equal(t, tc.valid, v.Valid())
+			if tc.valid {
+				require.Equal(t, tc.expected, movie.Genres)
+			} else {
+				require.Contains(t, v.Errors, "genres")
+			}
+		})
+	}
+}
@@ -1,26 +1,35 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/djudju12/greenlight/internal/msgpack"
 	"github.com/djudju12/greenlight/internal/validator"
 	"github.com/julienschmidt/httprouter"
 )
 
 func (app *application) readIDParam(r *http.Request) (int64, error) {
+	return app.readNamedIDParam(r, "id")
+}
+
+func (app *application) readNamedIDParam(r *http.Request, name string) (int64, error) {
 	params := httprouter.ParamsFromContext(r.Context())
 
 	// decimal (10) base 64
-	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+	id, err := strconv.ParseInt(params.ByName(name), 10, 64)
 	if err != nil || id < 1 {
-		return 0, errors.New("invalid id parameter")
+		return 0, fmt.Errorf("invalid %s parameter", name)
 	}
 
 	return id, nil
@@ -28,36 +37,40 @@ func (app *application) readIDParam(r *http.Request) (int64, error) {
 
 type envelope map[string]interface{}
 
-func (app *application) writeJSON(
-	w http.ResponseWriter, status int, data envelope, headers http.Header) error {
-	js, err := json.MarshalIndent(data, "", "\t")
-	if err != nil {
-		return err
-	}
-
-	js = append(js, '\n')
+var JsonMaxBytes = 1_048_576
 
-	for key, value := range headers {
-		w.Header()[key] = value
-	}
+func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	r.Body = http.MaxBytesReader(w, r.Body, int64(JsonMaxBytes))
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
+	var body io.Reader = r.Body
+
+	// MessagePack bodies are converted to JSON, so they are decoded and
+	// checked the same way
+	switch requestMediaType(r) {
+	case mediaTypeMsgpack, mediaTypeXMsgpack:
+		mp, err := io.ReadAll(r.Body)
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+			}
 
-	_, err = w.Write(js)
-	if err != nil {
-		return err
-	}
+			return err
+		}
 
-	return nil
-}
+		if len(mp) == 0 {
+			return errors.New("body must not be empty")
+		}
 
-var JsonMaxBytes = 1_048_576
+		js, err := msgpack.ToJSON(mp)
+		if err != nil {
+			return errors.New("body contains badly-formed MessagePack")
+		}
 
-func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
-	r.Body = http.MaxBytesReader(w, r.Body, int64(JsonMaxBytes))
+		body = bytes.NewReader(js)
+	}
 
-	dec := json.NewDecoder(r.Body)
+	dec := json.NewDecoder(body)
 	dec.DisallowUnknownFields()
 
 	err := dec.Decode(dst)
@@ -116,6 +129,66 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 	return nil
 }
 
+// errUnsupportedUpload is returned by readUpload when the body is neither a
+// file nor a multipart/form-data body.
+var errUnsupportedUpload = errors.New("unsupported upload")
+
+// readUpload reads a file uploaded either as the whole body of the request or
+// as the part named field of a multipart/form-data body. It returns the file
+// with the content type it was declared with. Uploads have a limit of their
+// own, the upload-max-bytes of the config, instead of the one of readJSON.
+func (app *application) readUpload(w http.ResponseWriter, r *http.Request, field string) ([]byte, string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, app.config.uploads.maxBytes)
+
+	contentType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, "", errUnsupportedUpload
+	}
+
+	var body io.Reader = r.Body
+
+	if contentType == "multipart/form-data" {
+		reader := multipart.NewReader(r.Body, params["boundary"])
+
+		for {
+			part, err := reader.NextPart()
+			if errors.Is(err, io.EOF) {
+				return nil, "", fmt.Errorf("body must contain a %q file", field)
+			}
+
+			if err != nil {
+				return nil, "", uploadError(err)
+			}
+
+			if part.FormName() == field {
+				contentType, _, _ = mime.ParseMediaType(part.Header.Get("Content-Type"))
+				body = part
+				break
+			}
+		}
+	}
+
+	file, err := io.ReadAll(body)
+	if err != nil {
+		return nil, "", uploadError(err)
+	}
+
+	if len(file) == 0 {
+		return nil, "", errors.New("file must not be empty")
+	}
+
+	return file, contentType, nil
+}
+
+func uploadError(err error) error {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+	}
+
+	return fmt.Errorf("body contains a badly-formed upload: %w", err)
+}
+
 func (app *application) readString(qs url.Values, key string, defaultValue string) string {
 	s := qs.Get(key)
 
@@ -153,6 +226,187 @@ func (app *application) readInt(
 	return i
 }
 
+func (app *application) readBool(
+	qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return defaultValue
+	}
+
+	return b
+}
+
+// readTime accepts either a full RFC 3339 timestamp or a plain date, which is
+// read as midnight UTC.
+func (app *application) readTime(
+	qs url.Values, key string, defaultValue time.Time, v *validator.Validator) time.Time {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err == nil {
+		return t
+	}
+
+	t, err = time.Parse(time.DateOnly, s)
+	if err != nil {
+		v.AddError(key, "must be a RFC 3339 timestamp or a date (YYYY-MM-DD)")
+		return defaultValue
+	}
+
+	return t
+}
+
+// negotiate picks the media type in offers that best matches the Accept header
+// of the request, following the q-values and wildcards of RFC 9110. A request
+// without an Accept header accepts anything, so it gets the first offer. Ties go
+// to the offer that comes first. It returns "" when no offer is acceptable.
+func (app *application) negotiate(r *http.Request, offers ...string) string {
+	accept := r.Header.Values("Accept")
+	if len(accept) == 0 {
+		return offers[0]
+	}
+
+	type mediaRange struct {
+		mediaType   string
+		q           float64
+		specificity int
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(strings.Join(accept, ","), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		specificity := 2
+		switch {
+		case mediaType == "*/*":
+			specificity = 0
+		case strings.HasSuffix(mediaType, "/*"):
+			specificity = 1
+		}
+
+		ranges = append(ranges, mediaRange{mediaType, q, specificity})
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		// the most specific range is the one that applies to the offer, so
+		// "text/csv;q=0" excludes CSV even when "*/*" is accepted too
+		q, specificity := 0.0, -1
+		for _, rng := range ranges {
+			if mediaTypeMatches(rng.mediaType, offer) && rng.specificity > specificity {
+				q, specificity = rng.q, rng.specificity
+			}
+		}
+
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+
+	return best
+}
+
+func mediaTypeMatches(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+
+	prefix, ok := strings.CutSuffix(mediaRange, "*")
+	return ok && strings.HasPrefix(mediaType, prefix)
+}
+
+// negotiateLanguage picks the language tag in offers that best matches the
+// Accept-Language header of the request. A language range matches the tags it
+// is a prefix of, like "pt" matches "pt-BR", and as a fallback the tags that are
+// a prefix of it, like "pt-BR" matches "pt". The "*" range is ignored, as the
+// offers are alternatives to a default the caller falls back to. Ties go to the
+// more specific match, then to the offer that comes first. It returns "" when
+// no offer is acceptable.
+func (app *application) negotiateLanguage(r *http.Request, offers ...string) string {
+	type languageRange struct {
+		tag string
+		q   float64
+	}
+
+	var ranges []languageRange
+	for _, part := range strings.Split(strings.Join(r.Header.Values("Accept-Language"), ","), ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			q, err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		ranges = append(ranges, languageRange{tag, q})
+	}
+
+	best, bestQ, bestSpecificity := "", 0.0, 0
+	for _, offer := range offers {
+		tag := strings.ToLower(offer)
+
+		q, specificity := 0.0, 0
+		for _, rng := range ranges {
+			s := languageMatch(rng.tag, tag)
+			if s > specificity {
+				q, specificity = rng.q, s
+			}
+		}
+
+		if q > bestQ || (q == bestQ && q > 0 && specificity > bestSpecificity) {
+			best, bestQ, bestSpecificity = offer, q, specificity
+		}
+	}
+
+	return best
+}
+
+// languageMatch returns how specifically the language range matches the tag,
+// both in lowercase: 3 when they are the same, 2 when the tag is a more specific
+// form of the range, 1 when the range is a more specific form of the tag and 0
+// when they don't match.
+func languageMatch(languageRange, tag string) int {
+	switch {
+	case languageRange == tag:
+		return 3
+	case strings.HasPrefix(tag, languageRange+"-"):
+		return 2
+	case strings.HasPrefix(languageRange, tag+"-"):
+		return 1
+	default:
+		return 0
+	}
+}
+
 func (app *application) background(fn func()) {
 	app.wg.Add(1)
 	go func() {
@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/djudju12/greenlight/internal/util"
 	"github.com/djudju12/greenlight/internal/validator"
@@ -200,3 +201,99 @@ func TestReadInt(t *testing.T) {
 		})
 	}
 }
+
+func TestReadTime(t *testing.T) {
+	app := &application{}
+	defaultValue := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name  string
+		value string
+		check func(t *testing.T, value time.Time, v *validator.Validator)
+	}{
+		{
+			name:  "Read time receives a RFC 3339 timestamp and return the time",
+			value: "2023-05-10T12:30:00Z",
+			check: func(t *testing.T, value time.Time, v *validator.Validator) {
+				require.True(t, v.Valid())
+				require.Equal(t, time.Date(2023, 5, 10, 12, 30, 0, 0, time.UTC), value)
+			},
+		},
+		{
+			name:  "Read time receives a date and return midnight of that date",
+			value: "2023-05-10",
+			check: func(t *testing.T, value time.Time, v *validator.Validator) {
+				require.True(t, v.Valid())
+				require.Equal(t, time.Date(2023, 5, 10, 0, 0, 0, 0, time.UTC), value)
+			},
+		},
+		{
+			name:  "Read time receives an invalid input and return the default value",
+			value: "yesterday",
+			check: func(t *testing.T, value time.Time, v *validator.Validator) {
+				require.Equal(t, defaultValue, value)
+				_, ok := v.Errors["value"]
+				require.True(t, ok)
+			},
+		},
+		{
+			name:  "Read time receives no input and return the default value",
+			value: "",
+			check: func(t *testing.T, value time.Time, v *validator.Validator) {
+				require.Equal(t, defaultValue, value)
+				require.True(t, v.Valid())
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			key := "value"
+			v := validator.New()
+			qs := make(url.Values)
+			qs.Add(key, tc.value)
+
+			// when
+			value := app.readTime(qs, key, defaultValue, v)
+
+			// then
+			tc.check(t, value, v)
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	app := &application{}
+	offers := []string{"application/json", "text/csv"}
+
+	testCases := []struct {
+		name     string
+		accept   string
+		expected string
+	}{
+		{name: "No Accept header returns the first offer", accept: "", expected: "application/json"},
+		{name: "Exact match", accept: "text/csv", expected: "text/csv"},
+		{name: "Highest q-value wins", accept: "application/json;q=0.5, text/csv;q=0.8", expected: "text/csv"},
+		{name: "Wildcard returns the first offer", accept: "*/*", expected: "application/json"},
+		{name: "Type wildcard", accept: "text/*", expected: "text/csv"},
+		{name: "Most specific range applies", accept: "*/*, application/json;q=0", expected: "text/csv"},
+		{name: "Nothing acceptable", accept: "application/xml", expected: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.accept != "" {
+				request.Header.Set("Accept", tc.accept)
+			}
+
+			// when
+			mediaType := app.negotiate(request, offers...)
+
+			// then
+			require.Equal(t, tc.expected, mediaType)
+		})
+	}
+}
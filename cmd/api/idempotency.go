@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/data"
+	"github.com/djudju12/greenlight/internal/validator"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyReplayedHeaders are the response headers stored with the key and
+// sent again when the response is replayed.
+var idempotencyReplayedHeaders = []string{"Content-Type", "Location"}
+
+// idempotencyRecorder passes the response through to the client while keeping
+// a copy of it to be stored against the idempotency key.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *idempotencyRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *idempotencyRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// idempotent makes retries of a request that carries an Idempotency-Key header
+// safe. The first response for a key is stored, and a retry with the same key
+// and body gets that response back instead of running next again. Keys are
+// scoped by user and path, and expire after the configured TTL. While the
+// first request is still running the key is only leased, so it's freed soon
+// if the server stops before storing the response. Server errors are not
+// stored, so a request that failed with one can be retried.
+func (app *application) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		v := validator.New()
+		if data.ValidateIdempotencyKey(v, key); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(JsonMaxBytes)))
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			switch {
+			case errors.As(err, &maxBytesError):
+				app.badRequestResponse(w, r, err)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash := sha256.Sum256(body)
+
+		// anonymous callers all share the same user, so their keys are scoped
+		// by the request body too, and a key picked by one of them never gets
+		// another's response back
+		user := app.contextGetUser(r)
+		if user.IsAnonymous() {
+			key += ":" + hex.EncodeToString(hash[:])
+		}
+
+		idempotencyKey := &data.IdempotencyKey{
+			Key:         key,
+			UserID:      user.ID,
+			RequestPath: r.URL.Path,
+			RequestHash: hash[:],
+			Expiry:      time.Now().Add(app.config.idempotency.lease),
+		}
+
+		err = app.models.Idempotency.Reserve(idempotencyKey)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrIdempotencyKeyExists):
+				app.replayIdempotentResponse(w, r, idempotencyKey)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		rec := &idempotencyRecorder{ResponseWriter: w}
+
+		// release the key if next panics, so the request can be retried
+		completed := false
+		defer func() {
+			if !completed {
+				app.releaseIdempotencyKey(r, idempotencyKey)
+			}
+		}()
+
+		next(rec, r)
+
+		if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+			return
+		}
+
+		idempotencyKey.Status = &rec.status
+		idempotencyKey.Body = rec.body.Bytes()
+		idempotencyKey.Expiry = time.Now().Add(app.config.idempotency.ttl)
+		idempotencyKey.Header = http.Header{}
+		for _, name := range idempotencyReplayedHeaders {
+			if value := rec.Header().Get(name); value != "" {
+				idempotencyKey.Header.Set(name, value)
+			}
+		}
+
+		err = app.models.Idempotency.Complete(idempotencyKey)
+		if err != nil {
+			// the response was already sent, so the key is released instead
+			app.logError(r, err)
+			return
+		}
+
+		completed = true
+	}
+}
+
+// replayIdempotentResponse answers a request whose key is already in use with
+// the stored response, as long as the request body is the same one.
+func (app *application) replayIdempotentResponse(w http.ResponseWriter, r *http.Request, idempotencyKey *data.IdempotencyKey) {
+	stored, err := app.models.Idempotency.Get(idempotencyKey.Key, idempotencyKey.UserID, idempotencyKey.RequestPath)
+	if err != nil {
+		switch {
+		// the key expired between the reservation and now
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.idempotencyKeyInProgressResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !bytes.Equal(stored.RequestHash, idempotencyKey.RequestHash) {
+		app.idempotencyKeyMismatchResponse(w, r)
+		return
+	}
+
+	if stored.Status == nil {
+		app.idempotencyKeyInProgressResponse(w, r)
+		return
+	}
+
+	for name, values := range stored.Header {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(*stored.Status)
+	w.Write(stored.Body)
+}
+
+func (app *application) releaseIdempotencyKey(r *http.Request, idempotencyKey *data.IdempotencyKey) {
+	err := app.models.Idempotency.Delete(idempotencyKey)
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+// purgeIdempotencyKeys deletes the expired idempotency keys every interval.
+func (app *application) purgeIdempotencyKeys(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+
+		deleted, err := app.models.Idempotency.DeleteExpired()
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			continue
+		}
+
+		if deleted > 0 {
+			app.logger.PrintInfo("expired idempotency keys deleted", map[string]string{
+				"deleted": strconv.FormatInt(deleted, 10),
+			})
+		}
+	}
+}
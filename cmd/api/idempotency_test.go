@@ -0,0 +1,284 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/data"
+	"github.com/djudju12/greenlight/internal/jsonlog"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestIdempotentMiddleware(t *testing.T) {
+	const (
+		key         = "a3f2c1d0-retry"
+		requestBody = `{"title": "Casablanca"}`
+		path        = "/v1/movies"
+	)
+
+	user := &data.User{ID: 7}
+	requestHash := sha256.Sum256([]byte(requestBody))
+	created := http.StatusCreated
+
+	matchesKey := gomock.Cond(func(x any) bool {
+		k, ok := x.(*data.IdempotencyKey)
+		return ok && k.Key == key && k.UserID == user.ID && k.RequestPath == path &&
+			string(k.RequestHash) == string(requestHash[:])
+	})
+
+	testCases := []struct {
+		name          string
+		header        string
+		handlerStatus int
+		buildStubs    func(t *testing.T, mockIdempotency *mockdb.MockIdempotencyQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder, calls int)
+	}{
+		{
+			name:          "Test Idempotent Middleware - NO KEY",
+			handlerStatus: http.StatusCreated,
+			buildStubs: func(t *testing.T, mockIdempotency *mockdb.MockIdempotencyQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, calls int) {
+				require.Equal(t, http.StatusCreated, r.Code)
+				require.Equal(t, 1, calls)
+			},
+		},
+		{
+			name:          "Test Idempotent Middleware - FIRST REQUEST STORED",
+			header:        key,
+			handlerStatus: http.StatusCreated,
+			buildStubs: func(t *testing.T, mockIdempotency *mockdb.MockIdempotencyQuerier) {
+				mockIdempotency.EXPECT().
+					Reserve(matchesKey).
+					DoAndReturn(func(k *data.IdempotencyKey) error {
+						// the key is only leased until the response is stored
+						require.WithinDuration(t, time.Now().Add(time.Minute), k.Expiry, time.Second)
+						return nil
+					})
+				mockIdempotency.EXPECT().
+					Complete(matchesKey).
+					DoAndReturn(func(k *data.IdempotencyKey) error {
+						require.Equal(t, http.StatusCreated, *k.Status)
+						require.Equal(t, "/v1/movies/1", k.Header.Get("Location"))
+						require.Equal(t, "application/json", k.Header.Get("Content-Type"))
+						require.JSONEq(t, `{"ok":true}`, string(k.Body))
+						require.WithinDuration(t, time.Now().Add(time.Hour), k.Expiry, time.Second)
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, calls int) {
+				require.Equal(t, http.StatusCreated, r.Code)
+				require.Equal(t, 1, calls)
+				require.Empty(t, r.Header().Get("Idempotent-Replayed"))
+			},
+		},
+		{
+			name:          "Test Idempotent Middleware - REPLAYED",
+			header:        key,
+			handlerStatus: http.StatusCreated,
+			buildStubs: func(t *testing.T, mockIdempotency *mockdb.MockIdempotencyQuerier) {
+				mockIdempotency.EXPECT().Reserve(matchesKey).Return(data.ErrIdempotencyKeyExists)
+				mockIdempotency.EXPECT().Get(key, user.ID, path).Return(&data.IdempotencyKey{
+					Key:         key,
+					UserID:      user.ID,
+					RequestPath: path,
+					RequestHash: requestHash[:],
+					Status:      &created,
+					Header:      http.Header{"Location": {"/v1/movies/1"}},
+					Body:        []byte(`{"ok":true}`),
+				}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, calls int) {
+				require.Equal(t, http.StatusCreated, r.Code)
+				require.Equal(t, 0, calls)
+				require.Equal(t, "true", r.Header().Get("Idempotent-Replayed"))
+				require.Equal(t, "/v1/movies/1", r.Header().Get("Location"))
+				require.Equal(t, `{"ok":true}`, r.Body.String())
+			},
+		},
+		{
+			name:          "Test Idempotent Middleware - 422 DIFFERENT BODY",
+			header:        key,
+			handlerStatus: http.StatusCreated,
+			buildStubs: func(t *testing.T, mockIdempotency *mockdb.MockIdempotencyQuerier) {
+				mockIdempotency.EXPECT().Reserve(matchesKey).Return(data.ErrIdempotencyKeyExists)
+				mockIdempotency.EXPECT().Get(key, user.ID, path).Return(&data.IdempotencyKey{
+					RequestHash: []byte("another body"),
+					Status:      &created,
+				}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, calls int) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Equal(t, 0, calls)
+			},
+		},
+		{
+			name:          "Test Idempotent Middleware - 409 IN PROGRESS",
+			header:        key,
+			handlerStatus: http.StatusCreated,
+			buildStubs: func(t *testing.T, mockIdempotency *mockdb.MockIdempotencyQuerier) {
+				mockIdempotency.EXPECT().Reserve(matchesKey).Return(data.ErrIdempotencyKeyExists)
+				mockIdempotency.EXPECT().Get(key, user.ID, path).Return(&data.IdempotencyKey{
+					RequestHash: requestHash[:],
+				}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, calls int) {
+				require.Equal(t, http.StatusConflict, r.Code)
+				require.Equal(t, 0, calls)
+			},
+		},
+		{
+			name:          "Test Idempotent Middleware - SERVER ERROR RELEASES KEY",
+			header:        key,
+			handlerStatus: http.StatusInternalServerError,
+			buildStubs: func(t *testing.T, mockIdempotency *mockdb.MockIdempotencyQuerier) {
+				mockIdempotency.EXPECT().Reserve(matchesKey).Return(nil)
+				mockIdempotency.EXPECT().Delete(matchesKey).Return(nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, calls int) {
+				require.Equal(t, http.StatusInternalServerError, r.Code)
+				require.Equal(t, 1, calls)
+			},
+		},
+		{
+			name:          "Test Idempotent Middleware - 422 INVALID KEY",
+			header:        strings.Repeat("k", 256),
+			handlerStatus: http.StatusCreated,
+			buildStubs: func(t *testing.T, mockIdempotency *mockdb.MockIdempotencyQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, calls int) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Equal(t, 0, calls)
+			},
+		},
+		{
+			name:          "Test Idempotent Middleware - 500 DB RETURN ERROR",
+			header:        key,
+			handlerStatus: http.StatusCreated,
+			buildStubs: func(t *testing.T, mockIdempotency *mockdb.MockIdempotencyQuerier) {
+				mockIdempotency.EXPECT().Reserve(matchesKey).Return(errors.New("DB ERROR"))
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, calls int) {
+				require.Equal(t, http.StatusInternalServerError, r.Code)
+				require.Equal(t, 0, calls)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			ctrl := gomock.NewController(t)
+			mockIdempotency := mockdb.NewMockIdempotencyQuerier(ctrl)
+			tc.buildStubs(t, mockIdempotency)
+
+			f, err := os.CreateTemp("", "tmpfile-")
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+
+			app := &application{
+				models: &data.Models{Idempotency: mockIdempotency},
+				logger: jsonlog.New(f, jsonlog.LevelInfo),
+			}
+			app.config.idempotency.ttl = time.Hour
+			app.config.idempotency.lease = time.Minute
+
+			calls := 0
+			handler := app.idempotent(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+
+				headers := http.Header{"Location": {"/v1/movies/1"}}
+				err := app.writeResponse(w, r, tc.handlerStatus, envelope{"ok": true}, headers)
+				require.NoError(t, err)
+			})
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(requestBody))
+			if tc.header != "" {
+				request.Header.Set(idempotencyKeyHeader, tc.header)
+			}
+			request = app.contextSetUser(request, user)
+
+			// when
+			handler(recorder, request)
+
+			// then
+			tc.checkResponse(t, recorder, calls)
+		})
+	}
+}
+
+func TestIdempotentMiddlewareAnonymous(t *testing.T) {
+	const key = "a3f2c1d0-signup"
+
+	testCases := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "Test Idempotent Middleware - ANONYMOUS KEY SCOPED BY BODY",
+			body: `{"name": "Alice", "email": "alice@example.com"}`,
+		},
+		{
+			name: "Test Idempotent Middleware - ANONYMOUS KEY WITH ANOTHER BODY",
+			body: `{"name": "Bob", "email": "bob@example.com"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			hash := sha256.Sum256([]byte(tc.body))
+			scopedKey := key + ":" + hex.EncodeToString(hash[:])
+
+			ctrl := gomock.NewController(t)
+			mockIdempotency := mockdb.NewMockIdempotencyQuerier(ctrl)
+
+			matchesKey := gomock.Cond(func(x any) bool {
+				k, ok := x.(*data.IdempotencyKey)
+				return ok && k.Key == scopedKey && k.UserID == data.AnonymousUser.ID
+			})
+
+			mockIdempotency.EXPECT().Reserve(matchesKey).Return(nil)
+			mockIdempotency.EXPECT().Complete(matchesKey).Return(nil)
+
+			app := &application{
+				models: &data.Models{Idempotency: mockIdempotency},
+				logger: jsonlog.New(io.Discard, jsonlog.LevelInfo),
+			}
+			app.config.idempotency.ttl = time.Hour
+			app.config.idempotency.lease = time.Minute
+
+			handler := app.idempotent(func(w http.ResponseWriter, r *http.Request) {
+				err := app.writeResponse(w, r, http.StatusAccepted, envelope{"ok": true}, nil)
+				require.NoError(t, err)
+			})
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(tc.body))
+			request.Header.Set(idempotencyKeyHeader, key)
+			request = app.contextSetUser(request, data.AnonymousUser)
+
+			// when
+			handler(recorder, request)
+
+			// then
+			require.Equal(t, http.StatusAccepted, recorder.Code)
+		})
+	}
+}
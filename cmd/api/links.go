@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/djudju12/greenlight/internal/data"
+)
+
+// paginationRels are the relations of the pagination links, in the order
+// they are written in the Link header.
+var paginationRels = []string{"first", "prev", "next", "last"}
+
+func movieURL(id int64) string {
+	return fmt.Sprintf("/v1/movies/%d", id)
+}
+
+// paginationLinks returns the URLs of the first, previous, next and last
+// pages, keyed by their relation. They keep the query string of the request,
+// only the page changes. There are no links when nothing was found, and no
+// previous or next links from the first and last pages.
+func paginationLinks(r *http.Request, metadata data.Metadata) map[string]string {
+	links := map[string]string{}
+
+	if metadata.TotalRecords == 0 {
+		return links
+	}
+
+	links["first"] = pageURL(r.URL, metadata.FirstPage)
+	links["last"] = pageURL(r.URL, metadata.LastPage)
+
+	if metadata.CurrentPage > metadata.FirstPage {
+		links["prev"] = pageURL(r.URL, min(metadata.CurrentPage-1, metadata.LastPage))
+	}
+
+	if metadata.CurrentPage < metadata.LastPage {
+		links["next"] = pageURL(r.URL, metadata.CurrentPage+1)
+	}
+
+	return links
+}
+
+func pageURL(u *url.URL, page int) string {
+	qs := u.Query()
+	qs.Set("page", strconv.Itoa(page))
+
+	return u.Path + "?" + qs.Encode()
+}
+
+// linkHeader formats the links as a RFC 8288 Link header value.
+func linkHeader(links map[string]string) string {
+	var values []string
+	for _, rel := range paginationRels {
+		if link, ok := links[rel]; ok {
+			values = append(values, fmt.Sprintf(`<%s>; rel="%s"`, link, rel))
+		}
+	}
+
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/djudju12/greenlight/internal/data"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestPaginationLinks(t *testing.T) {
+	testCases := []struct {
+		name     string
+		url      string
+		metadata data.Metadata
+		expected map[string]string
+		header   string
+	}{
+		{
+			name:     "Test Pagination Links - NO RECORDS",
+			url:      "/v1/movies?title=casablanca",
+			metadata: data.Metadata{},
+			expected: map[string]string{},
+			header:   "",
+		},
+		{
+			name:     "Test Pagination Links - MIDDLE PAGE",
+			url:      "/v1/movies?genres=drama&page=2&page_size=10",
+			metadata: data.Metadata{CurrentPage: 2, PageSize: 10, FirstPage: 1, LastPage: 3, TotalRecords: 25},
+			expected: map[string]string{
+				"first": "/v1/movies?genres=drama&page=1&page_size=10",
+				"prev":  "/v1/movies?genres=drama&page=1&page_size=10",
+				"next":  "/v1/movies?genres=drama&page=3&page_size=10",
+				"last":  "/v1/movies?genres=drama&page=3&page_size=10",
+			},
+			header: `</v1/movies?genres=drama&page=1&page_size=10>; rel="first", ` +
+				`</v1/movies?genres=drama&page=1&page_size=10>; rel="prev", ` +
+				`</v1/movies?genres=drama&page=3&page_size=10>; rel="next", ` +
+				`</v1/movies?genres=drama&page=3&page_size=10>; rel="last"`,
+		},
+		{
+			name:     "Test Pagination Links - FIRST PAGE",
+			url:      "/v1/movies",
+			metadata: data.Metadata{CurrentPage: 1, PageSize: 20, FirstPage: 1, LastPage: 2, TotalRecords: 30},
+			expected: map[string]string{
+				"first": "/v1/movies?page=1",
+				"next":  "/v1/movies?page=2",
+				"last":  "/v1/movies?page=2",
+			},
+			header: `</v1/movies?page=1>; rel="first", </v1/movies?page=2>; rel="next", </v1/movies?page=2>; rel="last"`,
+		},
+		{
+			name:     "Test Pagination Links - PAGE PAST THE LAST",
+			url:      "/v1/movies?page=7",
+			metadata: data.Metadata{CurrentPage: 7, PageSize: 20, FirstPage: 1, LastPage: 2, TotalRecords: 30},
+			expected: map[string]string{
+				"first": "/v1/movies?page=1",
+				"prev":  "/v1/movies?page=2",
+				"last":  "/v1/movies?page=2",
+			},
+			header: `</v1/movies?page=1>; rel="first", </v1/movies?page=2>; rel="prev", </v1/movies?page=2>; rel="last"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			request := httptest.NewRequest(http.MethodGet, tc.url, nil)
+
+			// when
+			links := paginationLinks(request, tc.metadata)
+
+			// then
+			require.Equal(t, tc.expected, links)
+			require.Equal(t, tc.header, linkHeader(links))
+		})
+	}
+}
+
+func TestListMoviesHandlerLinks(t *testing.T) {
+	// given
+	movies := []*data.Movie{randomMovie(), randomMovie()}
+	metadata := data.Metadata{CurrentPage: 1, PageSize: 2, FirstPage: 1, LastPage: 2, TotalRecords: 3}
+
+	test := newMovieTest(t, "/v1/movies?page_size=2&links=true")
+
+	mockMovies, ok := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+	require.True(t, ok)
+
+	mockMovies.EXPECT().
+		GetAll(gomock.Any(), gomock.Any()).
+		Return(movies, metadata, nil)
+
+	request := httptest.NewRequest(http.MethodGet, test.url, nil)
+
+	// when
+	test.app.listMoviesHandles(test.recorder, request)
+
+	// then
+	require.Equal(t, http.StatusOK, test.recorder.Code)
+	require.Equal(t,
+		`</v1/movies?links=true&page=1&page_size=2>; rel="first", `+
+			`</v1/movies?links=true&page=2&page_size=2>; rel="next", `+
+			`</v1/movies?links=true&page=2&page_size=2>; rel="last"`,
+		test.recorder.Header().Get("Link"))
+
+	var envelope struct {
+		Links  map[string]string `json:"links"`
+		Movies []struct {
+			ID    int64             `json:"id"`
+			Links map[string]string `json:"links"`
+		} `json:"movies"`
+	}
+	err := json.NewDecoder(test.recorder.Body).Decode(&envelope)
+	require.NoError(t, err)
+
+	require.Equal(t, "/v1/movies?page_size=2&links=true", envelope.Links["self"])
+	require.Equal(t, "/v1/movies?links=true&page=2&page_size=2", envelope.Links["next"])
+
+	require.Len(t, envelope.Movies, 2)
+	for i, movie := range envelope.Movies {
+		require.Equal(t, movies[i].ID, movie.ID)
+		require.Equal(t, movieURL(movies[i].ID), movie.Links["self"])
+	}
+
+	test.close()
+}
+
+func TestListMoviesHandlerNoLinksObject(t *testing.T) {
+	// given
+	test := newMovieTest(t, "/v1/movies")
+
+	mockMovies, ok := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+	require.True(t, ok)
+
+	mockMovies.EXPECT().
+		GetAll(gomock.Any(), gomock.Any()).
+		Return([]*data.Movie{}, data.Metadata{}, nil)
+
+	request := httptest.NewRequest(http.MethodGet, test.url, nil)
+
+	// when
+	test.app.listMoviesHandles(test.recorder, request)
+
+	// then
+	require.Equal(t, http.StatusOK, test.recorder.Code)
+	require.Empty(t, test.recorder.Header().Get("Link"))
+
+	var envelope map[string]json.RawMessage
+	err := json.NewDecoder(test.recorder.Body).Decode(&envelope)
+	require.NoError(t, err)
+	require.NotContains(t, envelope, "links")
+
+	test.close()
+}
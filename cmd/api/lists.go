@@ -0,0 +1,477 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/data"
+	"github.com/djudju12/greenlight/internal/validator"
+)
+
+type CreateListRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Visibility  string `json:"visibility"`
+}
+
+func (app *application) createListHandler(w http.ResponseWriter, r *http.Request) {
+	var input CreateListRequest
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	list := &data.List{
+		UserID:      app.contextGetUser(r).ID,
+		Name:        input.Name,
+		Description: input.Description,
+		Visibility:  input.Visibility,
+	}
+
+	if list.Visibility == "" {
+		list.Visibility = data.ListPrivate
+	}
+
+	v := validator.New()
+	if data.ValidateList(v, list); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Lists.Insert(list)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/lists/%d", list.ID))
+
+	err = app.writeResponse(w, r, http.StatusCreated, envelope{"list": list}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listOwnListsHandler returns every list of the authenticated user.
+func (app *application) listOwnListsHandler(w http.ResponseWriter, r *http.Request) {
+	lists, err := app.models.Lists.GetAllForUser(app.contextGetUser(r).ID, data.ListVisibilities)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"lists": lists}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listUserListsHandler returns the public lists of an user.
+func (app *application) listUserListsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	lists, err := app.models.Lists.GetAllForUser(userID, []string{data.ListPublic})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"lists": lists}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showListHandler returns the list with its items. Private lists are only
+// found by their owner, and unlisted ones also by those who send their token in
+// the share_token query parameter.
+func (app *application) showListHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	list, err := app.models.Lists.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !list.VisibleTo(app.contextGetUser(r), app.readShareToken(r)) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	items, err := app.models.Lists.GetItems(list.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"list": list, "items": items}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+type UpdateListRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Visibility  *string `json:"visibility"`
+}
+
+func (app *application) updateListHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readOwnList(w, r)
+	if !ok {
+		return
+	}
+
+	var input UpdateListRequest
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		list.Name = *input.Name
+	}
+
+	if input.Description != nil {
+		list.Description = *input.Description
+	}
+
+	if input.Visibility != nil {
+		list.Visibility = *input.Visibility
+	}
+
+	v := validator.New()
+	if data.ValidateList(v, list); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Lists.Update(list)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"list": list}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteListHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readOwnList(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.models.Lists.Delete(list.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"message": "list successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+type AddListItemRequest struct {
+	MovieID  int64 `json:"movie_id"`
+	Position int   `json:"position"`
+}
+
+// addListItemHandler adds a movie to the list, at the end unless a position is
+// given.
+func (app *application) addListItemHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readOwnList(w, r)
+	if !ok {
+		return
+	}
+
+	var input AddListItemRequest
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.MovieID > 0, "movie_id", "must be provided")
+	v.Check(input.Position >= 0, "position", "must not be negative")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	item := &data.ListItem{
+		ListID:   list.ID,
+		MovieID:  input.MovieID,
+		Position: input.Position,
+	}
+
+	err = app.models.Lists.AddItem(item)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrUnknownMovie):
+			v.AddError("movie_id", "must be an existing movie")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrDuplicateListItem):
+			v.AddError("movie_id", "the movie is already in the list")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusCreated, envelope{"item": item}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+type UpdateListItemRequest struct {
+	Position  *int       `json:"position"`
+	Watched   *bool      `json:"watched"`
+	WatchedAt *time.Time `json:"watched_at"`
+}
+
+// updateListItemHandler moves an item of the list and marks it as watched or
+// not. A movie marked as watched without a date was watched now.
+func (app *application) updateListItemHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readOwnList(w, r)
+	if !ok {
+		return
+	}
+
+	movieID, err := app.readNamedIDParam(r, "movie_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input UpdateListItemRequest
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	item, err := app.models.Lists.GetItem(list.ID, movieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+	applyListItemUpdate(item, input, time.Now(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Lists.UpdateItem(item)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"item": item}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// applyListItemUpdate copies to item the fields that are present in input.
+func applyListItemUpdate(item *data.ListItem, input UpdateListItemRequest, now time.Time, v *validator.Validator) {
+	if input.Position != nil {
+		v.Check(*input.Position > 0, "position", "must be greater than 0")
+		item.Position = *input.Position
+	}
+
+	if input.WatchedAt != nil {
+		v.Check(input.Watched == nil || *input.Watched, "watched_at", "must not be provided for an unwatched movie")
+		v.Check(!input.WatchedAt.After(now), "watched_at", "must not be in the future")
+
+		item.Watched = true
+		item.WatchedAt = input.WatchedAt
+		return
+	}
+
+	if input.Watched != nil {
+		switch {
+		case !*input.Watched:
+			item.WatchedAt = nil
+		case !item.Watched:
+			watchedAt := now.Truncate(time.Second)
+			item.WatchedAt = &watchedAt
+		}
+
+		item.Watched = *input.Watched
+	}
+}
+
+func (app *application) removeListItemHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readOwnList(w, r)
+	if !ok {
+		return
+	}
+
+	movieID, err := app.readNamedIDParam(r, "movie_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Lists.RemoveItem(list.ID, movieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"message": "movie successfully removed from the list"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+type ReorderListRequest struct {
+	MovieIDs []int64 `json:"movie_ids"`
+}
+
+// reorderListHandler sorts the whole list at once, given every movie of it in
+// the new order.
+func (app *application) reorderListHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readOwnList(w, r)
+	if !ok {
+		return
+	}
+
+	var input ReorderListRequest
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.MovieIDs != nil, "movie_ids", "must be provided")
+	v.Check(validator.Unique(input.MovieIDs), "movie_ids", "must not contain duplicate values")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Lists.Reorder(list.ID, input.MovieIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrInvalidListOrder):
+			v.AddError("movie_ids", "must contain every movie of the list exactly once")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	items, err := app.models.Lists.GetItems(list.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"list": list, "items": items}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readOwnList reads the list in the URL, making sure it belongs to the
+// authenticated user. Lists of others the user can't see are not found. When
+// the list can't be changed by the user the error response is sent and false
+// returned.
+func (app *application) readOwnList(w http.ResponseWriter, r *http.Request) (*data.List, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	list, err := app.models.Lists.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	user := app.contextGetUser(r)
+
+	switch {
+	case !list.VisibleTo(user, app.readShareToken(r)):
+		app.notFoundResponse(w, r)
+		return nil, false
+	case list.UserID != user.ID:
+		app.notPermittedResponse(w, r)
+		return nil, false
+	}
+
+	return list, true
+}
+
+// readShareToken returns the token of an unlisted list sent in the URL.
+func (app *application) readShareToken(r *http.Request) string {
+	return app.readString(r.URL.Query(), "share_token", "")
+}
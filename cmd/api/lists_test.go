@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/data"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/djudju12/greenlight/internal/validator"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newListTest(t *testing.T, url string) (test, *mockdb.MockListQuerier) {
+	test := newMovieTest(t, url)
+
+	mockLists := mockdb.NewMockListQuerier(gomock.NewController(t))
+	test.app.models.Lists = mockLists
+
+	return test, mockLists
+}
+
+const listShareToken = "NLHZEQ6OYUBHBVGM5S2QIBTLKA"
+
+func TestShowListHandler(t *testing.T) {
+	owner := &data.User{ID: 1, Activated: true}
+	other := &data.User{ID: 2, Activated: true}
+
+	testCases := []struct {
+		name          string
+		user          *data.User
+		visibility    string
+		query         string
+		buildStubs    func(t *testing.T, mockLists *mockdb.MockListQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "Test Show List Handler - 200 OK PRIVATE LIST OF THE OWNER",
+			user:       owner,
+			visibility: data.ListPrivate,
+			buildStubs: func(t *testing.T, mockLists *mockdb.MockListQuerier) {
+				mockLists.EXPECT().
+					GetItems(int64(7)).
+					Return([]*data.ListItem{{MovieID: 3, Position: 1}}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				var envelope struct {
+					List  data.List        `json:"list"`
+					Items []*data.ListItem `json:"items"`
+				}
+				err := json.NewDecoder(r.Body).Decode(&envelope)
+				require.NoError(t, err)
+				require.Equal(t, int64(7), envelope.List.ID)
+				require.Len(t, envelope.Items, 1)
+			},
+		},
+		{
+			name:       "Test Show List Handler - 200 OK UNLISTED LIST OF ANOTHER USER",
+			user:       other,
+			visibility: data.ListUnlisted,
+			query:      "?share_token=" + listShareToken,
+			buildStubs: func(t *testing.T, mockLists *mockdb.MockListQuerier) {
+				mockLists.EXPECT().GetItems(int64(7)).Return([]*data.ListItem{}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+			},
+		},
+		{
+			name:       "Test Show List Handler - 200 OK UNLISTED LIST OF THE OWNER",
+			user:       owner,
+			visibility: data.ListUnlisted,
+			buildStubs: func(t *testing.T, mockLists *mockdb.MockListQuerier) {
+				mockLists.EXPECT().GetItems(int64(7)).Return([]*data.ListItem{}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Contains(t, r.Body.String(), listShareToken)
+			},
+		},
+		{
+			name:       "Test Show List Handler - 404 UNLISTED LIST WITHOUT THE SHARE TOKEN",
+			user:       other,
+			visibility: data.ListUnlisted,
+			buildStubs: func(t *testing.T, mockLists *mockdb.MockListQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, r.Code)
+			},
+		},
+		{
+			name:       "Test Show List Handler - 404 UNLISTED LIST WITH ANOTHER SHARE TOKEN",
+			user:       data.AnonymousUser,
+			visibility: data.ListUnlisted,
+			query:      "?share_token=" + strings.ToLower(listShareToken),
+			buildStubs: func(t *testing.T, mockLists *mockdb.MockListQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, r.Code)
+			},
+		},
+		{
+			name:       "Test Show List Handler - 404 PRIVATE LIST OF ANOTHER USER",
+			user:       other,
+			visibility: data.ListPrivate,
+			buildStubs: func(t *testing.T, mockLists *mockdb.MockListQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, r.Code)
+			},
+		},
+		{
+			name:       "Test Show List Handler - 404 PRIVATE LIST OF ANONYMOUS USER",
+			user:       data.AnonymousUser,
+			visibility: data.ListPrivate,
+			buildStubs: func(t *testing.T, mockLists *mockdb.MockListQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, r.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test, mockLists := newListTest(t, "/v1/lists/7"+tc.query)
+
+			list := &data.List{ID: 7, UserID: owner.ID, Name: "watchlist", Visibility: tc.visibility}
+			if tc.visibility == data.ListUnlisted {
+				list.ShareToken = listShareToken
+			}
+
+			mockLists.EXPECT().Get(int64(7)).Return(list, nil)
+			tc.buildStubs(t, mockLists)
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodGet, "/v1/lists/:id", test.app.showListHandler)
+
+			request := httptest.NewRequest(http.MethodGet, test.url, nil)
+			request = test.app.contextSetUser(request, tc.user)
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestAddListItemHandler(t *testing.T) {
+	owner := &data.User{ID: 1, Activated: true}
+	list := &data.List{ID: 7, UserID: owner.ID, Name: "watchlist", Visibility: data.ListPublic}
+
+	testCases := []struct {
+		name          string
+		user          *data.User
+		body          any
+		buildStubs    func(t *testing.T, mockLists *mockdb.MockListQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test Add List Item Handler - 201 CREATED",
+			user: owner,
+			body: map[string]any{"movie_id": 3, "position": 1},
+			buildStubs: func(t *testing.T, mockLists *mockdb.MockListQuerier) {
+				mockLists.EXPECT().
+					AddItem(&data.ListItem{ListID: list.ID, MovieID: 3, Position: 1}).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, r.Code)
+			},
+		},
+		{
+			name: "Test Add List Item Handler - 403 LIST OF ANOTHER USER",
+			user: &data.User{ID: 2, Activated: true},
+			body: map[string]any{"movie_id": 3},
+			buildStubs: func(t *testing.T, mockLists *mockdb.MockListQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, r.Code)
+			},
+		},
+		{
+			name: "Test Add List Item Handler - 422 MOVIE ALREADY IN THE LIST",
+			user: owner,
+			body: map[string]any{"movie_id": 3},
+			buildStubs: func(t *testing.T, mockLists *mockdb.MockListQuerier) {
+				mockLists.EXPECT().AddItem(gomock.Any()).Return(data.ErrDuplicateListItem)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "movie_id")
+			},
+		},
+		{
+			name: "Test Add List Item Handler - 422 UNKNOWN MOVIE",
+			user: owner,
+			body: map[string]any{"movie_id": 3},
+			buildStubs: func(t *testing.T, mockLists *mockdb.MockListQuerier) {
+				mockLists.EXPECT().AddItem(gomock.Any()).Return(data.ErrUnknownMovie)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "movie_id")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test, mockLists := newListTest(t, "/v1/lists/7/items")
+
+			stored := *list
+			mockLists.EXPECT().Get(list.ID).Return(&stored, nil)
+			tc.buildStubs(t, mockLists)
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodPost, "/v1/lists/:id/items", test.app.addListItemHandler)
+
+			body, err := toReader(tc.body)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, test.url, body)
+			request = test.app.contextSetUser(request, tc.user)
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestApplyListItemUpdate(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	earlier := now.Add(-48 * time.Hour)
+	later := now.Add(time.Hour)
+
+	yes, no := true, false
+	position := 2
+
+	testCases := []struct {
+		name     string
+		item     data.ListItem
+		input    UpdateListItemRequest
+		expected data.ListItem
+		errors   []string
+	}{
+		{
+			name:     "watched without a date was watched now",
+			item:     data.ListItem{Position: 1},
+			input:    UpdateListItemRequest{Watched: &yes},
+			expected: data.ListItem{Position: 1, Watched: true, WatchedAt: &now},
+		},
+		{
+			name:     "watched again keeps the date",
+			item:     data.ListItem{Position: 1, Watched: true, WatchedAt: &earlier},
+			input:    UpdateListItemRequest{Watched: &yes, Position: &position},
+			expected: data.ListItem{Position: 2, Watched: true, WatchedAt: &earlier},
+		},
+		{
+			name:     "unwatched clears the date",
+			item:     data.ListItem{Position: 1, Watched: true, WatchedAt: &earlier},
+			input:    UpdateListItemRequest{Watched: &no},
+			expected: data.ListItem{Position: 1},
+		},
+		{
+			name:     "a date marks it as watched",
+			item:     data.ListItem{Position: 1},
+			input:    UpdateListItemRequest{WatchedAt: &earlier},
+			expected: data.ListItem{Position: 1, Watched: true, WatchedAt: &earlier},
+		},
+		{
+			name:   "a date in the future",
+			item:   data.ListItem{Position: 1},
+			input:  UpdateListItemRequest{WatchedAt: &later},
+			errors: []string{"watched_at"},
+		},
+		{
+			name:   "a date for an unwatched movie",
+			item:   data.ListItem{Position: 1},
+			input:  UpdateListItemRequest{Watched: &no, WatchedAt: &earlier},
+			errors: []string{"watched_at"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := validator.New()
+			item := tc.item
+
+			applyListItemUpdate(&item, tc.input, now, v)
+
+			if len(tc.errors) > 0 {
+				for _, key := range tc.errors {
+					require.Contains(t, v.Errors, key)
+				}
+				return
+			}
+
+			require.True(t, v.Valid())
+			require.Equal(t, tc.expected, item)
+		})
+	}
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"errors"
 	"expvar"
 	"flag"
 	"fmt"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -13,7 +15,8 @@ import (
 	"github.com/djudju12/greenlight/internal/data"
 	"github.com/djudju12/greenlight/internal/jsonlog"
 	"github.com/djudju12/greenlight/internal/mailer"
-	_ "github.com/lib/pq"
+	"github.com/djudju12/greenlight/internal/storage"
+	"github.com/lib/pq"
 )
 
 var (
@@ -26,9 +29,11 @@ type config struct {
 	env     string
 	db      data.DBCfg
 	limiter struct {
-		rps    float64
-		burst  int
-		enable bool
+		rps          float64
+		burst        int
+		suggestRps   float64
+		suggestBurst int
+		enable       bool
 	}
 
 	smtp struct {
@@ -42,14 +47,52 @@ type config struct {
 	cors struct {
 		trustedOrigins []string
 	}
+
+	stats struct {
+		cacheTTL time.Duration
+	}
+
+	imports struct {
+		maxBytes  int64
+		batchSize int
+	}
+
+	uploads struct {
+		maxBytes int64
+		dir      string
+	}
+
+	idempotency struct {
+		ttl           time.Duration
+		lease         time.Duration
+		purgeInterval time.Duration
+	}
+
+	events struct {
+		heartbeat     time.Duration
+		retention     time.Duration
+		purgeInterval time.Duration
+	}
+
+	webhooks struct {
+		pollInterval time.Duration
+		batchSize    int
+		timeout      time.Duration
+		maxAttempts  int
+		backoff      time.Duration
+		maxBackoff   time.Duration
+	}
 }
 
 type application struct {
-	config config
-	logger *jsonlog.Logger
-	models *data.Models
-	mailer mailer.Mailer
-	wg     sync.WaitGroup
+	config  config
+	logger  *jsonlog.Logger
+	models  *data.Models
+	mailer  mailer.Mailer
+	wg      sync.WaitGroup
+	stats   statsCache
+	events  *movieEventBroker
+	storage storage.Storage
 }
 
 func main() {
@@ -63,6 +106,8 @@ func main() {
 
 	flag.IntVar(&cfg.limiter.burst, "limiter-burst", 4, "Rate limiter maximum burst")
 	flag.Float64Var(&cfg.limiter.rps, "limiter-rps", 2, "Rate limiter request per second")
+	flag.Float64Var(&cfg.limiter.suggestRps, "limiter-suggest-rps", 20, "Rate limiter request per second for movie suggestions")
+	flag.IntVar(&cfg.limiter.suggestBurst, "limiter-suggest-burst", 40, "Rate limiter maximum burst for movie suggestions")
 	flag.BoolVar(&cfg.limiter.enable, "limiter-enable", true, "Eanble rate limiter")
 
 	flag.StringVar(&cfg.smtp.host, "smtp-host", "sandbox.smtp.mailtrap.io", "SMTP host")
@@ -77,6 +122,32 @@ func main() {
 			return nil
 		})
 
+	flag.DurationVar(&cfg.stats.cacheTTL, "stats-cache-ttl", 5*time.Minute, "How long the catalog statistics are cached")
+
+	flag.Int64Var(&cfg.imports.maxBytes, "import-max-bytes", 32<<20, "Maximum size of a movie import body")
+	// InsertMany never gets past an empty batch, so the size is checked here
+	positiveIntVar(flag.CommandLine, &cfg.imports.batchSize, "import-batch-size", 1000, "Number of movies inserted per batch on imports")
+
+	flag.Int64Var(&cfg.uploads.maxBytes, "upload-max-bytes", 10<<20, "Maximum size of an uploaded image")
+	flag.StringVar(&cfg.uploads.dir, "upload-dir", "./uploads", "Directory where the uploaded images are stored")
+
+	flag.DurationVar(&cfg.idempotency.ttl, "idempotency-ttl", 24*time.Hour, "How long the responses of requests with an Idempotency-Key are kept")
+	// the lease outlasts the writeTimeout, so a key is only freed before its
+	// request is over if the server stopped
+	flag.DurationVar(&cfg.idempotency.lease, "idempotency-lease", time.Minute, "How long an Idempotency-Key is held for a request that is still running")
+	flag.DurationVar(&cfg.idempotency.purgeInterval, "idempotency-purge-interval", time.Hour, "How often the expired idempotency keys are deleted")
+
+	flag.DurationVar(&cfg.events.heartbeat, "events-heartbeat", 15*time.Second, "How often a heartbeat is sent on idle movie event streams")
+	flag.DurationVar(&cfg.events.retention, "events-retention", 7*24*time.Hour, "How long the movie events are kept to resume streams from")
+	flag.DurationVar(&cfg.events.purgeInterval, "events-purge-interval", time.Hour, "How often the old movie events are deleted")
+
+	flag.DurationVar(&cfg.webhooks.pollInterval, "webhooks-poll-interval", 5*time.Second, "How often the queue of webhook deliveries is checked when empty")
+	flag.IntVar(&cfg.webhooks.batchSize, "webhooks-batch-size", 10, "Number of webhook deliveries sent at a time")
+	flag.DurationVar(&cfg.webhooks.timeout, "webhooks-timeout", 10*time.Second, "Timeout of a webhook delivery attempt")
+	flag.IntVar(&cfg.webhooks.maxAttempts, "webhooks-max-attempts", 8, "Number of attempts before a webhook delivery fails")
+	flag.DurationVar(&cfg.webhooks.backoff, "webhooks-backoff", 30*time.Second, "Delay before the first retry of a webhook delivery, doubled on every retry")
+	flag.DurationVar(&cfg.webhooks.maxBackoff, "webhooks-max-backoff", 6*time.Hour, "Maximum delay between retries of a webhook delivery")
+
 	displayVersion := flag.Bool("version", false, "Display version and exit")
 
 	flag.Parse()
@@ -117,10 +188,52 @@ func main() {
 		models: data.NewModels(db),
 		mailer: mailer.New(
 			cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		events:  newMovieEventBroker(),
+		storage: storage.NewLocal(cfg.uploads.dir),
+	}
+
+	// the listener has a connection of its own, outside of the pool
+	listener := pq.NewListener(cfg.db.Dsn, 10*time.Second, time.Minute,
+		func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				logger.PrintError(err, nil)
+			}
+		})
+
+	err = listener.Listen(data.MovieEventsChannel)
+	if err != nil {
+		logger.PrintFatal(err, nil)
 	}
 
+	defer listener.Close()
+
+	go app.purgeIdempotencyKeys(cfg.idempotency.purgeInterval)
+	go app.listenMovieEvents(listener)
+	go app.purgeMovieEvents(cfg.events.retention, cfg.events.purgeInterval)
+	go app.deliverWebhooks()
+
 	err = app.serve()
 	if err != nil {
 		logger.PrintFatal(err, nil)
 	}
 }
+
+// positiveIntVar defines an int flag like fs.IntVar, refusing the values below
+// 1 when the flags are parsed.
+func positiveIntVar(fs *flag.FlagSet, p *int, name string, value int, usage string) {
+	*p = value
+
+	fs.Func(name, fmt.Sprintf("%s (default %d)", usage, value), func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+
+		if n < 1 {
+			return errors.New("must be at least 1")
+		}
+
+		*p = n
+		return nil
+	})
+}
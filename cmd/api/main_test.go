@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPositiveIntVar(t *testing.T) {
+	testCases := []struct {
+		name    string
+		args    []string
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "default value",
+			want: 1000,
+		},
+		{
+			name: "value given",
+			args: []string{"-import-batch-size=50"},
+			want: 50,
+		},
+		{
+			name: "smallest value",
+			args: []string{"-import-batch-size=1"},
+			want: 1,
+		},
+		{
+			name:    "zero",
+			args:    []string{"-import-batch-size=0"},
+			wantErr: true,
+		},
+		{
+			name:    "negative",
+			args:    []string{"-import-batch-size=-5"},
+			wantErr: true,
+		},
+		{
+			name:    "not a number",
+			args:    []string{"-import-batch-size=many"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			fs := flag.NewFlagSet("api", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+
+			var batchSize int
+			positiveIntVar(fs, &batchSize, "import-batch-size", 1000, "Number of movies inserted per batch on imports")
+
+			// when
+			err := fs.Parse(tc.args)
+
+			// then
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, batchSize)
+		})
+	}
+}
@@ -32,6 +32,12 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 			// Use the builtin recover function to check if there has been a panic or
 			// not.
 			if err := recover(); err != nil {
+				// http.ErrAbortHandler is how a handler that already started writing
+				// its response aborts it, so let net/http handle it.
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
 				// If there was a panic, set a "Connection: close" header on the
 				// response. This acts as a trigger to make Go's HTTP server
 				// automatically close the current connection after a response has been
@@ -50,7 +56,10 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 	})
 }
 
-func (app *application) rateLimit(next http.Handler) http.Handler {
+// rateLimit allows each client IP rps requests per second, with bursts of up to
+// burst requests. Every call keeps its own set of clients, so routes wrapped by
+// different calls don't share their limits.
+func (app *application) rateLimit(rps float64, burst int, next http.Handler) http.Handler {
 
 	type client struct {
 		limiter  *rate.Limiter
@@ -88,8 +97,7 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 
 			if _, found := clients[ip]; !found {
 				clients[ip] = &client{
-					limiter: rate.NewLimiter(rate.Limit(app.config.limiter.rps),
-						app.config.limiter.burst)}
+					limiter: rate.NewLimiter(rate.Limit(rps), burst)}
 			}
 
 			clients[ip].lastSeen = time.Now()
@@ -111,6 +119,41 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 	})
 }
 
+// routeByPath sends the requests for the paths in routes to their own handler,
+// and every other request to next.
+func (app *application) routeByPath(next http.Handler, routes map[string]http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h, ok := routes[r.URL.Path]; ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// negotiateResponse picks the format of the response of the requests that
+// change data before they run, and answers 406 Not Acceptable when the client
+// accepts none, as once the handler wrote to the database it's too late for
+// that. Those responses never hold a list, so they can't be sent as CSV.
+func (app *application) negotiateResponse(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		enc, ok := app.responseEncoder(r, envelope{})
+		if !ok {
+			app.notAcceptableResponse(w, r, offeredMediaTypes(envelope{})...)
+			return
+		}
+
+		next.ServeHTTP(w, app.contextSetEncoder(r, enc))
+	})
+}
+
 func (app *application) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Add the "Vary: Authorization" header to the response. This indicates to any
@@ -231,7 +274,7 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 						// reflect the Origin header without checking against a list of trusted origins.
 						// Otherwise this would leave your service vulnerable to a distributed brute-force
 						// attack against any authentication credentials that are passed in that header.
-						w.Header().Add("Access-Control-Allow-Headers", "Authorization, Content-Type")
+						w.Header().Add("Access-Control-Allow-Headers", "Authorization, Content-Type, Idempotency-Key, "+runtimeFormatHeader)
 
 						w.WriteHeader(http.StatusOK)
 						return
@@ -4,6 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/djudju12/greenlight/internal/data"
 	"github.com/djudju12/greenlight/internal/validator"
@@ -32,11 +35,41 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	v := validator.New()
+
+	view := app.readMovieView(r, v)
+
+	// a movie that looks like one already in the catalog is refused, unless the
+	// client confirms it is a different one with allow_duplicates
+	allowDuplicates := app.readBool(r.URL.Query(), "allow_duplicates", false, v)
+
 	if data.ValidateMovie(v, movie); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
+	err = normalizeGenres(app.models.Genres, v, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	duplicates, err := app.models.Movies.FindDuplicates(movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if len(duplicates) > 0 && !allowDuplicates {
+		v.AddError("title", fmt.Sprintf("a movie with the same title and year already exists (%s), use allow_duplicates=true to create it anyway", duplicateIDs(duplicates)))
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	err = app.models.Movies.Insert(movie)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -44,9 +77,20 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	headers := make(http.Header)
-	headers.Set("Location", fmt.Sprintf("/v1/movies/%d", movie.ID))
+	headers.Set("Location", movieURL(movie.ID))
+
+	rendered, err := view.render(app, append([]*data.Movie{movie}, duplicates...))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{"movie": rendered[0]}
+	if len(duplicates) > 0 {
+		env["duplicates"] = rendered[1:]
+	}
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"movie": movie}, headers)
+	err = app.writeResponse(w, r, http.StatusCreated, env, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -59,7 +103,15 @@ func (app *application) showMovieHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	movie, err := app.models.Movies.Get(id)
+	v := validator.New()
+
+	view := app.readMovieView(r, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movie, err := app.models.Movies.GetFields(id, view.Fields)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -71,7 +123,29 @@ func (app *application) showMovieHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	headers := make(http.Header)
+	w.Header().Add("Vary", "Accept-Language")
+	w.Header().Add("Vary", runtimeFormatHeader)
+
+	if view.selects("title") {
+		languages, err := app.localizeTitles(r, []*data.Movie{movie})
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if languages[0] != "" {
+			headers.Set("Content-Language", languages[0])
+		}
+	}
+
+	rendered, err := view.renderOne(app, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"movie": rendered}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -91,11 +165,24 @@ func (app *application) updatesMovieHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	var input UpdateMovieRequest
-
-	err = app.readJSON(w, r, &input)
+	patch, err := app.readMoviePatch(w, r)
 	if err != nil {
-		app.badRequestResponse(w, r, err)
+		switch {
+		case errors.Is(err, errUnsupportedPatch):
+			w.Header().Set("Accept-Patch", strings.Join(moviePatchMediaTypes, ", "))
+			app.unsupportedMediaTypeResponse(w, r, moviePatchMediaTypes...)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+
+		return
+	}
+
+	v := validator.New()
+
+	view := app.readMovieView(r, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
@@ -110,6 +197,68 @@ func (app *application) updatesMovieHandler(w http.ResponseWriter, r *http.Reque
 
 		return
 	}
+
+	err = patch(movie, v)
+	if err != nil {
+		var testError *jsonPatchTestError
+		switch {
+		case errors.As(err, &testError):
+			app.patchTestFailedResponse(w, r, testError)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if data.ValidateMovie(v, movie); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = normalizeGenres(app.models.Genres, v, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Movies.Update(movie)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	rendered, err := view.renderOne(app, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"movie": rendered}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// applyMovieUpdate copies to movie the fields that are present in input.
+func applyMovieUpdate(movie *data.Movie, input UpdateMovieRequest) {
 	if input.Title != nil {
 		movie.Title = *input.Title
 	}
@@ -125,19 +274,102 @@ func (app *application) updatesMovieHandler(w http.ResponseWriter, r *http.Reque
 	if input.Genres != nil {
 		movie.Genres = input.Genres
 	}
+}
+
+// ReplaceMovieRequest holds every field of the movie. Version is optional, and
+// when it's given the movie is only replaced while it's still at that version.
+type ReplaceMovieRequest struct {
+	Title   string       `json:"title"`
+	Year    int32        `json:"year"`
+	Runtime data.Runtime `json:"runtime"`
+	Genres  []string     `json:"genres"`
+	Version *int32       `json:"version"`
+}
+
+// replaceMovieHandler replaces every field of the movie with the ones in the
+// request. When there is no movie with the ID, it's created with that ID, so
+// that movies can be kept in sync with another source. Only the IDs already
+// handed out to movies can be chosen, see InsertWithID.
+func (app *application) replaceMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input ReplaceMovieRequest
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	replacement := &data.Movie{
+		ID:      id,
+		Title:   input.Title,
+		Year:    input.Year,
+		Runtime: input.Runtime,
+		Genres:  input.Genres,
+	}
 
 	v := validator.New()
 
-	if data.ValidateMovie(v, movie); !v.Valid() {
+	view := app.readMovieView(r, v)
+
+	if data.ValidateMovie(v, replacement); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Movies.Update(movie)
+	err = normalizeGenres(app.models.Genres, v, replacement)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	status := http.StatusOK
+	headers := make(http.Header)
+
+	movie, err := app.models.Movies.Get(id)
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound) && input.Version != nil:
+		// the client expects the movie to exist, like the updates of a batch
+		app.notFoundResponse(w, r)
+		return
+
+	case errors.Is(err, data.ErrRecordNotFound):
+		movie = replacement
+		err = app.models.Movies.InsertWithID(movie)
+
+		status = http.StatusCreated
+		headers.Set("Location", movieURL(movie.ID))
+
+	case err == nil:
+		if input.Version != nil && *input.Version != movie.Version {
+			app.editConflictResponse(w, r)
+			return
+		}
+
+		movie.Title = replacement.Title
+		movie.Year = replacement.Year
+		movie.Runtime = replacement.Runtime
+		movie.Genres = replacement.Genres
+
+		err = app.models.Movies.Update(movie)
+	}
+
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrUnissuedMovieID):
+			v.AddError("id", "must be the id of a movie created before, new movies get theirs from POST /v1/movies")
+			app.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
@@ -145,11 +377,16 @@ func (app *application) updatesMovieHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	rendered, err := view.renderOne(app, movie)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+
+	err = app.writeResponse(w, r, status, envelope{"movie": rendered}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 }
 
 func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Request) {
@@ -170,16 +407,55 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "movie successfully deleteds"}, nil)
+	app.deleteMoviePosters(id)
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"message": "movie successfully deleteds"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
 type ListMoviesRequest struct {
-	Title  string
-	Genres []string
+	data.MovieFilters
 	data.Filters
+	Facets []string
+}
+
+var movieSortSafelist = []string{
+	"id",
+	"title",
+	"year",
+	"runtime",
+	"-id",
+	"-title",
+	"-year",
+	"-runtime",
+	"rating",
+	"-rating",
+	"relevance",
+}
+
+// readMovieFilters reads the query string parameters shared by every endpoint
+// that lists movies.
+func (app *application) readMovieFilters(qs url.Values, v *validator.Validator) data.MovieFilters {
+	var mf data.MovieFilters
+
+	mf.Title = app.readString(qs, "title", "")
+	mf.SearchMode = app.readString(qs, "search_mode", data.SearchFullText)
+	mf.Genres = app.readCSV(qs, "genres", []string{})
+	mf.GenresAny = app.readCSV(qs, "genres_any", []string{})
+	mf.GenresExclude = app.readCSV(qs, "genres_exclude", []string{})
+	mf.YearMin = app.readInt(qs, "year_min", 0, v)
+	mf.YearMax = app.readInt(qs, "year_max", 0, v)
+	mf.RuntimeMin = app.readInt(qs, "runtime_min", 0, v)
+	mf.RuntimeMax = app.readInt(qs, "runtime_max", 0, v)
+	mf.CreatedAfter = app.readTime(qs, "created_after", time.Time{}, v)
+	mf.CreatedBefore = app.readTime(qs, "created_before", time.Time{}, v)
+	mf.Person = int64(app.readInt(qs, "person", 0, v))
+	mf.PersonRole = app.readString(qs, "person_role", "")
+	mf.ExternalProvider, mf.ExternalID, _ = strings.Cut(app.readString(qs, "external_id", ""), ":")
+
+	return mf
 }
 
 func (app *application) listMoviesHandles(w http.ResponseWriter, r *http.Request) {
@@ -188,40 +464,103 @@ func (app *application) listMoviesHandles(w http.ResponseWriter, r *http.Request
 	v := validator.New()
 	qs := r.URL.Query()
 
-	input.Title = app.readString(qs, "title", "")
-	input.Genres = app.readCSV(qs, "genres", []string{})
+	input.MovieFilters = app.readMovieFilters(qs, v)
 
-	input.SortSafelist = []string{
-		"id",
-		"title",
-		"year",
-		"runtime",
-		"-id",
-		"-title",
-		"-year",
-		"-runtime",
-	}
+	input.SortSafelist = movieSortSafelist
 	input.Sort = app.readString(qs, "sort", "id")
 	input.Page = app.readInt(qs, "page", 1, v)
 	input.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Facets = app.readCSV(qs, "facets", []string{})
 
+	view := app.readMovieView(r, v)
+	input.Fields = view.Fields
+
+	data.ValidateMovieFilters(v, input.MovieFilters)
+	data.ValidateFacets(v, input.Facets)
 	if data.ValidateFilter(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	movies, filterMetadata, err := app.models.Movies.GetAll(input.Title, input.Genres, input.Filters)
+	movies, filterMetadata, err := app.models.Movies.GetAll(input.MovieFilters, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	w.Header().Add("Vary", "Accept-Language")
+	w.Header().Add("Vary", runtimeFormatHeader)
+
+	if view.selects("title") {
+		_, err = app.localizeTitles(r, movies)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	table, err := view.renderTable(app, movies)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	links := paginationLinks(r, filterMetadata)
+
+	response := envelope{
 		"metadata": filterMetadata,
-		"movies": movies,
-		}, nil)
+		"movies":   table,
+	}
+
+	if view.Links {
+		links["self"] = r.URL.RequestURI()
+		response["links"] = links
+	}
+
+	// the facets are computed over every movie matching the filters, not only
+	// over the current page
+	if len(input.Facets) > 0 {
+		facets, err := app.models.Movies.GetFacets(input.MovieFilters, input.Facets)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		response["facets"] = facets
+	}
+
+	headers := make(http.Header)
+	if link := linkHeader(links); link != "" {
+		headers.Set("Link", link)
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, response, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) suggestMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	prefix := strings.TrimSpace(app.readString(qs, "q", ""))
+	limit := app.readInt(qs, "limit", 10, v)
+
+	if data.ValidateSuggestionQuery(v, prefix, limit); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	suggestions, err := app.models.Movies.Suggest(prefix, limit)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"suggestions": suggestions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 }
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/djudju12/greenlight/internal/data"
+	"github.com/djudju12/greenlight/internal/validator"
+)
+
+const (
+	// batchAtomic commits the operations only if every one of them succeeds.
+	batchAtomic = "atomic"
+	// batchBestEffort commits the operations that succeed and reports the others.
+	batchBestEffort = "best_effort"
+
+	batchCreate = "create"
+	batchUpdate = "update"
+	batchDelete = "delete"
+)
+
+var maxBatchOperations = 100
+
+// BatchOperation is a single create, update or delete. Updates and deletes only
+// apply while the movie is still at Version. Movie holds a CreateMovieRequest
+// for creates and an UpdateMovieRequest for updates.
+type BatchOperation struct {
+	Op      string          `json:"op"`
+	ID      int64           `json:"id,omitempty"`
+	Version int32           `json:"version,omitempty"`
+	Movie   json.RawMessage `json:"movie,omitempty"`
+}
+
+type BatchMoviesRequest struct {
+	Mode       string           `json:"mode"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchResult reports what happened to the operation at Index. Status is the
+// HTTP status the operation would have gotten as a single request, and Movie
+// is the created or updated movie, rendered like in the single responses.
+type BatchResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	Status int    `json:"status"`
+	Movie  any    `json:"movie,omitempty"`
+	Error  any    `json:"error,omitempty"`
+}
+
+type BatchReport struct {
+	Mode      string        `json:"mode"`
+	Committed bool          `json:"committed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// batchOperationError is an operation that failed because of what was asked
+// (an invalid movie, a stale version...) rather than because of the server.
+type batchOperationError struct {
+	status  int
+	message any
+}
+
+func (e *batchOperationError) Error() string {
+	return fmt.Sprintf("batch operation failed with status %d: %v", e.status, e.message)
+}
+
+var errBatchRolledBack = errors.New("batch rolled back")
+
+func validateBatchMoviesRequest(v *validator.Validator, input BatchMoviesRequest) {
+	v.Check(validator.In(input.Mode, batchAtomic, batchBestEffort), "mode", "invalid batch mode")
+
+	v.Check(len(input.Operations) >= 1, "operations", "must contain at least 1 operation")
+	v.Check(len(input.Operations) <= maxBatchOperations, "operations",
+		fmt.Sprintf("must not contain more than %d operations", maxBatchOperations))
+
+	for i, op := range input.Operations {
+		key := fmt.Sprintf("operations[%d]", i)
+
+		v.Check(validator.In(op.Op, batchCreate, batchUpdate, batchDelete), key+".op", "invalid operation")
+
+		if op.Op == batchUpdate || op.Op == batchDelete {
+			v.Check(op.ID > 0, key+".id", "must be provided")
+			v.Check(op.Version > 0, key+".version", "must be provided")
+		}
+
+		if op.Op == batchCreate || op.Op == batchUpdate {
+			v.Check(len(op.Movie) > 0, key+".movie", "must be provided")
+		}
+	}
+}
+
+func (app *application) batchMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	var input BatchMoviesRequest
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Mode == "" {
+		input.Mode = batchAtomic
+	}
+
+	v := validator.New()
+
+	view := app.readMovieView(r, v)
+
+	if validateBatchMoviesRequest(v, input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	report := BatchReport{
+		Mode:      input.Mode,
+		Committed: true,
+		Results:   make([]BatchResult, len(input.Operations)),
+	}
+
+	status := http.StatusOK
+
+	err = app.models.Movies.InTx(func(tx data.MovieQuerier) error {
+		for i, op := range input.Operations {
+			var movie *data.Movie
+			var opStatus int
+
+			run := func(q data.MovieQuerier) error {
+				var err error
+				movie, opStatus, err = runBatchOperation(q, app.models.Genres, op)
+				return err
+			}
+
+			// in best effort mode every operation runs inside its own savepoint,
+			// so a failed one is undone without aborting the whole transaction
+			if input.Mode == batchBestEffort {
+				err = tx.InTx(run)
+			} else {
+				err = run(tx)
+			}
+
+			report.Results[i] = BatchResult{Index: i, Op: op.Op}
+
+			var opErr *batchOperationError
+			switch {
+			case errors.As(err, &opErr):
+				report.Results[i].Status = opErr.status
+				report.Results[i].Error = opErr.message
+
+				if input.Mode == batchAtomic {
+					status = opErr.status
+					rollbackBatch(report.Results, i)
+					return errBatchRolledBack
+				}
+			case err != nil:
+				return err
+			default:
+				report.Results[i].Status = opStatus
+
+				// deletes have no movie, and a nil *data.Movie would be sent as null
+				if movie != nil {
+					report.Results[i].Movie = movie
+				}
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, errBatchRolledBack):
+			report.Committed = false
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if report.Committed {
+		err = renderBatchMovies(app, view, report.Results)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	// the posters are only removed once the deletes are committed, and in best
+	// effort mode only for the deletes that succeeded
+	if report.Committed {
+		for i, result := range report.Results {
+			if result.Op == batchDelete && result.Error == nil {
+				app.deleteMoviePosters(input.Operations[i].ID)
+			}
+		}
+	}
+
+	err = app.writeResponse(w, r, status, envelope{"batch": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// renderBatchMovies renders the movies of the results with the view, all at
+// once so that the related data is loaded a single time.
+func renderBatchMovies(app *application, view MovieView, results []BatchResult) error {
+	var (
+		movies  []*data.Movie
+		indexes []int
+	)
+
+	for i, result := range results {
+		if movie, ok := result.Movie.(*data.Movie); ok {
+			movies = append(movies, movie)
+			indexes = append(indexes, i)
+		}
+	}
+
+	rendered, err := view.render(app, movies)
+	if err != nil {
+		return err
+	}
+
+	for i, movie := range rendered {
+		results[indexes[i]].Movie = movie
+	}
+
+	return nil
+}
+
+// rollbackBatch marks every result but the failed one as not applied.
+func rollbackBatch(results []BatchResult, failed int) {
+	for i := range results {
+		switch {
+		case i < failed:
+			results[i].Movie = nil
+			results[i].Status = http.StatusFailedDependency
+			results[i].Error = fmt.Sprintf("rolled back because operation %d failed", failed)
+		case i > failed:
+			results[i] = BatchResult{
+				Index:  i,
+				Status: http.StatusFailedDependency,
+				Error:  fmt.Sprintf("not executed because operation %d failed", failed),
+			}
+		}
+	}
+}
+
+// runBatchOperation applies op using q, checking the genres of the movie
+// against genres. It returns the affected movie (nil on
+// deletes) and the status of the operation, or a *batchOperationError when the
+// operation can't be applied.
+func runBatchOperation(q data.MovieQuerier, genres data.GenreQuerier, op BatchOperation) (*data.Movie, int, error) {
+	switch op.Op {
+	case batchCreate:
+		var input CreateMovieRequest
+		if err := decodeJSONStrict(op.Movie, &input); err != nil {
+			return nil, 0, &batchOperationError{http.StatusBadRequest, err.Error()}
+		}
+
+		movie := &data.Movie{
+			Title:   input.Title,
+			Year:    input.Year,
+			Runtime: input.Runtime,
+			Genres:  input.Genres,
+		}
+
+		v := validator.New()
+		if data.ValidateMovie(v, movie); !v.Valid() {
+			return nil, 0, &batchOperationError{http.StatusUnprocessableEntity, v.Errors}
+		}
+
+		if err := normalizeGenres(genres, v, movie); err != nil {
+			return nil, 0, err
+		}
+
+		if !v.Valid() {
+			return nil, 0, &batchOperationError{http.StatusUnprocessableEntity, v.Errors}
+		}
+
+		if err := q.Insert(movie); err != nil {
+			return nil, 0, err
+		}
+
+		return movie, http.StatusCreated, nil
+
+	case batchUpdate:
+		var input UpdateMovieRequest
+		if err := decodeJSONStrict(op.Movie, &input); err != nil {
+			return nil, 0, &batchOperationError{http.StatusBadRequest, err.Error()}
+		}
+
+		movie, err := getBatchMovie(q, op)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		applyMovieUpdate(movie, input)
+
+		v := validator.New()
+		if data.ValidateMovie(v, movie); !v.Valid() {
+			return nil, 0, &batchOperationError{http.StatusUnprocessableEntity, v.Errors}
+		}
+
+		if err := normalizeGenres(genres, v, movie); err != nil {
+			return nil, 0, err
+		}
+
+		if !v.Valid() {
+			return nil, 0, &batchOperationError{http.StatusUnprocessableEntity, v.Errors}
+		}
+
+		err = q.Update(movie)
+		if err != nil {
+			if errors.Is(err, data.ErrEditConflict) {
+				return nil, 0, editConflictOperationError()
+			}
+
+			return nil, 0, err
+		}
+
+		return movie, http.StatusOK, nil
+
+	case batchDelete:
+		if _, err := getBatchMovie(q, op); err != nil {
+			return nil, 0, err
+		}
+
+		err := q.DeleteVersion(op.ID, op.Version)
+		if err != nil {
+			if errors.Is(err, data.ErrEditConflict) {
+				return nil, 0, editConflictOperationError()
+			}
+
+			return nil, 0, err
+		}
+
+		return nil, http.StatusOK, nil
+	}
+
+	// unreachable, the operations are validated before they run
+	panic("unknown batch operation: " + op.Op)
+}
+
+// getBatchMovie fetches the movie targeted by op, checking that it is still at
+// the version the client expects.
+func getBatchMovie(q data.MovieQuerier, op BatchOperation) (*data.Movie, error) {
+	movie, err := q.Get(op.ID)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return nil, &batchOperationError{http.StatusNotFound, "the request resource could not be found"}
+		}
+
+		return nil, err
+	}
+
+	if movie.Version != op.Version {
+		return nil, editConflictOperationError()
+	}
+
+	return movie, nil
+}
+
+func editConflictOperationError() *batchOperationError {
+	return &batchOperationError{
+		http.StatusConflict,
+		"unable to update the record due to an edit conflict, please try again",
+	}
+}
+
+// decodeJSONStrict decodes raw into dst, rejecting unknown fields like readJSON.
+func decodeJSONStrict(raw json.RawMessage, dst any) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err != nil {
+		return fmt.Errorf("movie contains invalid JSON: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/djudju12/greenlight/internal/data"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/djudju12/greenlight/internal/storage"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestBatchMoviesHandler(t *testing.T) {
+	newMovie := randomMovie()
+	updatedMovie := randomMovie()
+	deletedMovie := randomMovie()
+	deletedPoster := &data.Poster{MovieID: deletedMovie.ID, Checksum: "0123456789abcdef0123456789abcdef"}
+
+	createOp := BatchOperation{
+		Op: batchCreate,
+		Movie: requireJSON(t, CreateMovieRequest{
+			Title:   newMovie.Title,
+			Year:    newMovie.Year,
+			Runtime: newMovie.Runtime,
+			Genres:  newMovie.Genres,
+		}),
+	}
+
+	newTitle := "a new title"
+	updateOp := BatchOperation{
+		Op:      batchUpdate,
+		ID:      updatedMovie.ID,
+		Version: updatedMovie.Version,
+		Movie:   requireJSON(t, UpdateMovieRequest{Title: &newTitle}),
+	}
+
+	deleteOp := BatchOperation{
+		Op:      batchDelete,
+		ID:      deletedMovie.ID,
+		Version: deletedMovie.Version,
+	}
+
+	runInTx := func(mockMovies *mockdb.MockMovieQuerier) func(fn func(data.MovieQuerier) error) error {
+		return func(fn func(data.MovieQuerier) error) error {
+			return fn(mockMovies)
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		requestBody   BatchMoviesRequest
+		buildStubs    func(t *testing.T, mockMovies *mockdb.MockMovieQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+		// whether the poster of deletedMovie is removed from the storage
+		postersDeleted bool
+	}{
+		{
+			name: "Test Batch Movies Handler - 200 OK ATOMIC",
+			requestBody: BatchMoviesRequest{
+				Operations: []BatchOperation{createOp, updateOp, deleteOp},
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().InTx(gomock.Any()).DoAndReturn(runInTx(mockMovies))
+
+				mockMovies.EXPECT().
+					Insert(EqMovieRequest(newMovie)).
+					DoAndReturn(func(m *data.Movie) error {
+						m.ID = newMovie.ID
+						m.Version = 1
+						return nil
+					})
+
+				current := *updatedMovie
+				mockMovies.EXPECT().Get(updatedMovie.ID).Return(&current, nil)
+				mockMovies.EXPECT().Update(gomock.Any()).Return(nil)
+
+				mockMovies.EXPECT().Get(deletedMovie.ID).Return(deletedMovie, nil)
+				mockMovies.EXPECT().DeleteVersion(deletedMovie.ID, deletedMovie.Version).Return(nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				report := requireBatchReport(t, r)
+				require.True(t, report.Committed)
+				require.Equal(t, batchAtomic, report.Mode)
+				require.Len(t, report.Results, 3)
+
+				require.Equal(t, http.StatusCreated, report.Results[0].Status)
+				require.EqualValues(t, newMovie.ID, report.Results[0].Movie.(map[string]any)["id"])
+				require.Equal(t, http.StatusOK, report.Results[1].Status)
+				require.Equal(t, newTitle, report.Results[1].Movie.(map[string]any)["title"])
+				require.Equal(t, http.StatusOK, report.Results[2].Status)
+				require.Nil(t, report.Results[2].Movie)
+			},
+			postersDeleted: true,
+		},
+		{
+			name:  "Test Batch Movies Handler - 200 OK RUNTIME FORMAT",
+			query: "?runtime_format=integer",
+			requestBody: BatchMoviesRequest{
+				Operations: []BatchOperation{createOp, deleteOp},
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().InTx(gomock.Any()).DoAndReturn(runInTx(mockMovies))
+				mockMovies.EXPECT().Insert(EqMovieRequest(newMovie)).Return(nil)
+
+				mockMovies.EXPECT().Get(deletedMovie.ID).Return(deletedMovie, nil)
+				mockMovies.EXPECT().DeleteVersion(deletedMovie.ID, deletedMovie.Version).Return(nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				report := requireBatchReport(t, r)
+				require.EqualValues(t, newMovie.Runtime, report.Results[0].Movie.(map[string]any)["runtime"])
+				require.Nil(t, report.Results[1].Movie)
+			},
+			postersDeleted: true,
+		},
+		{
+			name:  "Test Batch Movies Handler - 422 INVALID RUNTIME FORMAT",
+			query: "?runtime_format=seconds",
+			requestBody: BatchMoviesRequest{
+				Operations: []BatchOperation{createOp},
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "runtime_format")
+			},
+		},
+		{
+			name: "Test Batch Movies Handler - 409 ATOMIC ROLLED BACK ON STALE VERSION",
+			requestBody: BatchMoviesRequest{
+				Mode:       batchAtomic,
+				Operations: []BatchOperation{createOp, updateOp, deleteOp},
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().InTx(gomock.Any()).DoAndReturn(runInTx(mockMovies))
+				mockMovies.EXPECT().Insert(gomock.Any()).Return(nil)
+
+				stale := *updatedMovie
+				stale.Version++
+				mockMovies.EXPECT().Get(updatedMovie.ID).Return(&stale, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, r.Code)
+
+				report := requireBatchReport(t, r)
+				require.False(t, report.Committed)
+				require.Len(t, report.Results, 3)
+
+				require.Equal(t, http.StatusFailedDependency, report.Results[0].Status)
+				require.Nil(t, report.Results[0].Movie)
+				require.Equal(t, http.StatusConflict, report.Results[1].Status)
+				require.Equal(t, http.StatusFailedDependency, report.Results[2].Status)
+			},
+		},
+		{
+			name: "Test Batch Movies Handler - 409 ATOMIC ROLLED BACK AFTER DELETE",
+			requestBody: BatchMoviesRequest{
+				Mode:       batchAtomic,
+				Operations: []BatchOperation{deleteOp, updateOp},
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().InTx(gomock.Any()).DoAndReturn(runInTx(mockMovies))
+
+				mockMovies.EXPECT().Get(deletedMovie.ID).Return(deletedMovie, nil)
+				mockMovies.EXPECT().DeleteVersion(deletedMovie.ID, deletedMovie.Version).Return(nil)
+
+				stale := *updatedMovie
+				stale.Version++
+				mockMovies.EXPECT().Get(updatedMovie.ID).Return(&stale, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, r.Code)
+
+				report := requireBatchReport(t, r)
+				require.False(t, report.Committed)
+				require.Equal(t, http.StatusFailedDependency, report.Results[0].Status)
+			},
+		},
+		{
+			name: "Test Batch Movies Handler - 200 OK BEST EFFORT WITH FAILED OPERATION",
+			requestBody: BatchMoviesRequest{
+				Mode:       batchBestEffort,
+				Operations: []BatchOperation{updateOp, deleteOp},
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				// the batch transaction plus one savepoint per operation
+				mockMovies.EXPECT().InTx(gomock.Any()).Times(3).DoAndReturn(runInTx(mockMovies))
+
+				mockMovies.EXPECT().Get(updatedMovie.ID).Return(nil, data.ErrRecordNotFound)
+
+				mockMovies.EXPECT().Get(deletedMovie.ID).Return(deletedMovie, nil)
+				mockMovies.EXPECT().DeleteVersion(deletedMovie.ID, deletedMovie.Version).Return(nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				report := requireBatchReport(t, r)
+				require.True(t, report.Committed)
+				require.Equal(t, http.StatusNotFound, report.Results[0].Status)
+				require.NotNil(t, report.Results[0].Error)
+				require.Equal(t, http.StatusOK, report.Results[1].Status)
+			},
+			postersDeleted: true,
+		},
+		{
+			name: "Test Batch Movies Handler - 200 OK BEST EFFORT WITH INVALID MOVIE",
+			requestBody: BatchMoviesRequest{
+				Mode: batchBestEffort,
+				Operations: []BatchOperation{{
+					Op:    batchCreate,
+					Movie: requireJSON(t, CreateMovieRequest{Title: "no year"}),
+				}},
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().InTx(gomock.Any()).Times(2).DoAndReturn(runInTx(mockMovies))
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				report := requireBatchReport(t, r)
+				require.Equal(t, http.StatusUnprocessableEntity, report.Results[0].Status)
+			},
+		},
+		{
+			name: "Test Batch Movies Handler - 422 INVALID OPERATIONS",
+			requestBody: BatchMoviesRequest{
+				Mode:       "sometimes",
+				Operations: []BatchOperation{{Op: batchDelete}},
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+
+				var envelope struct {
+					Error map[string]string `json:"error"`
+				}
+				err := json.NewDecoder(r.Body).Decode(&envelope)
+				require.NoError(t, err)
+				require.Contains(t, envelope.Error, "mode")
+				require.Contains(t, envelope.Error, "operations[0].id")
+				require.Contains(t, envelope.Error, "operations[0].version")
+			},
+		},
+		{
+			name: "Test Batch Movies Handler - 500 DB RETURN ERROR",
+			requestBody: BatchMoviesRequest{
+				Operations: []BatchOperation{createOp},
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().InTx(gomock.Any()).DoAndReturn(runInTx(mockMovies))
+				mockMovies.EXPECT().Insert(gomock.Any()).Return(errors.New("DB ERROR"))
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, r.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, "/v1/movies/batch"+tc.query)
+
+			for _, size := range posterSizes() {
+				err := test.app.storage.Put(posterKey(deletedPoster, size), bytes.NewReader([]byte(size+" poster")))
+				require.NoError(t, err)
+			}
+
+			mockMovies, ok := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+			require.True(t, ok)
+			tc.buildStubs(t, mockMovies)
+
+			body, err := toReader(tc.requestBody)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, test.url, body)
+
+			// when
+			test.app.batchMoviesHandler(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+
+			f, err := test.app.storage.Open(posterKey(deletedPoster, posterOriginal))
+			if tc.postersDeleted {
+				require.ErrorIs(t, err, storage.ErrNotFound)
+			} else {
+				require.NoError(t, err)
+				require.NoError(t, f.Close())
+			}
+
+			test.close()
+		})
+	}
+}
+
+func requireJSON(t *testing.T, v any) json.RawMessage {
+	js, err := json.Marshal(v)
+	require.NoError(t, err)
+
+	return js
+}
+
+func requireBatchReport(t *testing.T, r *httptest.ResponseRecorder) BatchReport {
+	var envelope struct {
+		Batch BatchReport `json:"batch"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&envelope)
+	require.NoError(t, err)
+
+	return envelope.Batch
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/data"
+	"github.com/djudju12/greenlight/internal/validator"
+)
+
+const (
+	mediaTypeNDJSON = "application/x-ndjson"
+	mediaTypeCSV    = "text/csv"
+)
+
+// exportFlushEvery is how many movies are buffered before they are sent to the
+// client. Every flush also pushes back the write deadline of the connection.
+var exportFlushEvery = 100
+
+func (app *application) exportMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	var input ListMoviesRequest
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.MovieFilters = app.readMovieFilters(qs, v)
+	input.SortSafelist = movieSortSafelist
+	input.Sort = app.readString(qs, "sort", "id")
+
+	data.ValidateMovieFilters(v, input.MovieFilters)
+	v.Check(validator.In(input.Sort, input.SortSafelist...), "sort", "invalid sort value")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	mediaType := app.negotiate(r, mediaTypeNDJSON, mediaTypeCSV)
+	if mediaType == "" {
+		app.notAcceptableResponse(w, r, mediaTypeNDJSON, mediaTypeCSV)
+		return
+	}
+
+	buf := bufio.NewWriter(w)
+
+	var write func(movie *data.Movie) error
+	var flush func() error
+
+	switch mediaType {
+	case mediaTypeCSV:
+		cw := csv.NewWriter(buf)
+		header := true
+
+		write = func(movie *data.Movie) error {
+			if header {
+				header = false
+				if err := cw.Write(exportCSVColumns); err != nil {
+					return err
+				}
+			}
+
+			record := make([]string, len(exportCSVColumns))
+			for i, field := range exportCSVColumns {
+				record[i] = movieCSVField(movie, field)
+			}
+
+			return cw.Write(record)
+		}
+
+		flush = func() error {
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return err
+			}
+
+			return buf.Flush()
+		}
+
+	default:
+		// json.Encoder ends every value with a newline, which is all NDJSON needs
+		enc := json.NewEncoder(buf)
+
+		write = func(movie *data.Movie) error {
+			return enc.Encode(movie)
+		}
+
+		flush = buf.Flush
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Content-Disposition", `attachment; filename="movies`+exportExtension(mediaType)+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	// the export can take longer than the server's WriteTimeout, so the deadline
+	// is moved forward every time a chunk is sent. A stuck client still gets
+	// disconnected.
+	rc := http.NewResponseController(w)
+
+	count := 0
+	err := app.models.Movies.Stream(input.MovieFilters, input.Filters, func(movie *data.Movie) error {
+		if err := write(movie); err != nil {
+			return err
+		}
+
+		count++
+		if count%exportFlushEvery != 0 {
+			return nil
+		}
+
+		if err := flush(); err != nil {
+			return err
+		}
+
+		_ = rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+		return rc.Flush()
+	})
+
+	if err == nil {
+		err = flush()
+	}
+
+	if err != nil {
+		// the status line was already sent, so the only way to tell the client
+		// that the export is incomplete is to abort the response
+		app.logError(r, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+var exportCSVColumns = []string{"id", "title", "year", "runtime", "genres"}
+
+// movieCSVField formats a field of the movie for CSV. The runtime is a plain
+// number of minutes and the genres are joined the same way the import expects
+// them, so that an export can be imported back.
+func movieCSVField(movie *data.Movie, field string) string {
+	switch field {
+	case "id":
+		return strconv.FormatInt(movie.ID, 10)
+	case "title":
+		return movie.Title
+	case "year":
+		return strconv.Itoa(int(movie.Year))
+	case "runtime":
+		return strconv.Itoa(int(movie.Runtime))
+	case "genres":
+		return strings.Join(movie.Genres, csvGenresSeparator)
+	case "version":
+		return strconv.Itoa(int(movie.Version))
+	case "rating":
+		return strconv.FormatFloat(movie.Rating, 'f', 2, 64)
+	case "votes":
+		return strconv.Itoa(int(movie.Votes))
+	default:
+		return ""
+	}
+}
+
+func exportExtension(mediaType string) string {
+	if mediaType == mediaTypeCSV {
+		return ".csv"
+	}
+
+	return ".ndjson"
+}
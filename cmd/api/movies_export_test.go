@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/djudju12/greenlight/internal/data"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestExportMoviesHandler(t *testing.T) {
+	var movies []*data.Movie
+	for i := 0; i < 3; i++ {
+		movies = append(movies, randomMovie())
+	}
+
+	streamMovies := func(mf data.MovieFilters, f data.Filters, fn func(*data.Movie) error) error {
+		for _, movie := range movies {
+			if err := fn(movie); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	testCases := []struct {
+		name          string
+		url           string
+		accept        string
+		buildStubs    func(t *testing.T, app *application)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test Export Movies Handler - 200 OK NDJSON BY DEFAULT",
+			url:  "/v1/movies/export?year_min=1990&sort=-year",
+			buildStubs: func(t *testing.T, app *application) {
+				mockMovies, ok := app.models.Movies.(*mockdb.MockMovieQuerier)
+				require.True(t, ok)
+
+				expectedFilters := data.MovieFilters{
+					SearchMode:    data.SearchFullText,
+					Genres:        []string{},
+					GenresAny:     []string{},
+					GenresExclude: []string{},
+					YearMin:       1990,
+				}
+
+				mockMovies.EXPECT().
+					Stream(expectedFilters, data.Filters{Sort: "-year", SortSafelist: movieSortSafelist}, gomock.Any()).
+					DoAndReturn(streamMovies)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, mediaTypeNDJSON, r.Header().Get("Content-Type"))
+
+				scanner := bufio.NewScanner(r.Body)
+				for _, movie := range movies {
+					require.True(t, scanner.Scan())
+					require.Contains(t, scanner.Text(), `"runtime":"`+strconv.Itoa(int(movie.Runtime))+` mins"`)
+
+					var actual data.Movie
+					err := json.Unmarshal(scanner.Bytes(), &actual)
+					require.NoError(t, err)
+					requireMovieMatch(t, movie, &actual)
+				}
+				require.False(t, scanner.Scan())
+			},
+		},
+		{
+			name:   "Test Export Movies Handler - 200 OK CSV",
+			url:    "/v1/movies/export",
+			accept: "application/json;q=0.9, text/csv",
+			buildStubs: func(t *testing.T, app *application) {
+				mockMovies, ok := app.models.Movies.(*mockdb.MockMovieQuerier)
+				require.True(t, ok)
+
+				mockMovies.EXPECT().
+					Stream(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(streamMovies)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, mediaTypeCSV, r.Header().Get("Content-Type"))
+
+				records, err := csv.NewReader(r.Body).ReadAll()
+				require.NoError(t, err)
+				require.Len(t, records, len(movies)+1)
+				require.Equal(t, []string{"id", "title", "year", "runtime", "genres"}, records[0])
+
+				for i, movie := range movies {
+					require.Equal(t, []string{
+						strconv.FormatInt(movie.ID, 10),
+						movie.Title,
+						strconv.Itoa(int(movie.Year)),
+						strconv.Itoa(int(movie.Runtime)),
+						strings.Join(movie.Genres, "|"),
+					}, records[i+1])
+				}
+			},
+		},
+		{
+			name:   "Test Export Movies Handler - 406 NOT ACCEPTABLE",
+			url:    "/v1/movies/export",
+			accept: "application/xml",
+			buildStubs: func(t *testing.T, app *application) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotAcceptable, r.Code)
+			},
+		},
+		{
+			name: "Test Export Movies Handler - 422 INVALID SORT",
+			url:  "/v1/movies/export?sort=genres",
+			buildStubs: func(t *testing.T, app *application) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, tc.url)
+			tc.buildStubs(t, test.app)
+
+			request := httptest.NewRequest(http.MethodGet, test.url, nil)
+			if tc.accept != "" {
+				request.Header.Set("Accept", tc.accept)
+			}
+
+			// when
+			test.app.exportMoviesHandler(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestExportMoviesHandlerAbortsOnError(t *testing.T) {
+	test := newMovieTest(t, "/v1/movies/export")
+	defer test.close()
+
+	mockMovies, ok := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+	require.True(t, ok)
+
+	mockMovies.EXPECT().
+		Stream(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(errors.New("DB ERROR"))
+
+	request := httptest.NewRequest(http.MethodGet, test.url, nil)
+
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		test.app.exportMoviesHandler(test.recorder, request)
+	})
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/djudju12/greenlight/internal/data"
+	"github.com/djudju12/greenlight/internal/validator"
+)
+
+// In CSV files the genres of a movie are a single column, with the genres
+// separated by this character.
+const csvGenresSeparator = "|"
+
+var csvMovieColumns = []string{"title", "year", "runtime", "genres"}
+
+// the id column written by the CSV export is accepted, but the imported movies
+// always get new IDs
+var csvIgnoredColumns = []string{"id"}
+
+// ImportRowError reports why a row of the import was not accepted. Line is the
+// line of the row in the uploaded file, starting at 1.
+type ImportRowError struct {
+	Line   int               `json:"line"`
+	Errors map[string]string `json:"errors"`
+}
+
+type ImportReport struct {
+	DryRun      bool             `json:"dry_run"`
+	TotalRows   int              `json:"total_rows"`
+	ValidRows   int              `json:"valid_rows"`
+	InvalidRows int              `json:"invalid_rows"`
+	Inserted    int              `json:"inserted"`
+	Errors      []ImportRowError `json:"errors"`
+}
+
+// importRow is a parsed row. When the row couldn't be parsed or validated, the
+// errors are in v and movie must not be inserted.
+type importRow struct {
+	line  int
+	movie *data.Movie
+	v     *validator.Validator
+}
+
+func (app *application) importMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	dryRun := app.readBool(r.URL.Query(), "dry_run", false, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = ""
+	}
+
+	var parse func(io.Reader) ([]importRow, error)
+	switch mediaType {
+	case "text/csv":
+		parse = parseMoviesCSV
+	case "application/x-ndjson", "application/ndjson":
+		parse = parseMoviesNDJSON
+	default:
+		app.unsupportedMediaTypeResponse(w, r, "text/csv", "application/x-ndjson")
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, app.config.imports.maxBytes)
+
+	rows, err := parse(body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			err = fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		}
+
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if len(rows) == 0 {
+		app.badRequestResponse(w, r, errors.New("body must contain at least one movie"))
+		return
+	}
+
+	report := ImportReport{
+		DryRun:    dryRun,
+		TotalRows: len(rows),
+		Errors:    []ImportRowError{},
+	}
+
+	// the genres of every row are resolved at once
+	parsed := make([]*data.Movie, 0, len(rows))
+	for _, row := range rows {
+		parsed = append(parsed, row.movie)
+	}
+
+	slugs, err := resolveGenres(app.models.Genres, parsed...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	movies := make([]*data.Movie, 0, len(rows))
+	for _, row := range rows {
+		if row.v.Valid() {
+			data.ValidateMovie(row.v, row.movie)
+		}
+
+		if row.v.Valid() {
+			data.NormalizeGenres(row.v, row.movie, slugs)
+		}
+
+		if !row.v.Valid() {
+			report.Errors = append(report.Errors, ImportRowError{Line: row.line, Errors: row.v.Errors})
+			continue
+		}
+
+		movies = append(movies, row.movie)
+	}
+
+	report.ValidRows = len(movies)
+	report.InvalidRows = len(report.Errors)
+
+	if !dryRun && len(movies) > 0 {
+		err = app.models.Movies.InsertMany(movies, app.config.imports.batchSize)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		report.Inserted = len(movies)
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"import": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// parseMoviesCSV reads a CSV file whose first line is a header naming the
+// columns, in any order. The year and runtime columns are plain integers.
+func parseMoviesCSV(body io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("body must not be empty")
+		}
+
+		return nil, csvError(err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if validator.In(name, csvIgnoredColumns...) {
+			continue
+		}
+
+		if !validator.In(name, csvMovieColumns...) {
+			return nil, fmt.Errorf("csv header contains unknown column %q", name)
+		}
+
+		columns[name] = i
+	}
+
+	for _, name := range csvMovieColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("csv header must contain the %q column", name)
+		}
+	}
+
+	rows := []importRow{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, csvError(err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := importRow{line: line, movie: &data.Movie{}, v: validator.New()}
+
+		if len(record) != len(header) {
+			row.v.AddError("row", fmt.Sprintf("must have %d columns", len(header)))
+			rows = append(rows, row)
+			continue
+		}
+
+		row.movie.Title = record[columns["title"]]
+
+		year, err := strconv.ParseInt(record[columns["year"]], 10, 32)
+		if err != nil {
+			row.v.AddError("year", "must be an integer value")
+		}
+		row.movie.Year = int32(year)
+
+		// partner feeds write runtimes in every format, not only in minutes
+		runtime, err := data.ParseRuntime(record[columns["runtime"]])
+		if err != nil {
+			row.v.AddError("runtime", "must be a number of minutes or a duration like 1h 42m")
+		}
+		row.movie.Runtime = runtime
+
+		row.movie.Genres = []string{}
+		for _, genre := range strings.Split(record[columns["genres"]], csvGenresSeparator) {
+			if genre = strings.TrimSpace(genre); genre != "" {
+				row.movie.Genres = append(row.movie.Genres, genre)
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// csvError keeps the size limit error so it can be reported as such, and
+// rewords every other error of the csv package.
+func csvError(err error) error {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return err
+	}
+
+	var parseError *csv.ParseError
+	if errors.As(err, &parseError) {
+		return fmt.Errorf("body contains badly-formed CSV (at line %d)", parseError.Line)
+	}
+
+	return err
+}
+
+// parseMoviesNDJSON reads one movie per line, each one in the same format
+// accepted by POST /v1/movies. Blank lines are ignored.
+func parseMoviesNDJSON(body io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), JsonMaxBytes)
+
+	rows := []importRow{}
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		row := importRow{line: line, movie: &data.Movie{}, v: validator.New()}
+
+		var input struct {
+			CreateMovieRequest
+
+			// written by the export, ignored like the id column of CSV files
+			ID      json.RawMessage `json:"id"`
+			Version json.RawMessage `json:"version"`
+			Rating  json.RawMessage `json:"rating"`
+			Votes   json.RawMessage `json:"votes"`
+			Score   json.RawMessage `json:"score"`
+		}
+
+		dec := json.NewDecoder(strings.NewReader(text))
+		dec.DisallowUnknownFields()
+
+		err := dec.Decode(&input)
+		if err == nil && dec.More() {
+			err = errors.New("line must only contain a single JSON value")
+		}
+
+		if err != nil {
+			row.v.AddError("row", err.Error())
+			rows = append(rows, row)
+			continue
+		}
+
+		row.movie.Title = input.Title
+		row.movie.Year = input.Year
+		row.movie.Runtime = input.Runtime
+		row.movie.Genres = input.Genres
+
+		rows = append(rows, row)
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, fmt.Errorf("lines must not be larger than %d bytes", JsonMaxBytes)
+		}
+
+		return nil, err
+	}
+
+	return rows, nil
+}
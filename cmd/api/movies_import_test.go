@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/djudju12/greenlight/internal/data"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestImportMoviesHandler(t *testing.T) {
+	testCases := []struct {
+		name          string
+		query         string
+		contentType   string
+		body          string
+		buildStubs    func(t *testing.T, app *application)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name:        "Test Import Movies Handler - 200 OK CSV",
+			contentType: "text/csv",
+			body: "title,year,runtime,genres\n" +
+				"Casablanca,1942,102,drama|romance\n" +
+				"\"Monty Python, the Holy Grail\",1975,91,comedy\n",
+			buildStubs: func(t *testing.T, app *application) {
+				mockMovies, ok := app.models.Movies.(*mockdb.MockMovieQuerier)
+				require.True(t, ok)
+
+				mockMovies.EXPECT().
+					InsertMany([]*data.Movie{
+						{Title: "Casablanca", Year: 1942, Runtime: 102, Genres: []string{"drama", "romance"}},
+						{Title: "Monty Python, the Holy Grail", Year: 1975, Runtime: 91, Genres: []string{"comedy"}},
+					}, 2).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				report := requireImportReport(t, r)
+				require.Equal(t, 2, report.TotalRows)
+				require.Equal(t, 2, report.Inserted)
+				require.Empty(t, report.Errors)
+			},
+		},
+		{
+			name:        "Test Import Movies Handler - 200 OK CSV WITH FORMATTED RUNTIMES",
+			contentType: "text/csv",
+			body: "title,year,runtime,genres\n" +
+				"Casablanca,1942,1h 42m,drama|romance\n" +
+				"\"Monty Python, the Holy Grail\",1975,PT1H31M,comedy\n",
+			buildStubs: func(t *testing.T, app *application) {
+				mockMovies, ok := app.models.Movies.(*mockdb.MockMovieQuerier)
+				require.True(t, ok)
+
+				mockMovies.EXPECT().
+					InsertMany([]*data.Movie{
+						{Title: "Casablanca", Year: 1942, Runtime: 102, Genres: []string{"drama", "romance"}},
+						{Title: "Monty Python, the Holy Grail", Year: 1975, Runtime: 91, Genres: []string{"comedy"}},
+					}, 2).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				report := requireImportReport(t, r)
+				require.Equal(t, 2, report.TotalRows)
+				require.Equal(t, 2, report.Inserted)
+				require.Empty(t, report.Errors)
+			},
+		},
+		{
+			name:        "Test Import Movies Handler - 200 OK NDJSON WITH INVALID ROWS",
+			contentType: "application/x-ndjson",
+			body: `{"id": 7, "title": "Casablanca", "year": 1942, "runtime": "102 mins", "genres": ["drama"], "version": 2}` + "\n" +
+				"\n" +
+				`{"title": "", "year": 1942, "runtime": "102 mins", "genres": ["drama"]}` + "\n" +
+				`{"title": "Broken", "year": "soon"}` + "\n",
+			buildStubs: func(t *testing.T, app *application) {
+				mockMovies, ok := app.models.Movies.(*mockdb.MockMovieQuerier)
+				require.True(t, ok)
+
+				mockMovies.EXPECT().
+					InsertMany([]*data.Movie{
+						{Title: "Casablanca", Year: 1942, Runtime: 102, Genres: []string{"drama"}},
+					}, 2).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				report := requireImportReport(t, r)
+				require.Equal(t, 3, report.TotalRows)
+				require.Equal(t, 1, report.ValidRows)
+				require.Equal(t, 2, report.InvalidRows)
+				require.Equal(t, 1, report.Inserted)
+
+				require.Len(t, report.Errors, 2)
+				require.Equal(t, 3, report.Errors[0].Line)
+				require.Contains(t, report.Errors[0].Errors, "title")
+				require.Equal(t, 4, report.Errors[1].Line)
+				require.Contains(t, report.Errors[1].Errors, "row")
+			},
+		},
+		{
+			name:        "Test Import Movies Handler - 200 OK DRY RUN",
+			query:       "?dry_run=true",
+			contentType: "text/csv",
+			body: "genres,title,runtime,year\n" +
+				"drama,Casablanca,102,1942\n" +
+				"drama,Casablanca,ten,1942\n",
+			buildStubs: func(t *testing.T, app *application) {
+				t.Log("nothing is inserted on a dry run")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				report := requireImportReport(t, r)
+				require.True(t, report.DryRun)
+				require.Equal(t, 1, report.ValidRows)
+				require.Equal(t, 0, report.Inserted)
+				require.Len(t, report.Errors, 1)
+				require.Equal(t, 3, report.Errors[0].Line)
+				require.Contains(t, report.Errors[0].Errors, "runtime")
+			},
+		},
+		{
+			name:        "Test Import Movies Handler - 400 CSV WITHOUT REQUIRED COLUMN",
+			contentType: "text/csv",
+			body:        "title,year\nCasablanca,1942\n",
+			buildStubs: func(t *testing.T, app *application) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, r.Code)
+			},
+		},
+		{
+			name:        "Test Import Movies Handler - 400 BODY TOO LARGE",
+			contentType: "application/x-ndjson",
+			body:        strings.Repeat(`{"title": "Casablanca"}`+"\n", 100),
+			buildStubs: func(t *testing.T, app *application) {
+				app.config.imports.maxBytes = 64
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, r.Code)
+			},
+		},
+		{
+			name:        "Test Import Movies Handler - 415 UNSUPPORTED CONTENT TYPE",
+			contentType: "application/xml",
+			body:        "<movies></movies>",
+			buildStubs: func(t *testing.T, app *application) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnsupportedMediaType, r.Code)
+			},
+		},
+		{
+			name:        "Test Import Movies Handler - 500 DB RETURN ERROR",
+			contentType: "text/csv",
+			body:        "title,year,runtime,genres\nCasablanca,1942,102,drama\n",
+			buildStubs: func(t *testing.T, app *application) {
+				mockMovies, ok := app.models.Movies.(*mockdb.MockMovieQuerier)
+				require.True(t, ok)
+
+				mockMovies.EXPECT().
+					InsertMany(gomock.Any(), gomock.Any()).
+					Return(errors.New("DB ERROR"))
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, r.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, "/v1/movies/import"+tc.query)
+			test.app.config.imports.maxBytes = 1 << 20
+			test.app.config.imports.batchSize = 2
+
+			tc.buildStubs(t, test.app)
+
+			request := httptest.NewRequest(http.MethodPost, test.url, strings.NewReader(tc.body))
+			request.Header.Set("Content-Type", tc.contentType)
+
+			// when
+			test.app.importMoviesHandler(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func requireImportReport(t *testing.T, r *httptest.ResponseRecorder) ImportReport {
+	var envelope struct {
+		Import ImportReport `json:"import"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&envelope)
+	require.NoError(t, err)
+
+	return envelope.Import
+}
+
+// the NDJSON export of movies, reviewed ones included, is imported back as is
+func TestImportMoviesHandlerExportRoundTrip(t *testing.T) {
+	movie := randomMovie()
+	movie.Rating = 7.5
+	movie.Votes = 4
+
+	export := newMovieTest(t, "/v1/movies/export")
+	defer export.close()
+
+	mockMovies, ok := export.app.models.Movies.(*mockdb.MockMovieQuerier)
+	require.True(t, ok)
+
+	mockMovies.EXPECT().
+		Stream(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(mf data.MovieFilters, f data.Filters, fn func(*data.Movie) error) error {
+			return fn(movie)
+		})
+
+	export.app.exportMoviesHandler(export.recorder, httptest.NewRequest(http.MethodGet, export.url, nil))
+	require.Equal(t, http.StatusOK, export.recorder.Code)
+	require.Contains(t, export.recorder.Body.String(), `"votes":4`)
+
+	test := newMovieTest(t, "/v1/movies/import")
+	defer test.close()
+	test.app.config.imports.maxBytes = 1 << 20
+	test.app.config.imports.batchSize = 2
+
+	mockMovies, ok = test.app.models.Movies.(*mockdb.MockMovieQuerier)
+	require.True(t, ok)
+
+	mockMovies.EXPECT().
+		InsertMany([]*data.Movie{
+			{Title: movie.Title, Year: movie.Year, Runtime: movie.Runtime, Genres: movie.Genres},
+		}, 2).
+		Return(nil)
+
+	request := httptest.NewRequest(http.MethodPost, test.url, export.recorder.Body)
+	request.Header.Set("Content-Type", mediaTypeNDJSON)
+
+	test.app.importMoviesHandler(test.recorder, request)
+
+	require.Equal(t, http.StatusOK, test.recorder.Code)
+
+	report := requireImportReport(t, test.recorder)
+	require.Equal(t, 1, report.Inserted)
+	require.Empty(t, report.Errors)
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/djudju12/greenlight/internal/data"
+	"github.com/djudju12/greenlight/internal/validator"
+)
+
+// movieIncluder loads related data for the movies, to be embedded in them
+// under the name it's registered with. The returned map is keyed by movie ID,
+// and the movies missing from it get an empty value.
+type movieIncluder struct {
+	load  func(app *application, movies []*data.Movie) (map[int64]any, error)
+	empty func() any
+}
+
+// movieIncludes holds the related data that can be requested with include=.
+var movieIncludes = map[string]movieIncluder{
+	"revisions": {
+		load: func(app *application, movies []*data.Movie) (map[int64]any, error) {
+			revisions, err := app.models.Movies.GetRevisions(movieIDs(movies))
+			if err != nil {
+				return nil, err
+			}
+
+			loaded := make(map[int64]any, len(revisions))
+			for id, movieRevisions := range revisions {
+				loaded[id] = movieRevisions
+			}
+
+			return loaded, nil
+		},
+		empty: func() any { return []*data.MovieRevision{} },
+	},
+	"credits": {
+		load: func(app *application, movies []*data.Movie) (map[int64]any, error) {
+			credits, err := app.models.People.GetCreditsForMovies(movieIDs(movies))
+			if err != nil {
+				return nil, err
+			}
+
+			loaded := make(map[int64]any, len(credits))
+			for id, movieCredits := range credits {
+				loaded[id] = movieCredits
+			}
+
+			return loaded, nil
+		},
+		empty: func() any { return []*data.Credit{} },
+	},
+	"titles": {
+		load: func(app *application, movies []*data.Movie) (map[int64]any, error) {
+			titles, err := app.models.Movies.GetTitles(movieIDs(movies))
+			if err != nil {
+				return nil, err
+			}
+
+			loaded := make(map[int64]any, len(titles))
+			for id, movieTitles := range titles {
+				loaded[id] = movieTitles
+			}
+
+			return loaded, nil
+		},
+		empty: func() any { return []*data.MovieTitle{} },
+	},
+	"external_ids": {
+		load: func(app *application, movies []*data.Movie) (map[int64]any, error) {
+			externalIDs, err := app.models.Movies.GetExternalIDs(movieIDs(movies))
+			if err != nil {
+				return nil, err
+			}
+
+			loaded := make(map[int64]any, len(externalIDs))
+			for id, movieExternalIDs := range externalIDs {
+				loaded[id] = movieExternalIDs
+			}
+
+			return loaded, nil
+		},
+		empty: func() any { return []*data.ExternalID{} },
+	},
+}
+
+// runtimeFormatHeader chooses the format of the runtimes in the responses, like
+// the runtime_format query parameter, which takes precedence over it. Every
+// response with movies follows them, but the export, which always writes the
+// runtimes the way the import reads them.
+const runtimeFormatHeader = "X-Greenlight-Runtime-Format"
+
+// MovieView selects what is sent of each movie: the fields to serialize, with
+// no fields meaning all of them, the related data to embed, whether to add a
+// links object with the URL of the movie, and the format of the runtime.
+type MovieView struct {
+	Fields        []string
+	Include       []string
+	Links         bool
+	RuntimeFormat string
+}
+
+func (app *application) readMovieView(r *http.Request, v *validator.Validator) MovieView {
+	qs := r.URL.Query()
+
+	runtimeFormat := r.Header.Get(runtimeFormatHeader)
+	if runtimeFormat == "" {
+		runtimeFormat = data.RuntimeMinutes
+	}
+
+	view := MovieView{
+		Fields:        app.readCSV(qs, "fields", nil),
+		Include:       app.readCSV(qs, "include", nil),
+		Links:         app.readBool(qs, "links", false, v),
+		RuntimeFormat: app.readString(qs, "runtime_format", runtimeFormat),
+	}
+
+	data.ValidateMovieFields(v, view.Fields)
+
+	v.Check(validator.In(view.RuntimeFormat, data.RuntimeFormats...), "runtime_format", fmt.Sprintf("must be one of %v", data.RuntimeFormats))
+
+	for _, name := range view.Include {
+		_, ok := movieIncludes[name]
+		v.Check(ok, "include", fmt.Sprintf("must be one of %s", strings.Join(movieIncludeNames(), ", ")))
+	}
+
+	v.Check(validator.Unique(view.Include), "include", "must not contain duplicate values")
+
+	return view
+}
+
+// selects reports whether the field is sent, with no fields meaning all of them.
+func (view MovieView) selects(field string) bool {
+	return len(view.Fields) == 0 || slices.Contains(view.Fields, field)
+}
+
+// render returns the movies as they must be serialized. Without fields,
+// includes, links or another runtime format the movies are returned as they
+// are.
+func (view MovieView) render(app *application, movies []*data.Movie) ([]any, error) {
+	rendered := make([]any, len(movies))
+
+	if len(view.Fields) == 0 && len(view.Include) == 0 && !view.Links && view.RuntimeFormat == data.RuntimeMinutes {
+		for i, movie := range movies {
+			rendered[i] = movie
+		}
+
+		return rendered, nil
+	}
+
+	for i, movie := range movies {
+		rendered[i] = selectMovieFields(movie, view.Fields, view.RuntimeFormat)
+
+		if view.Links {
+			rendered[i].(envelope)["links"] = envelope{"self": movieURL(movie.ID)}
+		}
+	}
+
+	for _, name := range view.Include {
+		includer := movieIncludes[name]
+
+		loaded, err := includer.load(app, movies)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, movie := range movies {
+			value, ok := loaded[movie.ID]
+			if !ok {
+				value = includer.empty()
+			}
+
+			rendered[i].(envelope)[name] = value
+		}
+	}
+
+	return rendered, nil
+}
+
+// renderTable renders the movies as a list that can also be sent as CSV.
+func (view MovieView) renderTable(app *application, movies []*data.Movie) (movieTable, error) {
+	rendered, err := view.render(app, movies)
+	if err != nil {
+		return movieTable{}, err
+	}
+
+	return movieTable{movies: movies, rendered: rendered, view: view}, nil
+}
+
+func (view MovieView) renderOne(app *application, movie *data.Movie) (any, error) {
+	rendered, err := view.render(app, []*data.Movie{movie})
+	if err != nil {
+		return nil, err
+	}
+
+	return rendered[0], nil
+}
+
+// selectMovieFields returns only the selected fields of the movie, with no
+// fields meaning all of them, and its runtime in the format.
+func selectMovieFields(movie *data.Movie, fields []string, runtimeFormat string) envelope {
+	selected := envelope{}
+
+	if len(fields) == 0 {
+		fields = data.MovieFieldSafelist
+
+		if movie.Score != 0 {
+			selected["score"] = movie.Score
+		}
+	}
+
+	for _, field := range fields {
+		switch field {
+		case "id":
+			selected[field] = movie.ID
+		case "title":
+			selected[field] = movie.Title
+		case "year":
+			selected[field] = movie.Year
+		case "runtime":
+			selected[field] = movie.Runtime.In(runtimeFormat)
+		case "genres":
+			selected[field] = movie.Genres
+		case "version":
+			selected[field] = movie.Version
+		case "rating":
+			selected[field] = movie.Rating
+		case "votes":
+			selected[field] = movie.Votes
+		}
+	}
+
+	return selected
+}
+
+func movieIncludeNames() []string {
+	names := make([]string, 0, len(movieIncludes))
+	for name := range movieIncludes {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+	return names
+}
+
+func movieIDs(movies []*data.Movie) []int64 {
+	ids := make([]int64, len(movies))
+	for i, movie := range movies {
+		ids[i] = movie.ID
+	}
+
+	return ids
+}
+
+// movieTable is a rendered list of movies. As CSV, the fields are formatted
+// like in the export and the included data is written as JSON.
+type movieTable struct {
+	movies   []*data.Movie
+	rendered []any
+	view     MovieView
+}
+
+var _ csvTable = movieTable{}
+
+func (t movieTable) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.rendered)
+}
+
+func (t movieTable) fields() []string {
+	if len(t.view.Fields) == 0 {
+		return data.MovieFieldSafelist
+	}
+
+	return t.view.Fields
+}
+
+func (t movieTable) csvHeader() []string {
+	return append(slices.Clone(t.fields()), t.view.Include...)
+}
+
+func (t movieTable) csvRecords() ([][]string, error) {
+	fields := t.fields()
+	records := make([][]string, len(t.movies))
+
+	for i, movie := range t.movies {
+		record := make([]string, 0, len(fields)+len(t.view.Include))
+		for _, field := range fields {
+			record = append(record, movieCSVField(movie, field))
+		}
+
+		for _, name := range t.view.Include {
+			js, err := json.Marshal(t.rendered[i].(envelope)[name])
+			if err != nil {
+				return nil, err
+			}
+
+			record = append(record, string(js))
+		}
+
+		records[i] = record
+	}
+
+	return records, nil
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/data"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestShowMovieHandlerView(t *testing.T) {
+	movie := randomMovie()
+	revision := &data.MovieRevision{
+		Version:   1,
+		Title:     "old title",
+		Year:      movie.Year,
+		Runtime:   movie.Runtime,
+		Genres:    movie.Genres,
+		RevisedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	unrated := *movie
+	unrated.Rating, unrated.Votes = 0, 0
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(t *testing.T, mockMovies *mockdb.MockMovieQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "Test Show Movie Handler - 200 OK ONLY SELECTED FIELDS",
+			query: "?fields=title,year",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().
+					GetFields(movie.ID, []string{"title", "year"}).
+					Return(&data.Movie{ID: movie.ID, Title: movie.Title, Year: movie.Year}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				var envelope struct {
+					Movie map[string]any `json:"movie"`
+				}
+				err := json.NewDecoder(r.Body).Decode(&envelope)
+				require.NoError(t, err)
+
+				require.Len(t, envelope.Movie, 2)
+				require.Equal(t, movie.Title, envelope.Movie["title"])
+				require.EqualValues(t, movie.Year, envelope.Movie["year"])
+			},
+		},
+		{
+			name:  "Test Show Movie Handler - 200 OK INCLUDE REVISIONS",
+			query: "?fields=id&include=revisions",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().
+					GetFields(movie.ID, []string{"id"}).
+					Return(&data.Movie{ID: movie.ID}, nil)
+
+				mockMovies.EXPECT().
+					GetRevisions([]int64{movie.ID}).
+					Return(map[int64][]*data.MovieRevision{movie.ID: {revision}}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				var envelope struct {
+					Movie struct {
+						ID        int64                 `json:"id"`
+						Revisions []*data.MovieRevision `json:"revisions"`
+					} `json:"movie"`
+				}
+				err := json.NewDecoder(r.Body).Decode(&envelope)
+				require.NoError(t, err)
+
+				require.Equal(t, movie.ID, envelope.Movie.ID)
+				require.Equal(t, []*data.MovieRevision{revision}, envelope.Movie.Revisions)
+			},
+		},
+		{
+			name: "Test Show Movie Handler - 200 OK UNRATED MOVIE",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().GetFields(movie.ID, nil).Return(&unrated, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				requireUnratedMovie(t, r)
+			},
+		},
+		{
+			name:  "Test Show Movie Handler - 200 OK UNRATED MOVIE WITH LINKS",
+			query: "?links=true",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().GetFields(movie.ID, nil).Return(&unrated, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				requireUnratedMovie(t, r)
+			},
+		},
+		{
+			name:  "Test Show Movie Handler - 422 INVALID FIELDS AND INCLUDE",
+			query: "?fields=title,created_at&include=cast",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+
+				errs := requireErrorMap(t, r)
+				require.Contains(t, errs, "fields")
+				require.Contains(t, errs, "include")
+			},
+		},
+		{
+			name:  "Test Show Movie Handler - 500 DB RETURN ERROR ON INCLUDE",
+			query: "?include=revisions",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().
+					GetFields(movie.ID, nil).
+					Return(movie, nil)
+
+				mockMovies.EXPECT().
+					GetRevisions(gomock.Any()).
+					Return(nil, errors.New("DB ERROR"))
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, r.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, fmt.Sprintf("/v1/movies/%d%s", movie.ID, tc.query))
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodGet, "/v1/movies/:id", test.app.showMovieHandler)
+
+			mockMovies, ok := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+			require.True(t, ok)
+			tc.buildStubs(t, mockMovies)
+
+			request := httptest.NewRequest(http.MethodGet, test.url, nil)
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+// requireUnratedMovie checks that a movie without reviews is still sent with its
+// rating and votes.
+func requireUnratedMovie(t *testing.T, r *httptest.ResponseRecorder) {
+	var envelope struct {
+		Movie map[string]any `json:"movie"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&envelope)
+	require.NoError(t, err)
+
+	require.Contains(t, envelope.Movie, "rating")
+	require.EqualValues(t, 0, envelope.Movie["rating"])
+	require.Contains(t, envelope.Movie, "votes")
+	require.EqualValues(t, 0, envelope.Movie["votes"])
+}
+
+func TestListMoviesHandlerView(t *testing.T) {
+	// given
+	movies := []*data.Movie{randomMovie(), randomMovie()}
+	test := newMovieTest(t, "/v1/movies?fields=title&include=revisions")
+
+	mockMovies, ok := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+	require.True(t, ok)
+
+	mockMovies.EXPECT().
+		GetAll(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(mf data.MovieFilters, f data.Filters) ([]*data.Movie, data.Metadata, error) {
+			require.Equal(t, []string{"title"}, f.Fields)
+			return movies, data.Metadata{}, nil
+		})
+
+	mockMovies.EXPECT().
+		GetRevisions([]int64{movies[0].ID, movies[1].ID}).
+		Return(map[int64][]*data.MovieRevision{movies[1].ID: {{Version: 1}}}, nil)
+
+	request := httptest.NewRequest(http.MethodGet, test.url, nil)
+
+	// when
+	test.app.listMoviesHandles(test.recorder, request)
+
+	// then
+	require.Equal(t, http.StatusOK, test.recorder.Code)
+
+	var envelope struct {
+		Movies []map[string]json.RawMessage `json:"movies"`
+	}
+	err := json.NewDecoder(test.recorder.Body).Decode(&envelope)
+	require.NoError(t, err)
+
+	require.Len(t, envelope.Movies, 2)
+	for i, movie := range envelope.Movies {
+		require.Len(t, movie, 2)
+		require.JSONEq(t, fmt.Sprintf("%q", movies[i].Title), string(movie["title"]))
+	}
+
+	require.JSONEq(t, "[]", string(envelope.Movies[0]["revisions"]))
+	var revisions []*data.MovieRevision
+	err = json.Unmarshal(envelope.Movies[1]["revisions"], &revisions)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	require.Equal(t, int32(1), revisions[0].Version)
+
+	test.close()
+}
+
+func TestShowMovieHandlerRuntimeFormat(t *testing.T) {
+	movie := &data.Movie{ID: 7, Title: "Casablanca", Year: 1942, Runtime: 102, Genres: []string{"drama"}}
+
+	testCases := []struct {
+		name          string
+		query         string
+		header        string
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test Show Movie Handler - 200 OK DEFAULT RUNTIME FORMAT",
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, "102 mins", requireMovieRuntime(t, r))
+			},
+		},
+		{
+			name:  "Test Show Movie Handler - 200 OK RUNTIME FORMAT QUERY PARAMETER",
+			query: "?runtime_format=hours",
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, "1h 42m", requireMovieRuntime(t, r))
+				require.Contains(t, r.Header().Values("Vary"), runtimeFormatHeader)
+			},
+		},
+		{
+			name:   "Test Show Movie Handler - 200 OK RUNTIME FORMAT HEADER",
+			header: data.RuntimeISO8601,
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, "PT1H42M", requireMovieRuntime(t, r))
+			},
+		},
+		{
+			name:   "Test Show Movie Handler - 200 OK QUERY PARAMETER OVER HEADER",
+			query:  "?runtime_format=integer&fields=runtime",
+			header: data.RuntimeISO8601,
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, float64(102), requireMovieRuntime(t, r))
+			},
+		},
+		{
+			name:   "Test Show Movie Handler - 422 UNKNOWN RUNTIME FORMAT",
+			header: "seconds",
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "runtime_format")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, fmt.Sprintf("/v1/movies/%d%s", movie.ID, tc.query))
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodGet, "/v1/movies/:id", test.app.showMovieHandler)
+
+			mockMovies, ok := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+			require.True(t, ok)
+			mockMovies.EXPECT().GetFields(movie.ID, gomock.Any()).Return(movie, nil).MaxTimes(1)
+
+			request := httptest.NewRequest(http.MethodGet, test.url, nil)
+			if tc.header != "" {
+				request.Header.Set(runtimeFormatHeader, tc.header)
+			}
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func requireMovieRuntime(t *testing.T, r *httptest.ResponseRecorder) any {
+	t.Helper()
+
+	var envelope struct {
+		Movie map[string]any `json:"movie"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&envelope)
+	require.NoError(t, err)
+
+	return envelope.Movie["runtime"]
+}
+
+func TestWriteMovieHandlersRuntimeFormat(t *testing.T) {
+	movie := &data.Movie{ID: 7, Title: "Casablanca", Year: 1942, Runtime: 102, Genres: []string{"drama"}, Version: 1}
+	body := `{"title": "Casablanca", "year": 1942, "runtime": 102, "genres": ["drama"]}`
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		header        string
+		body          string
+		buildStubs    func(t *testing.T, mockMovies *mockdb.MockMovieQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Test Create Movie Handler - 201 CREATED RUNTIME FORMAT QUERY PARAMETER",
+			method: http.MethodPost,
+			url:    "/v1/movies?runtime_format=hours",
+			body:   body,
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().FindDuplicates(gomock.Any()).Return(nil, nil)
+				mockMovies.EXPECT().Insert(gomock.Any()).Return(nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, r.Code)
+				require.Equal(t, "1h 42m", requireMovieRuntime(t, r))
+			},
+		},
+		{
+			name:   "Test Create Movie Handler - 422 UNKNOWN RUNTIME FORMAT",
+			method: http.MethodPost,
+			url:    "/v1/movies",
+			header: "seconds",
+			body:   body,
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "runtime_format")
+			},
+		},
+		{
+			name:   "Test Updates Movie Handler - 200 OK RUNTIME FORMAT HEADER",
+			method: http.MethodPatch,
+			url:    "/v1/movies/7",
+			header: data.RuntimeISO8601,
+			body:   `{"runtime": "1h 50m"}`,
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				current := *movie
+				mockMovies.EXPECT().Get(movie.ID).Return(&current, nil)
+				mockMovies.EXPECT().Update(gomock.Any()).Return(nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, "PT1H50M", requireMovieRuntime(t, r))
+			},
+		},
+		{
+			name:   "Test Updates Movie Handler - 422 UNKNOWN RUNTIME FORMAT",
+			method: http.MethodPatch,
+			url:    "/v1/movies/7?runtime_format=seconds",
+			body:   `{"runtime": "1h 50m"}`,
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "runtime_format")
+			},
+		},
+		{
+			name:   "Test Replace Movie Handler - 200 OK RUNTIME FORMAT QUERY PARAMETER",
+			method: http.MethodPut,
+			url:    "/v1/movies/7?runtime_format=integer",
+			body:   body,
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				current := *movie
+				mockMovies.EXPECT().Get(movie.ID).Return(&current, nil)
+				mockMovies.EXPECT().Update(gomock.Any()).Return(nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, float64(102), requireMovieRuntime(t, r))
+			},
+		},
+		{
+			name:   "Test Merge Movie Handler - 200 OK RUNTIME FORMAT HEADER",
+			method: http.MethodPost,
+			url:    "/v1/movies/3/merge",
+			header: data.RuntimeHours,
+			body:   `{"into": 7}`,
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().GetPoster(int64(3)).Return(nil, data.ErrRecordNotFound)
+				mockMovies.EXPECT().Merge(int64(3), movie.ID).Return(nil)
+				mockMovies.EXPECT().Get(movie.ID).Return(movie, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, "1h 42m", requireMovieRuntime(t, r))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, tc.url)
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodPost, "/v1/movies", test.app.createMovieHandler)
+			router.HandlerFunc(http.MethodPatch, "/v1/movies/:id", test.app.updatesMovieHandler)
+			router.HandlerFunc(http.MethodPut, "/v1/movies/:id", test.app.replaceMovieHandler)
+			router.HandlerFunc(http.MethodPost, "/v1/movies/:id/merge", test.app.mergeMovieHandler)
+
+			mockMovies, ok := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+			require.True(t, ok)
+			tc.buildStubs(t, mockMovies)
+
+			request := httptest.NewRequest(tc.method, test.url, strings.NewReader(tc.body))
+			if tc.header != "" {
+				request.Header.Set(runtimeFormatHeader, tc.header)
+			}
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
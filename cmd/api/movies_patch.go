@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/djudju12/greenlight/internal/data"
+	"github.com/djudju12/greenlight/internal/validator"
+)
+
+const (
+	mediaTypeMergePatch = "application/merge-patch+json"
+	mediaTypeJSONPatch  = "application/json-patch+json"
+)
+
+const (
+	jsonPatchAdd     = "add"
+	jsonPatchRemove  = "remove"
+	jsonPatchReplace = "replace"
+	jsonPatchTest    = "test"
+)
+
+var moviePatchMediaTypes = []string{
+	mediaTypeJSON, mediaTypeMsgpack, mediaTypeXMsgpack, mediaTypeMergePatch, mediaTypeJSONPatch,
+}
+
+var jsonPatchOps = []string{jsonPatchAdd, jsonPatchRemove, jsonPatchReplace, jsonPatchTest}
+
+var errUnsupportedPatch = errors.New("unsupported patch media type")
+
+// moviePatch applies a parsed patch document to movie. Problems with the
+// content of the document are added to v, and a failed JSON Patch test
+// operation is returned as a *jsonPatchTestError.
+type moviePatch func(movie *data.Movie, v *validator.Validator) error
+
+// JSONPatchOperation is an operation of a JSON Patch (RFC 6902) document.
+// From is only accepted so that move and copy operations are reported as
+// unsupported instead of as unknown keys.
+type JSONPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+type jsonPatchTestError struct {
+	index int
+}
+
+func (e *jsonPatchTestError) Error() string {
+	return fmt.Sprintf("the test operation at patch[%d] failed", e.index)
+}
+
+// readMoviePatch reads the body of a movie update in the format given by its
+// Content-Type. Plain JSON and MessagePack bodies are read as an
+// UpdateMovieRequest, where the absent fields are kept.
+func (app *application) readMoviePatch(w http.ResponseWriter, r *http.Request) (moviePatch, error) {
+	mediaType := ""
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		var err error
+		mediaType, _, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, errUnsupportedPatch
+		}
+	}
+
+	switch mediaType {
+	case "", mediaTypeJSON, mediaTypeMsgpack, mediaTypeXMsgpack:
+		var input UpdateMovieRequest
+
+		err := app.readJSON(w, r, &input)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(movie *data.Movie, v *validator.Validator) error {
+			applyMovieUpdate(movie, input)
+			return nil
+		}, nil
+
+	case mediaTypeMergePatch:
+		return app.readMergePatch(w, r)
+
+	case mediaTypeJSONPatch:
+		var ops []JSONPatchOperation
+
+		err := app.readJSON(w, r, &ops)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(movie *data.Movie, v *validator.Validator) error {
+			return applyJSONPatch(movie, ops, v)
+		}, nil
+
+	default:
+		return nil, errUnsupportedPatch
+	}
+}
+
+// readMergePatch reads a JSON Merge Patch (RFC 7396) document. Unlike in the
+// plain JSON body, a null member removes the field from the movie.
+func (app *application) readMergePatch(w http.ResponseWriter, r *http.Request) (moviePatch, error) {
+	var doc map[string]json.RawMessage
+
+	err := app.readJSON(w, r, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		input   UpdateMovieRequest
+		removed []string
+	)
+
+	fields := map[string]any{
+		"title":   &input.Title,
+		"year":    &input.Year,
+		"runtime": &input.Runtime,
+		"genres":  &input.Genres,
+	}
+
+	for key, raw := range doc {
+		dst, ok := fields[key]
+		if !ok {
+			return nil, fmt.Errorf("body contains unknown key %q", key)
+		}
+
+		if bytes.Equal(raw, []byte("null")) {
+			removed = append(removed, key)
+			continue
+		}
+
+		err := json.Unmarshal(raw, dst)
+		if err != nil {
+			var unmarshalTypeError *json.UnmarshalTypeError
+			if errors.As(err, &unmarshalTypeError) {
+				return nil, fmt.Errorf("body contains incorrect JSON type for field %q", key)
+			}
+
+			return nil, err
+		}
+	}
+
+	return func(movie *data.Movie, v *validator.Validator) error {
+		for _, field := range removed {
+			removeMovieField(movie, field)
+		}
+
+		applyMovieUpdate(movie, input)
+		return nil
+	}, nil
+}
+
+// applyJSONPatch applies the operations in order, stopping at the first one
+// that can't be applied. The fields of the movie are addressed as /title,
+// /year, /runtime and /genres, and a single genre as /genres/<index>, or
+// /genres/- to add one at the end.
+func applyJSONPatch(movie *data.Movie, ops []JSONPatchOperation, v *validator.Validator) error {
+	for i, op := range ops {
+		key := fmt.Sprintf("patch[%d]", i)
+
+		v.Check(validator.In(op.Op, jsonPatchOps...), key+".op",
+			fmt.Sprintf("must be one of %s", strings.Join(jsonPatchOps, ", ")))
+		v.Check(op.Op == jsonPatchRemove || op.Value != nil, key+".value", "must be provided")
+
+		field, index, hasIndex := strings.Cut(strings.TrimPrefix(op.Path, "/"), "/")
+		v.Check(strings.HasPrefix(op.Path, "/") && movieFieldPatchable(field, hasIndex), key+".path",
+			"must be /title, /year, /runtime, /genres or /genres/<index>")
+
+		if !v.Valid() {
+			return nil
+		}
+
+		var err error
+		if hasIndex {
+			err = patchMovieGenre(movie, op, index)
+		} else {
+			err = patchMovieField(movie, op, field)
+		}
+
+		switch {
+		case errors.Is(err, errJSONPatchTestFailed):
+			return &jsonPatchTestError{index: i}
+		case errors.Is(err, errJSONPatchIndex):
+			v.AddError(key+".path", "must reference an existing genre")
+			return nil
+		case err != nil:
+			v.AddError(key+".value", err.Error())
+			return nil
+		}
+	}
+
+	return nil
+}
+
+var (
+	errJSONPatchTestFailed = errors.New("test failed")
+	errJSONPatchIndex      = errors.New("index out of range")
+)
+
+func movieFieldPatchable(field string, hasIndex bool) bool {
+	switch field {
+	case "title", "year", "runtime":
+		return !hasIndex
+	case "genres":
+		return true
+	default:
+		return false
+	}
+}
+
+func movieField(movie *data.Movie, field string) any {
+	switch field {
+	case "title":
+		return &movie.Title
+	case "year":
+		return &movie.Year
+	case "runtime":
+		return &movie.Runtime
+	default:
+		return &movie.Genres
+	}
+}
+
+func removeMovieField(movie *data.Movie, field string) {
+	switch field {
+	case "title":
+		movie.Title = ""
+	case "year":
+		movie.Year = 0
+	case "runtime":
+		movie.Runtime = 0
+	case "genres":
+		movie.Genres = nil
+	}
+}
+
+// patchMovieField applies op to a whole field. As the fields always exist, add
+// works like replace.
+func patchMovieField(movie *data.Movie, op JSONPatchOperation, field string) error {
+	dst := movieField(movie, field)
+
+	switch op.Op {
+	case jsonPatchRemove:
+		removeMovieField(movie, field)
+		return nil
+
+	case jsonPatchTest:
+		// a runtime can be written in many ways, so the minutes are compared
+		// instead of the JSON values
+		if field == "runtime" {
+			var runtime data.Runtime
+			if err := unmarshalPatchValue(op.Value, &runtime); err != nil {
+				return err
+			}
+
+			if runtime != movie.Runtime {
+				return errJSONPatchTestFailed
+			}
+
+			return nil
+		}
+
+		current, err := json.Marshal(dst)
+		if err != nil {
+			return err
+		}
+
+		if !jsonEqual(current, op.Value) {
+			return errJSONPatchTestFailed
+		}
+
+		return nil
+
+	default:
+		return unmarshalPatchValue(op.Value, dst)
+	}
+}
+
+func patchMovieGenre(movie *data.Movie, op JSONPatchOperation, index string) error {
+	if index == "-" {
+		if op.Op != jsonPatchAdd {
+			return errJSONPatchIndex
+		}
+
+		index = strconv.Itoa(len(movie.Genres))
+	}
+
+	i, err := strconv.Atoi(index)
+	if err != nil || i < 0 || i > len(movie.Genres) || (i == len(movie.Genres) && op.Op != jsonPatchAdd) {
+		return errJSONPatchIndex
+	}
+
+	if op.Op == jsonPatchRemove {
+		movie.Genres = append(movie.Genres[:i], movie.Genres[i+1:]...)
+		return nil
+	}
+
+	var genre string
+	err = unmarshalPatchValue(op.Value, &genre)
+	if err != nil {
+		return err
+	}
+
+	switch op.Op {
+	case jsonPatchAdd:
+		movie.Genres = append(movie.Genres[:i], append([]string{genre}, movie.Genres[i:]...)...)
+	case jsonPatchReplace:
+		movie.Genres[i] = genre
+	case jsonPatchTest:
+		if movie.Genres[i] != genre {
+			return errJSONPatchTestFailed
+		}
+	}
+
+	return nil
+}
+
+func unmarshalPatchValue(value json.RawMessage, dst any) error {
+	err := json.Unmarshal(value, dst)
+	if err != nil {
+		var unmarshalTypeError *json.UnmarshalTypeError
+		if errors.As(err, &unmarshalTypeError) {
+			return fmt.Errorf("must be a JSON %s", jsonTypeName(unmarshalTypeError.Type))
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Slice:
+		return "array"
+	default:
+		return "number"
+	}
+}
+
+// jsonEqual reports whether a and b encode the same JSON value.
+func jsonEqual(a, b []byte) bool {
+	var x, y any
+
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(x, y)
+}
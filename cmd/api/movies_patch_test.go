@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/djudju12/greenlight/internal/data"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestUpdateMoviesHandlerPatchFormats(t *testing.T) {
+	testCases := []struct {
+		name          string
+		contentType   string
+		requestBody   string
+		buildStubs    func(t *testing.T, mockMovies *mockdb.MockMovieQuerier, movie *data.Movie)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder, movie data.Movie)
+	}{
+		{
+			name:        "Test Update Movie Handler - MERGE PATCH 200 OK",
+			contentType: mediaTypeMergePatch,
+			requestBody: `{"title": "Casablanca", "year": 1942}`,
+			buildStubs:  expectMovieUpdate,
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie data.Movie) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				movie.Title = "Casablanca"
+				movie.Year = 1942
+				requireBodyMatchMovie(t, r.Body, &movie)
+			},
+		},
+		{
+			name:        "Test Update Movie Handler - MERGE PATCH 422 NULL REMOVES FIELD",
+			contentType: mediaTypeMergePatch + "; charset=utf-8",
+			requestBody: `{"genres": null}`,
+			buildStubs:  expectMovieGet,
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie data.Movie) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "genres")
+			},
+		},
+		{
+			name:        "Test Update Movie Handler - MERGE PATCH 400 UNKNOWN KEY",
+			contentType: mediaTypeMergePatch,
+			requestBody: `{"director": "Michael Curtiz"}`,
+			buildStubs:  expectNoStubs,
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie data.Movie) {
+				require.Equal(t, http.StatusBadRequest, r.Code)
+			},
+		},
+		{
+			name:        "Test Update Movie Handler - MERGE PATCH 400 INCORRECT TYPE",
+			contentType: mediaTypeMergePatch,
+			requestBody: `{"year": "1942"}`,
+			buildStubs:  expectNoStubs,
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie data.Movie) {
+				require.Equal(t, http.StatusBadRequest, r.Code)
+			},
+		},
+		{
+			name:        "Test Update Movie Handler - JSON PATCH 200 OK",
+			contentType: mediaTypeJSONPatch,
+			requestBody: `[
+				{"op": "test", "path": "/genres/0", "value": "genre0"},
+				{"op": "remove", "path": "/genres/0"},
+				{"op": "add", "path": "/genres/-", "value": "drama"},
+				{"op": "add", "path": "/genres/0", "value": "romance"},
+				{"op": "replace", "path": "/genres/1", "value": "noir"},
+				{"op": "replace", "path": "/runtime", "value": "102 mins"}
+			]`,
+			buildStubs: expectMovieUpdate,
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie data.Movie) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				movie.Genres = []string{"romance", "noir", "genre2", "drama"}
+				movie.Runtime = 102
+				requireBodyMatchMovie(t, r.Body, &movie)
+			},
+		},
+		{
+			name:        "Test Update Movie Handler - JSON PATCH 409 TEST FAILED",
+			contentType: mediaTypeJSONPatch,
+			requestBody: `[
+				{"op": "remove", "path": "/genres/0"},
+				{"op": "test", "path": "/year", "value": 1800}
+			]`,
+			buildStubs: expectMovieGet,
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie data.Movie) {
+				require.Equal(t, http.StatusConflict, r.Code)
+				require.Contains(t, r.Body.String(), "patch[1]")
+			},
+		},
+		{
+			name:        "Test Update Movie Handler - JSON PATCH 200 OK RUNTIME TESTED IN ANY FORMAT",
+			contentType: mediaTypeJSONPatch,
+			requestBody: `[
+				{"op": "test", "path": "/runtime", "value": 102},
+				{"op": "test", "path": "/runtime", "value": "1h 42m"},
+				{"op": "test", "path": "/runtime", "value": "PT1H42M"},
+				{"op": "replace", "path": "/title", "value": "Casablanca"}
+			]`,
+			buildStubs: expectMovieUpdate,
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie data.Movie) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				movie.Title = "Casablanca"
+				requireBodyMatchMovie(t, r.Body, &movie)
+			},
+		},
+		{
+			name:        "Test Update Movie Handler - JSON PATCH 409 RUNTIME TEST FAILED",
+			contentType: mediaTypeJSONPatch,
+			requestBody: `[{"op": "test", "path": "/runtime", "value": "1h 43m"}]`,
+			buildStubs:  expectMovieGet,
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie data.Movie) {
+				require.Equal(t, http.StatusConflict, r.Code)
+				require.Contains(t, r.Body.String(), "patch[0]")
+			},
+		},
+		{
+			name:        "Test Update Movie Handler - JSON PATCH 422 INVALID RUNTIME TESTED",
+			contentType: mediaTypeJSONPatch,
+			requestBody: `[{"op": "test", "path": "/runtime", "value": "soon"}]`,
+			buildStubs:  expectMovieGet,
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie data.Movie) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "patch[0].value")
+			},
+		},
+		{
+			name:        "Test Update Movie Handler - JSON PATCH 422 INDEX OUT OF RANGE",
+			contentType: mediaTypeJSONPatch,
+			requestBody: `[{"op": "replace", "path": "/genres/3", "value": "noir"}]`,
+			buildStubs:  expectMovieGet,
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie data.Movie) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "patch[0].path")
+			},
+		},
+		{
+			name:        "Test Update Movie Handler - JSON PATCH 422 INVALID OPERATION",
+			contentType: mediaTypeJSONPatch,
+			requestBody: `[{"op": "move", "from": "/title", "path": "/director"}]`,
+			buildStubs:  expectMovieGet,
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie data.Movie) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+
+				errs := requireErrorMap(t, r)
+				require.Contains(t, errs, "patch[0].op")
+				require.Contains(t, errs, "patch[0].value")
+				require.Contains(t, errs, "patch[0].path")
+			},
+		},
+		{
+			name:        "Test Update Movie Handler - JSON PATCH 422 INCORRECT VALUE",
+			contentType: mediaTypeJSONPatch,
+			requestBody: `[{"op": "replace", "path": "/year", "value": "1942"}]`,
+			buildStubs:  expectMovieGet,
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie data.Movie) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Equal(t, "must be a JSON number", requireErrorMap(t, r)["patch[0].value"])
+			},
+		},
+		{
+			name:        "Test Update Movie Handler - 415 UNSUPPORTED CONTENT TYPE",
+			contentType: "text/plain",
+			requestBody: `title=Casablanca`,
+			buildStubs:  expectNoStubs,
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie data.Movie) {
+				require.Equal(t, http.StatusUnsupportedMediaType, r.Code)
+				require.Contains(t, r.Header().Get("Accept-Patch"), mediaTypeJSONPatch)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			movie := randomMovie()
+			movie.Genres = []string{"genre0", "genre1", "genre2"}
+			movie.Runtime = 102
+			original := *movie
+
+			test := newMovieTest(t, fmt.Sprintf("/v1/movies/%d", movie.ID))
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodPatch, "/v1/movies/:id", test.app.updatesMovieHandler)
+
+			mockMovies, ok := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+			require.True(t, ok)
+			tc.buildStubs(t, mockMovies, movie)
+
+			request := httptest.NewRequest(http.MethodPatch, test.url, strings.NewReader(tc.requestBody))
+			request.Header.Set("Content-Type", tc.contentType)
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder, original)
+			test.close()
+		})
+	}
+}
+
+func expectNoStubs(t *testing.T, mockMovies *mockdb.MockMovieQuerier, movie *data.Movie) {
+	t.Log("no stubs for this test")
+}
+
+func expectMovieGet(t *testing.T, mockMovies *mockdb.MockMovieQuerier, movie *data.Movie) {
+	mockMovies.EXPECT().Get(movie.ID).Return(movie, nil)
+}
+
+func expectMovieUpdate(t *testing.T, mockMovies *mockdb.MockMovieQuerier, movie *data.Movie) {
+	expectMovieGet(t, mockMovies, movie)
+	mockMovies.EXPECT().Update(gomock.Any()).Return(nil)
+}
+
+func requireErrorMap(t *testing.T, r *httptest.ResponseRecorder) map[string]string {
+	var envelope struct {
+		Error map[string]string `json:"error"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&envelope)
+	require.NoError(t, err)
+
+	return envelope.Error
+}
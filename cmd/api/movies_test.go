@@ -18,6 +18,7 @@ import (
 	"github.com/djudju12/greenlight/internal/data"
 	"github.com/djudju12/greenlight/internal/jsonlog"
 	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/djudju12/greenlight/internal/storage"
 	"github.com/djudju12/greenlight/internal/util"
 	"github.com/julienschmidt/httprouter"
 	"github.com/stretchr/testify/require"
@@ -28,6 +29,19 @@ func newMovieTest(t *testing.T, url string) test {
 	ctrl := gomock.NewController(t)
 	movies := mockdb.NewMockMovieQuerier(ctrl)
 
+	// every genre is in the catalog, spelled as its slug
+	genres := mockdb.NewMockGenreQuerier(ctrl)
+	genres.EXPECT().
+		Resolve(gomock.Any()).
+		DoAndReturn(func(names []string) (map[string]string, error) {
+			slugs := make(map[string]string, len(names))
+			for _, name := range names {
+				slugs[name] = name
+			}
+			return slugs, nil
+		}).
+		AnyTimes()
+
 	recorder := httptest.NewRecorder()
 
 	f, err := os.CreateTemp("", "tmpfile-")
@@ -38,8 +52,10 @@ func newMovieTest(t *testing.T, url string) test {
 	app := &application{
 		models: &data.Models{
 			Movies: movies,
+			Genres: genres,
 		},
-		logger: jsonlog.New(f, jsonlog.LevelInfo),
+		storage: storage.NewLocal(t.TempDir()),
+		logger:  jsonlog.New(f, jsonlog.LevelInfo),
 	}
 
 	return test{
@@ -78,6 +94,10 @@ func TestCreateMovieHandler(t *testing.T) {
 					Runtime: movie.Runtime,
 				}
 
+				mockMovies.EXPECT().
+					FindDuplicates(EqMovieRequest(expectedMovie)).
+					Return([]*data.Movie{}, nil)
+
 				mockMovies.EXPECT().
 					Insert(EqMovieRequest(expectedMovie)).
 					DoAndReturn(func(m *data.Movie) error {
@@ -108,6 +128,10 @@ func TestCreateMovieHandler(t *testing.T) {
 				mockMovies, ok := app.models.Movies.(*mockdb.MockMovieQuerier)
 				require.True(t, ok)
 
+				mockMovies.EXPECT().
+					FindDuplicates(gomock.Any()).
+					Return([]*data.Movie{}, nil)
+
 				mockMovies.EXPECT().
 					Insert(gomock.Any()).
 					Return(errors.New("DB RETURNED ERROR"))
@@ -322,6 +346,232 @@ func TestUpdateMoviesHandler(t *testing.T) {
 	}
 }
 
+func TestReplaceMovieHandler(t *testing.T) {
+	requestMovie := randomMovie()
+	requestBody := CreateMovieRequest{
+		Title:   requestMovie.Title,
+		Year:    requestMovie.Year,
+		Runtime: requestMovie.Runtime,
+		Genres:  requestMovie.Genres,
+	}
+
+	// the movies of the test cases are at version 3
+	requestBodyAt := func(version int32) ReplaceMovieRequest {
+		return ReplaceMovieRequest{
+			Title:   requestMovie.Title,
+			Year:    requestMovie.Year,
+			Runtime: requestMovie.Runtime,
+			Genres:  requestMovie.Genres,
+			Version: &version,
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		requestBody   any
+		buildStubs    func(t *testing.T, mockMovies *mockdb.MockMovieQuerier, movie *data.Movie)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder, movie *data.Movie)
+	}{
+		{
+			name:        "Test Replace Movie Handler - 200 OK REPLACED",
+			requestBody: requestBody,
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier, movie *data.Movie) {
+				mockMovies.EXPECT().
+					Get(movie.ID).
+					Return(movie, nil)
+
+				mockMovies.EXPECT().
+					Update(EqMovieRequest(requestMovie)).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie *data.Movie) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Empty(t, r.Header().Get("Location"))
+
+				expectedMovie := *requestMovie
+				expectedMovie.ID = movie.ID
+				expectedMovie.CreatedAt = movie.CreatedAt
+				expectedMovie.Version = movie.Version
+				requireBodyMatchMovie(t, r.Body, &expectedMovie)
+			},
+		},
+		{
+			name:        "Test Replace Movie Handler - 200 OK AT EXPECTED VERSION",
+			requestBody: requestBodyAt(3),
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier, movie *data.Movie) {
+				mockMovies.EXPECT().
+					Get(movie.ID).
+					Return(movie, nil)
+
+				mockMovies.EXPECT().
+					Update(EqMovieRequest(requestMovie)).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie *data.Movie) {
+				require.Equal(t, http.StatusOK, r.Code)
+			},
+		},
+		{
+			name:        "Test Replace Movie Handler - 409 STALE VERSION",
+			requestBody: requestBodyAt(2),
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier, movie *data.Movie) {
+				mockMovies.EXPECT().
+					Get(movie.ID).
+					Return(movie, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie *data.Movie) {
+				require.Equal(t, http.StatusConflict, r.Code)
+			},
+		},
+		{
+			name:        "Test Replace Movie Handler - 404 EXPECTED VERSION OF MISSING MOVIE",
+			requestBody: requestBodyAt(3),
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier, movie *data.Movie) {
+				mockMovies.EXPECT().
+					Get(movie.ID).
+					Return(nil, data.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie *data.Movie) {
+				require.Equal(t, http.StatusNotFound, r.Code)
+			},
+		},
+		{
+			name:        "Test Replace Movie Handler - 201 CREATED WITH ID",
+			requestBody: requestBody,
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier, movie *data.Movie) {
+				mockMovies.EXPECT().
+					Get(movie.ID).
+					Return(nil, data.ErrRecordNotFound)
+
+				mockMovies.EXPECT().
+					InsertWithID(EqMovieRequest(requestMovie)).
+					DoAndReturn(func(m *data.Movie) error {
+						require.Equal(t, movie.ID, m.ID)
+						m.CreatedAt = movie.CreatedAt
+						m.Version = 1
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie *data.Movie) {
+				require.Equal(t, http.StatusCreated, r.Code)
+				require.Equal(t, fmt.Sprintf("/v1/movies/%d", movie.ID), r.Header().Get("Location"))
+
+				expectedMovie := *requestMovie
+				expectedMovie.ID = movie.ID
+				expectedMovie.CreatedAt = movie.CreatedAt
+				expectedMovie.Version = 1
+				requireBodyMatchMovie(t, r.Body, &expectedMovie)
+			},
+		},
+		{
+			name:        "Test Replace Movie Handler - 409 CREATED CONCURRENTLY",
+			requestBody: requestBody,
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier, movie *data.Movie) {
+				mockMovies.EXPECT().
+					Get(movie.ID).
+					Return(nil, data.ErrRecordNotFound)
+
+				mockMovies.EXPECT().
+					InsertWithID(gomock.Any()).
+					Return(data.ErrEditConflict)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie *data.Movie) {
+				require.Equal(t, http.StatusConflict, r.Code)
+			},
+		},
+		{
+			name:        "Test Replace Movie Handler - 422 UNISSUED ID",
+			requestBody: requestBody,
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier, movie *data.Movie) {
+				mockMovies.EXPECT().
+					Get(movie.ID).
+					Return(nil, data.ErrRecordNotFound)
+
+				mockMovies.EXPECT().
+					InsertWithID(gomock.Any()).
+					Return(data.ErrUnissuedMovieID)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie *data.Movie) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "id")
+			},
+		},
+		{
+			name:        "Test Replace Movie Handler - 409 DB RETURNED EDIT CONFLICT",
+			requestBody: requestBody,
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier, movie *data.Movie) {
+				mockMovies.EXPECT().
+					Get(movie.ID).
+					Return(movie, nil)
+
+				mockMovies.EXPECT().
+					Update(gomock.Any()).
+					Return(data.ErrEditConflict)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie *data.Movie) {
+				require.Equal(t, http.StatusConflict, r.Code)
+			},
+		},
+		{
+			name:        "Test Replace Movie Handler - 422 MISSING FIELDS",
+			requestBody: map[string]string{"title": requestMovie.Title},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier, movie *data.Movie) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie *data.Movie) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+
+				errs := requireErrorMap(t, r)
+				require.Contains(t, errs, "year")
+				require.Contains(t, errs, "runtime")
+				require.Contains(t, errs, "genres")
+			},
+		},
+		{
+			name:        "Test Replace Movie Handler - 500 DB RETURNED ERROR",
+			requestBody: requestBody,
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier, movie *data.Movie) {
+				mockMovies.EXPECT().
+					Get(movie.ID).
+					Return(nil, errors.New("DB RETURNED ERROR"))
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder, movie *data.Movie) {
+				require.Equal(t, http.StatusInternalServerError, r.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			movie := randomMovie()
+			movie.Version = 3
+			original := *movie
+
+			test := newMovieTest(t, fmt.Sprintf("/v1/movies/%d", movie.ID))
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodPut, "/v1/movies/:id", test.app.replaceMovieHandler)
+
+			mockMovies, ok := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+			require.True(t, ok)
+			tc.buildStubs(t, mockMovies, movie)
+
+			body, err := toReader(tc.requestBody)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPut, test.url, body)
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder, &original)
+			test.close()
+		})
+	}
+}
+
 func TestDeleteMovieHandler(t *testing.T) {
 	movie := randomMovie()
 
@@ -427,7 +677,7 @@ func TestShowMovieHandler(t *testing.T) {
 				require.True(t, ok)
 
 				mockMovies.EXPECT().
-					Get(movie.ID).
+					GetFields(movie.ID, nil).
 					Return(movie, nil)
 			},
 			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
@@ -443,7 +693,7 @@ func TestShowMovieHandler(t *testing.T) {
 				require.True(t, ok)
 
 				mockMovies.EXPECT().
-					Get(movie.ID).
+					GetFields(movie.ID, nil).
 					Return(&data.Movie{}, data.ErrRecordNotFound)
 			},
 			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
@@ -458,7 +708,7 @@ func TestShowMovieHandler(t *testing.T) {
 				require.True(t, ok)
 
 				mockMovies.EXPECT().
-					Get(movie.ID).
+					GetFields(movie.ID, nil).
 					Return(&data.Movie{}, errors.New("DB ERROR"))
 			},
 			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
@@ -509,6 +759,9 @@ func TestListMoviesHandler(t *testing.T) {
 		"-title",
 		"-year",
 		"-runtime",
+		"rating",
+		"-rating",
+		"relevance",
 	}
 
 	var movies []*data.Movie
@@ -537,8 +790,13 @@ func TestListMoviesHandler(t *testing.T) {
 				require.True(t, ok)
 
 				expectedInput := ListMoviesRequest{
-					Title:  "",
-					Genres: []string{},
+					MovieFilters: data.MovieFilters{
+						Title:         "",
+						SearchMode:    data.SearchFullText,
+						Genres:        []string{},
+						GenresAny:     []string{},
+						GenresExclude: []string{},
+					},
 					Filters: data.Filters{
 						Page:         1,
 						PageSize:     n,
@@ -548,7 +806,91 @@ func TestListMoviesHandler(t *testing.T) {
 				}
 
 				mockMovies.EXPECT().
-					GetAll(expectedInput.Title, expectedInput.Genres, expectedInput.Filters).
+					GetAll(expectedInput.MovieFilters, expectedInput.Filters).
+					Return(movies, data.Metadata{}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				requireBodyMatchListMovies(t, r.Body, movies)
+			},
+		},
+		{
+			name: "Test List Movie Handler - 200 OK WITH RANGE AND GENRE FILTERS",
+			requestParams: ListMoviesRequest{
+				MovieFilters: data.MovieFilters{
+					GenresAny:     []string{"drama", "comedy"},
+					GenresExclude: []string{"horror"},
+					YearMin:       1990,
+					YearMax:       2000,
+					RuntimeMin:    90,
+					RuntimeMax:    120,
+					CreatedAfter:  time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+				},
+				Filters: data.Filters{
+					Page:     1,
+					PageSize: n,
+					Sort:     "-year",
+				},
+			},
+			buildStubs: func(t *testing.T, app *application) {
+				mockMovies, ok := app.models.Movies.(*mockdb.MockMovieQuerier)
+				require.True(t, ok)
+
+				expectedFilters := data.MovieFilters{
+					SearchMode:    data.SearchFullText,
+					Genres:        []string{},
+					GenresAny:     []string{"drama", "comedy"},
+					GenresExclude: []string{"horror"},
+					YearMin:       1990,
+					YearMax:       2000,
+					RuntimeMin:    90,
+					RuntimeMax:    120,
+					CreatedAfter:  time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+				}
+
+				mockMovies.EXPECT().
+					GetAll(expectedFilters, gomock.Any()).
+					Return(movies, data.Metadata{}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				requireBodyMatchListMovies(t, r.Body, movies)
+			},
+		},
+		{
+			name: "Test List Movie Handler - 200 OK FUZZY SEARCH SORTED BY RELEVANCE",
+			requestParams: ListMoviesRequest{
+				MovieFilters: data.MovieFilters{
+					Title:      "brekfast",
+					SearchMode: data.SearchFuzzy,
+				},
+				Filters: data.Filters{
+					Page:     1,
+					PageSize: n,
+					Sort:     "relevance",
+				},
+			},
+			buildStubs: func(t *testing.T, app *application) {
+				mockMovies, ok := app.models.Movies.(*mockdb.MockMovieQuerier)
+				require.True(t, ok)
+
+				expectedFilters := data.MovieFilters{
+					Title:         "brekfast",
+					SearchMode:    data.SearchFuzzy,
+					Genres:        []string{},
+					GenresAny:     []string{},
+					GenresExclude: []string{},
+				}
+
+				expectedPagination := data.Filters{
+					Page:         1,
+					PageSize:     n,
+					Sort:         "relevance",
+					SortSafelist: sortSafelist,
+				}
+
+				mockMovies.EXPECT().
+					GetAll(expectedFilters, expectedPagination).
 					Return(movies, data.Metadata{}, nil)
 			},
 			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
@@ -556,6 +898,115 @@ func TestListMoviesHandler(t *testing.T) {
 				requireBodyMatchListMovies(t, r.Body, movies)
 			},
 		},
+		{
+			name: "Test List Movie Handler - 200 OK WITH FACETS",
+			requestParams: ListMoviesRequest{
+				MovieFilters: data.MovieFilters{
+					Genres: []string{"drama"},
+				},
+				Filters: data.Filters{
+					Page:     1,
+					PageSize: n,
+					Sort:     "id",
+				},
+				Facets: []string{data.FacetGenres, data.FacetDecade},
+			},
+			buildStubs: func(t *testing.T, app *application) {
+				mockMovies, ok := app.models.Movies.(*mockdb.MockMovieQuerier)
+				require.True(t, ok)
+
+				expectedFilters := data.MovieFilters{
+					SearchMode:    data.SearchFullText,
+					Genres:        []string{"drama"},
+					GenresAny:     []string{},
+					GenresExclude: []string{},
+				}
+
+				mockMovies.EXPECT().
+					GetAll(expectedFilters, gomock.Any()).
+					Return(movies, data.Metadata{}, nil)
+
+				mockMovies.EXPECT().
+					GetFacets(expectedFilters, []string{data.FacetGenres, data.FacetDecade}).
+					Return(data.Facets{
+						data.FacetGenres: {{Value: "drama", Count: 7}, {Value: "comedy", Count: 2}},
+						data.FacetDecade: {{Value: "1990s", Count: 7}},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				var envelope struct {
+					Movies []*data.Movie `json:"movies"`
+					Facets data.Facets   `json:"facets"`
+				}
+				err := json.NewDecoder(r.Body).Decode(&envelope)
+				require.NoError(t, err)
+
+				require.Len(t, envelope.Movies, n)
+				require.Equal(t, []data.FacetCount{{Value: "drama", Count: 7}, {Value: "comedy", Count: 2}},
+					envelope.Facets[data.FacetGenres])
+				require.Equal(t, []data.FacetCount{{Value: "1990s", Count: 7}},
+					envelope.Facets[data.FacetDecade])
+			},
+		},
+		{
+			name: "Test List Movie Handler - 422 INVALID FACET",
+			requestParams: ListMoviesRequest{
+				Filters: data.Filters{
+					Page:     1,
+					PageSize: n,
+					Sort:     "id",
+				},
+				Facets: []string{"director"},
+			},
+			buildStubs: func(t *testing.T, app *application) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+			},
+		},
+		{
+			name: "Test List Movie Handler - 422 INVALID SEARCH MODE",
+			requestParams: ListMoviesRequest{
+				MovieFilters: data.MovieFilters{
+					Title:      "breakfast",
+					SearchMode: "telepathic",
+				},
+				Filters: data.Filters{
+					Page:     1,
+					PageSize: n,
+					Sort:     "id",
+				},
+			},
+			buildStubs: func(t *testing.T, app *application) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+			},
+		},
+		{
+			name: "Test List Movie Handler - 422 INVERTED YEAR RANGE",
+			requestParams: ListMoviesRequest{
+				MovieFilters: data.MovieFilters{
+					YearMin: 2000,
+					YearMax: 1990,
+				},
+				Filters: data.Filters{
+					Page:     1,
+					PageSize: n,
+					Sort:     "id",
+				},
+			},
+			buildStubs: func(t *testing.T, app *application) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -581,6 +1032,132 @@ func TestListMoviesHandler(t *testing.T) {
 	}
 }
 
+func TestSuggestMoviesHandler(t *testing.T) {
+	movie := randomMovie()
+	suggestions := []*data.MovieSuggestion{
+		{ID: movie.ID, Title: movie.Title, Year: movie.Year},
+	}
+
+	testCases := []struct {
+		name          string
+		url           string
+		buildStubs    func(t *testing.T, app *application)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test Suggest Movies Handler - 200 OK",
+			url:  "/v1/movies/suggest?q=" + movie.Title[:3],
+			buildStubs: func(t *testing.T, app *application) {
+				mockMovies, ok := app.models.Movies.(*mockdb.MockMovieQuerier)
+				require.True(t, ok)
+
+				mockMovies.EXPECT().
+					Suggest(movie.Title[:3], 10).
+					Return(suggestions, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				var envelope struct {
+					Suggestions []*data.MovieSuggestion `json:"suggestions"`
+				}
+				err := json.NewDecoder(r.Body).Decode(&envelope)
+				require.NoError(t, err)
+				require.Equal(t, suggestions, envelope.Suggestions)
+			},
+		},
+		{
+			name: "Test Suggest Movies Handler - 200 OK WITH LIMIT",
+			url:  "/v1/movies/suggest?q=the&limit=5",
+			buildStubs: func(t *testing.T, app *application) {
+				mockMovies, ok := app.models.Movies.(*mockdb.MockMovieQuerier)
+				require.True(t, ok)
+
+				mockMovies.EXPECT().
+					Suggest("the", 5).
+					Return([]*data.MovieSuggestion{}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+			},
+		},
+		{
+			name: "Test Suggest Movies Handler - 422 NO QUERY",
+			url:  "/v1/movies/suggest",
+			buildStubs: func(t *testing.T, app *application) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+			},
+		},
+		{
+			name: "Test Suggest Movies Handler - 422 LIMIT TOO LARGE",
+			url:  "/v1/movies/suggest?q=the&limit=500",
+			buildStubs: func(t *testing.T, app *application) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+			},
+		},
+		{
+			name: "Test Suggest Movies Handler - 500 DB RETURN ERROR",
+			url:  "/v1/movies/suggest?q=the",
+			buildStubs: func(t *testing.T, app *application) {
+				mockMovies, ok := app.models.Movies.(*mockdb.MockMovieQuerier)
+				require.True(t, ok)
+
+				mockMovies.EXPECT().
+					Suggest(gomock.Any(), gomock.Any()).
+					Return(nil, errors.New("DB ERROR"))
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, r.Code)
+			},
+		},
+		{
+			name: "Test Suggest Movies Handler - 200 OK ID STILL ROUTED TO SHOW MOVIE",
+			url:  fmt.Sprintf("/v1/movies/%d", movie.ID),
+			buildStubs: func(t *testing.T, app *application) {
+				mockMovies, ok := app.models.Movies.(*mockdb.MockMovieQuerier)
+				require.True(t, ok)
+
+				mockMovies.EXPECT().
+					GetFields(movie.ID, nil).
+					Return(movie, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				requireBodyMatchMovie(t, r.Body, movie)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, tc.url)
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodGet, "/v1/movies/:id", test.app.byIDOr(
+				test.app.showMovieHandler,
+				map[string]http.HandlerFunc{"suggest": test.app.suggestMoviesHandler}))
+
+			tc.buildStubs(t, test.app)
+
+			request := httptest.NewRequest(http.MethodGet, test.url, nil)
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
 func randomMovie() *data.Movie {
 	var genres []string
 	for i := 0; i < 3; i++ {
@@ -686,10 +1263,50 @@ func urlFromRequest(baseUrl string, request ListMoviesRequest) string {
 		url = fmt.Sprintf("%stitle=%s&", url, request.Title)
 	}
 
+	if request.SearchMode != "" {
+		url = fmt.Sprintf("%ssearch_mode=%s&", url, request.SearchMode)
+	}
+
 	if len(request.Genres) > 0 {
 		url = fmt.Sprintf("%sgenres=%s&", url, strings.Join(request.Genres, ","))
 	}
 
+	if len(request.GenresAny) > 0 {
+		url = fmt.Sprintf("%sgenres_any=%s&", url, strings.Join(request.GenresAny, ","))
+	}
+
+	if len(request.GenresExclude) > 0 {
+		url = fmt.Sprintf("%sgenres_exclude=%s&", url, strings.Join(request.GenresExclude, ","))
+	}
+
+	if request.YearMin > 0 {
+		url = fmt.Sprintf("%syear_min=%d&", url, request.YearMin)
+	}
+
+	if request.YearMax > 0 {
+		url = fmt.Sprintf("%syear_max=%d&", url, request.YearMax)
+	}
+
+	if request.RuntimeMin > 0 {
+		url = fmt.Sprintf("%sruntime_min=%d&", url, request.RuntimeMin)
+	}
+
+	if request.RuntimeMax > 0 {
+		url = fmt.Sprintf("%sruntime_max=%d&", url, request.RuntimeMax)
+	}
+
+	if !request.CreatedAfter.IsZero() {
+		url = fmt.Sprintf("%screated_after=%s&", url, request.CreatedAfter.Format(time.RFC3339))
+	}
+
+	if !request.CreatedBefore.IsZero() {
+		url = fmt.Sprintf("%screated_before=%s&", url, request.CreatedBefore.Format(time.RFC3339))
+	}
+
+	if len(request.Facets) > 0 {
+		url = fmt.Sprintf("%sfacets=%s&", url, strings.Join(request.Facets, ","))
+	}
+
 	if request.Page > 0 {
 		url = fmt.Sprintf("%spage=%d&", url, request.Page)
 	}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/djudju12/greenlight/internal/data"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newPeopleTest(t *testing.T, url string) (test, *mockdb.MockPersonQuerier) {
+	test := newMovieTest(t, url)
+
+	mockPeople := mockdb.NewMockPersonQuerier(gomock.NewController(t))
+	test.app.models.People = mockPeople
+
+	return test, mockPeople
+}
+
+func TestCreatePersonHandler(t *testing.T) {
+	testCases := []struct {
+		name          string
+		body          any
+		buildStubs    func(t *testing.T, mockPeople *mockdb.MockPersonQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test Create Person Handler - 201 CREATED",
+			body: map[string]any{"name": "Agnès Varda"},
+			buildStubs: func(t *testing.T, mockPeople *mockdb.MockPersonQuerier) {
+				mockPeople.EXPECT().
+					Insert(&data.Person{Name: "Agnès Varda"}).
+					DoAndReturn(func(person *data.Person) error {
+						person.ID = 3
+						person.Version = 1
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, r.Code)
+				require.Equal(t, "/v1/people/3", r.Header().Get("Location"))
+
+				var envelope struct {
+					Person data.Person `json:"person"`
+				}
+				err := json.NewDecoder(r.Body).Decode(&envelope)
+				require.NoError(t, err)
+				require.Equal(t, data.Person{ID: 3, Name: "Agnès Varda", Version: 1}, envelope.Person)
+			},
+		},
+		{
+			name: "Test Create Person Handler - 422 MISSING NAME",
+			body: map[string]any{"name": ""},
+			buildStubs: func(t *testing.T, mockPeople *mockdb.MockPersonQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "name")
+			},
+		},
+		{
+			name: "Test Create Person Handler - 500 DB RETURN ERROR",
+			body: map[string]any{"name": "Agnès Varda"},
+			buildStubs: func(t *testing.T, mockPeople *mockdb.MockPersonQuerier) {
+				mockPeople.EXPECT().Insert(gomock.Any()).Return(errors.New("DB ERROR"))
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, r.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test, mockPeople := newPeopleTest(t, "/v1/people")
+			tc.buildStubs(t, mockPeople)
+
+			body, err := toReader(tc.body)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, test.url, body)
+
+			// when
+			test.app.createPersonHandler(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestUpdatePersonHandler(t *testing.T) {
+	testCases := []struct {
+		name          string
+		body          any
+		buildStubs    func(t *testing.T, mockPeople *mockdb.MockPersonQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test Update Person Handler - 200 OK",
+			body: map[string]any{"name": "Akira Kurosawa"},
+			buildStubs: func(t *testing.T, mockPeople *mockdb.MockPersonQuerier) {
+				mockPeople.EXPECT().
+					Get(int64(1)).
+					Return(&data.Person{ID: 1, Name: "Akira Kurosava", Version: 1}, nil)
+
+				mockPeople.EXPECT().
+					Update(&data.Person{ID: 1, Name: "Akira Kurosawa", Version: 1}).
+					DoAndReturn(func(person *data.Person) error {
+						person.Version = 2
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				var envelope struct {
+					Person data.Person `json:"person"`
+				}
+				err := json.NewDecoder(r.Body).Decode(&envelope)
+				require.NoError(t, err)
+				require.Equal(t, int32(2), envelope.Person.Version)
+			},
+		},
+		{
+			name: "Test Update Person Handler - 404 NOT FOUND",
+			body: map[string]any{"name": "Akira Kurosawa"},
+			buildStubs: func(t *testing.T, mockPeople *mockdb.MockPersonQuerier) {
+				mockPeople.EXPECT().Get(int64(1)).Return(nil, data.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, r.Code)
+			},
+		},
+		{
+			name: "Test Update Person Handler - 409 EDIT CONFLICT",
+			body: map[string]any{"name": "Akira Kurosawa"},
+			buildStubs: func(t *testing.T, mockPeople *mockdb.MockPersonQuerier) {
+				mockPeople.EXPECT().
+					Get(int64(1)).
+					Return(&data.Person{ID: 1, Name: "Akira Kurosava", Version: 1}, nil)
+
+				mockPeople.EXPECT().Update(gomock.Any()).Return(data.ErrEditConflict)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, r.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test, mockPeople := newPeopleTest(t, "/v1/people/1")
+			tc.buildStubs(t, mockPeople)
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodPatch, "/v1/people/:id", test.app.updatePersonHandler)
+
+			body, err := toReader(tc.body)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPatch, test.url, body)
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestCreatePersonCreditHandler(t *testing.T) {
+	testCases := []struct {
+		name          string
+		body          any
+		buildStubs    func(t *testing.T, mockPeople *mockdb.MockPersonQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test Create Person Credit Handler - 201 CREATED",
+			body: map[string]any{"movie_id": 10, "role": "actor", "character": "Cléo"},
+			buildStubs: func(t *testing.T, mockPeople *mockdb.MockPersonQuerier) {
+				mockPeople.EXPECT().
+					AddCredit(&data.Credit{MovieID: 10, PersonID: 2, Role: data.RoleActor, Character: "Cléo"}).
+					DoAndReturn(func(credit *data.Credit) error {
+						credit.ID = 5
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, r.Code)
+
+				var envelope struct {
+					Credit map[string]any `json:"credit"`
+				}
+				err := json.NewDecoder(r.Body).Decode(&envelope)
+				require.NoError(t, err)
+				require.Equal(t, map[string]any{"id": float64(5), "role": "actor", "character": "Cléo"}, envelope.Credit)
+			},
+		},
+		{
+			name: "Test Create Person Credit Handler - 422 CHARACTER FOR A DIRECTOR",
+			body: map[string]any{"movie_id": 10, "role": "director", "character": "Cléo"},
+			buildStubs: func(t *testing.T, mockPeople *mockdb.MockPersonQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "character")
+			},
+		},
+		{
+			name: "Test Create Person Credit Handler - 422 INVALID ROLE",
+			body: map[string]any{"role": "producer"},
+			buildStubs: func(t *testing.T, mockPeople *mockdb.MockPersonQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+
+				errs := requireErrorMap(t, r)
+				require.Contains(t, errs, "role")
+				require.Contains(t, errs, "movie_id")
+			},
+		},
+		{
+			name: "Test Create Person Credit Handler - 422 UNKNOWN MOVIE",
+			body: map[string]any{"movie_id": 10, "role": "writer"},
+			buildStubs: func(t *testing.T, mockPeople *mockdb.MockPersonQuerier) {
+				mockPeople.EXPECT().AddCredit(gomock.Any()).Return(data.ErrUnknownMovie)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "movie_id")
+			},
+		},
+		{
+			name: "Test Create Person Credit Handler - 422 DUPLICATE CREDIT",
+			body: map[string]any{"movie_id": 10, "role": "writer"},
+			buildStubs: func(t *testing.T, mockPeople *mockdb.MockPersonQuerier) {
+				mockPeople.EXPECT().AddCredit(gomock.Any()).Return(data.ErrDuplicateCredit)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "role")
+			},
+		},
+		{
+			name: "Test Create Person Credit Handler - 404 PERSON NOT FOUND",
+			body: map[string]any{"movie_id": 10, "role": "writer"},
+			buildStubs: func(t *testing.T, mockPeople *mockdb.MockPersonQuerier) {
+				mockPeople.EXPECT().AddCredit(gomock.Any()).Return(data.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, r.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test, mockPeople := newPeopleTest(t, "/v1/people/2/credits")
+			tc.buildStubs(t, mockPeople)
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodPost, "/v1/people/:id/credits", test.app.createPersonCreditHandler)
+
+			body, err := toReader(tc.body)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, test.url, body)
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestShowMovieHandlerCredits(t *testing.T) {
+	movie := randomMovie()
+	credit := &data.Credit{
+		ID:       1,
+		MovieID:  movie.ID,
+		PersonID: 4,
+		Person:   &data.PersonSummary{ID: 4, Name: "Agnès Varda"},
+		Role:     data.RoleDirector,
+	}
+
+	test, mockPeople := newPeopleTest(t, fmt.Sprintf("/v1/movies/%d?fields=id&include=credits", movie.ID))
+	defer test.close()
+
+	mockMovies, ok := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+	require.True(t, ok)
+
+	mockMovies.EXPECT().
+		GetFields(movie.ID, []string{"id"}).
+		Return(&data.Movie{ID: movie.ID}, nil)
+
+	mockPeople.EXPECT().
+		GetCreditsForMovies([]int64{movie.ID}).
+		Return(map[int64][]*data.Credit{movie.ID: {credit}}, nil)
+
+	router := httprouter.New()
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id", test.app.showMovieHandler)
+
+	request := httptest.NewRequest(http.MethodGet, test.url, nil)
+
+	router.ServeHTTP(test.recorder, request)
+
+	require.Equal(t, http.StatusOK, test.recorder.Code)
+
+	var envelope struct {
+		Movie struct {
+			ID      int64            `json:"id"`
+			Credits []map[string]any `json:"credits"`
+		} `json:"movie"`
+	}
+	err := json.NewDecoder(test.recorder.Body).Decode(&envelope)
+	require.NoError(t, err)
+
+	require.Equal(t, movie.ID, envelope.Movie.ID)
+	require.Equal(t, []map[string]any{{
+		"id":     float64(1),
+		"person": map[string]any{"id": float64(4), "name": "Agnès Varda"},
+		"role":   "director",
+	}}, envelope.Movie.Credits)
+}
+
+func TestListMoviesHandlerPersonFilter(t *testing.T) {
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(t *testing.T, mockMovies *mockdb.MockMovieQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "Test List Movies Handler - 200 OK FILTER BY PERSON",
+			query: "?person=4&person_role=director",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().
+					GetAll(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(mf data.MovieFilters, f data.Filters) ([]*data.Movie, data.Metadata, error) {
+						require.Equal(t, int64(4), mf.Person)
+						require.Equal(t, data.RoleDirector, mf.PersonRole)
+						return []*data.Movie{}, data.Metadata{}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+			},
+		},
+		{
+			name:  "Test List Movies Handler - 422 ROLE WITHOUT PERSON",
+			query: "?person_role=director",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "person_role")
+			},
+		},
+		{
+			name:  "Test List Movies Handler - 422 INVALID PERSON ROLE",
+			query: "?person=4&person_role=producer",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "person_role")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, "/v1/movies"+tc.query)
+
+			mockMovies, ok := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+			require.True(t, ok)
+			tc.buildStubs(t, mockMovies)
+
+			request := httptest.NewRequest(http.MethodGet, test.url, nil)
+
+			// when
+			test.app.listMoviesHandles(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/djudju12/greenlight/internal/data"
+	"github.com/djudju12/greenlight/internal/storage"
+	"github.com/djudju12/greenlight/internal/validator"
+)
+
+const posterOriginal = "original"
+
+// posterThumbnails are the widths of the thumbnails generated for each poster,
+// by the name they are requested with.
+var posterThumbnails = map[string]int{
+	"small":  154,
+	"medium": 342,
+}
+
+func posterSizes() []string {
+	return []string{posterOriginal, "small", "medium"}
+}
+
+// posterKey returns the key the size of the poster is stored under. The keys
+// have the version of the poster, so the files of a new upload never replace
+// the ones being served, which are only deleted once the new poster is
+// recorded.
+func posterKey(poster *data.Poster, size string) string {
+	return fmt.Sprintf("%s/%s/%s", moviePostersKey(poster.MovieID), posterVersion(poster), size)
+}
+
+// legacyPosterKey is where the posters were stored before their keys had the
+// version, which is still read for the posters uploaded back then.
+func legacyPosterKey(movieID int64, size string) string {
+	return fmt.Sprintf("%s/%s", moviePostersKey(movieID), size)
+}
+
+// moviePostersKey is the prefix of the keys of every poster of the movie.
+func moviePostersKey(movieID int64) string {
+	return fmt.Sprintf("posters/%d", movieID)
+}
+
+// posterVersion identifies the contents of the poster in its links.
+func posterVersion(poster *data.Poster) string {
+	return poster.Checksum[:16]
+}
+
+// posterContentType returns the content type the size of the poster is stored
+// in. The originals are kept as they were uploaded, and the thumbnails of the
+// images that aren't JPEG are PNG.
+func posterContentType(poster *data.Poster, size string) string {
+	if size == posterOriginal || poster.ContentType == "image/jpeg" {
+		return poster.ContentType
+	}
+
+	return "image/png"
+}
+
+// posterLinks returns the URL of each size of the poster. The URLs change with
+// every new upload, so they can be cached for good.
+func posterLinks(poster *data.Poster) envelope {
+	links := envelope{}
+
+	for _, size := range posterSizes() {
+		qs := url.Values{"v": {posterVersion(poster)}}
+		if size != posterOriginal {
+			qs.Set("size", size)
+		}
+
+		links[size] = fmt.Sprintf("%s/poster?%s", movieURL(poster.MovieID), qs.Encode())
+	}
+
+	return links
+}
+
+// uploadMoviePosterHandler stores the image in the body as the poster of the
+// movie, along with its thumbnails. The image can be the whole body or the
+// "poster" part of a multipart/form-data body.
+func (app *application) uploadMoviePosterHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.models.Movies.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	file, contentType, err := app.readUpload(w, r, "poster")
+	if err != nil {
+		switch {
+		case errors.Is(err, errUnsupportedUpload):
+			app.unsupportedMediaTypeResponse(w, r, append(slices.Clone(data.PosterContentTypes), "multipart/form-data")...)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+
+		return
+	}
+
+	if !validator.In(contentType, data.PosterContentTypes...) {
+		app.unsupportedMediaTypeResponse(w, r, data.PosterContentTypes...)
+		return
+	}
+
+	v := validator.New()
+
+	// only the header of the image is read before its dimensions are checked,
+	// so huge images are refused without being decoded
+	config, format, err := image.DecodeConfig(bytes.NewReader(file))
+	if err != nil {
+		v.AddError("poster", "must be a valid JPEG, PNG or GIF image")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	checksum := sha256.Sum256(file)
+
+	poster := &data.Poster{
+		MovieID:     id,
+		ContentType: contentType,
+		Width:       config.Width,
+		Height:      config.Height,
+		Size:        int64(len(file)),
+		Checksum:    hex.EncodeToString(checksum[:]),
+	}
+
+	v.Check("image/"+format == contentType, "content_type", "must match the format of the image")
+	if data.ValidatePoster(v, poster); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(file))
+	if err != nil {
+		v.AddError("poster", "must be a valid JPEG, PNG or GIF image")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.storePoster(poster, file, img)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	replaced, err := app.models.Movies.SetPoster(poster)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			// the movie was deleted meanwhile, along with its posters
+			app.deleteMoviePosters(id)
+			app.notFoundResponse(w, r)
+		default:
+			// the files are kept, as they can be the ones of the poster the
+			// movie has, uploaded again
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	// the old files are only deleted once nothing points to them, and an
+	// upload of the same image has the same files
+	if replaced != "" && replaced != poster.Checksum {
+		app.deletePosterFiles(&data.Poster{MovieID: id, Checksum: replaced})
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"poster": poster, "links": posterLinks(poster)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// storePoster puts the original image and its thumbnails in the storage.
+func (app *application) storePoster(poster *data.Poster, file []byte, img image.Image) error {
+	err := app.storage.Put(posterKey(poster, posterOriginal), bytes.NewReader(file))
+	if err != nil {
+		return err
+	}
+
+	for size, width := range posterThumbnails {
+		var buf bytes.Buffer
+
+		thumb := thumbnail(img, width)
+		if posterContentType(poster, size) == "image/jpeg" {
+			err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(&buf, thumb)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = app.storage.Put(posterKey(poster, size), &buf)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// showMoviePosterHandler serves the poster of the movie in the size given by
+// the size query parameter. A request for the current version of the poster,
+// which is what its links point to, can be cached for good. Any other is
+// revalidated with the ETag.
+func (app *application) showMoviePosterHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	qs := r.URL.Query()
+	size := app.readString(qs, "size", posterOriginal)
+
+	v := validator.New()
+	if v.Check(validator.In(size, posterSizes()...), "size", fmt.Sprintf("must be one of %v", posterSizes())); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	poster, err := app.models.Movies.GetPoster(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	file, err := app.storage.Open(posterKey(poster, size))
+	if errors.Is(err, storage.ErrNotFound) {
+		file, err = app.storage.Open(legacyPosterKey(id, size))
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	defer file.Close()
+
+	w.Header().Set("Content-Type", posterContentType(poster, size))
+	w.Header().Set("ETag", fmt.Sprintf(`"%s-%s"`, posterVersion(poster), size))
+
+	if qs.Get("v") == posterVersion(poster) {
+		w.Header().Set("Cache-Control", "max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+
+	// ServeContent answers the conditional and range requests
+	http.ServeContent(w, r, "", poster.UpdatedAt, file)
+}
+
+func (app *application) deleteMoviePosterHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	poster, err := app.models.Movies.DeletePoster(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	app.deletePosterFiles(poster)
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"message": "movie poster successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deletePosterFiles removes the files of the poster from the storage, along
+// with the ones stored before the keys had the version. The files are only
+// logged when they can't be removed, as without the record of the poster they
+// are never served.
+func (app *application) deletePosterFiles(poster *data.Poster) {
+	for _, size := range posterSizes() {
+		for _, key := range []string{posterKey(poster, size), legacyPosterKey(poster.MovieID, size)} {
+			err := app.storage.Delete(key)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{"key": key})
+			}
+		}
+	}
+}
+
+// deleteMoviePosters removes every poster file of a deleted movie from the
+// storage.
+func (app *application) deleteMoviePosters(movieID int64) {
+	err := app.storage.DeleteAll(moviePostersKey(movieID))
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{"key": moviePostersKey(movieID)})
+	}
+}
+
+// thumbnail scales img down to the width, keeping its aspect ratio. Each pixel
+// of the thumbnail is the average of the pixels of img it covers. Images that
+// are not wider than the width keep their size.
+func thumbnail(img image.Image, width int) *image.RGBA {
+	bounds := img.Bounds()
+
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	srcWidth, srcHeight := src.Rect.Dx(), src.Rect.Dy()
+	if srcWidth <= width {
+		return src
+	}
+
+	height := max(1, srcHeight*width/srcWidth)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcHeight/height, (y+1)*srcHeight/height
+
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcWidth/width, (x+1)*srcWidth/width
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					sum[0] += int(src.Pix[i])
+					sum[1] += int(src.Pix[i+1])
+					sum[2] += int(src.Pix[i+2])
+					sum[3] += int(src.Pix[i+3])
+					i += 4
+				}
+			}
+
+			n := (y1 - y0) * (x1 - x0)
+			j := dst.PixOffset(x, y)
+			for c := range sum {
+				dst.Pix[j+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+
+	return dst
+}
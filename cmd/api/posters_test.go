@@ -0,0 +1,397 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/data"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/djudju12/greenlight/internal/storage"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func randomPoster(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	require.NoError(t, err)
+
+	return buf.Bytes()
+}
+
+func multipartPoster(t *testing.T, field, contentType string, file []byte) (io.Reader, string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="`+field+`"; filename="poster"`)
+	header.Set("Content-Type", contentType)
+
+	part, err := writer.CreatePart(header)
+	require.NoError(t, err)
+
+	_, err = part.Write(file)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	return &buf, writer.FormDataContentType()
+}
+
+func TestUploadMoviePosterHandler(t *testing.T) {
+	poster := randomPoster(t, 400, 600)
+
+	checksum := sha256.Sum256(poster)
+	uploaded := &data.Poster{MovieID: 7, Checksum: hex.EncodeToString(checksum[:])}
+	replaced := &data.Poster{MovieID: 7, Checksum: "0123456789abcdef0123456789abcdef"}
+
+	testCases := []struct {
+		name          string
+		body          func(t *testing.T) (io.Reader, string)
+		buildStubs    func(t *testing.T, mockMovies *mockdb.MockMovieQuerier)
+		checkResponse func(t *testing.T, test test, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test Upload Movie Poster Handler - 200 OK RAW BODY",
+			body: func(t *testing.T) (io.Reader, string) {
+				return bytes.NewReader(poster), "image/png"
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().
+					SetPoster(gomock.Any()).
+					DoAndReturn(func(p *data.Poster) (string, error) {
+						require.Equal(t, "image/png", p.ContentType)
+						require.Equal(t, 400, p.Width)
+						require.Equal(t, 600, p.Height)
+						require.Equal(t, int64(len(poster)), p.Size)
+						require.Equal(t, uploaded.Checksum, p.Checksum)
+						return "", nil
+					})
+			},
+			checkResponse: func(t *testing.T, test test, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				for size, width := range map[string]int{"original": 400, "medium": 342, "small": 154} {
+					f, err := test.app.storage.Open(posterKey(uploaded, size))
+					require.NoError(t, err)
+
+					config, _, err := image.DecodeConfig(f)
+					require.NoError(t, err)
+					require.Equal(t, width, config.Width)
+					require.Equal(t, width*600/400, config.Height)
+					require.NoError(t, f.Close())
+				}
+			},
+		},
+		{
+			name: "Test Upload Movie Poster Handler - 200 OK MULTIPART BODY",
+			body: func(t *testing.T) (io.Reader, string) {
+				return multipartPoster(t, "poster", "image/png", poster)
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().SetPoster(gomock.Any()).Return("", nil)
+			},
+			checkResponse: func(t *testing.T, test test, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+			},
+		},
+		{
+			name: "Test Upload Movie Poster Handler - 200 OK REPLACING THE POSTER",
+			body: func(t *testing.T) (io.Reader, string) {
+				return bytes.NewReader(poster), "image/png"
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().SetPoster(gomock.Any()).Return(replaced.Checksum, nil)
+			},
+			checkResponse: func(t *testing.T, test test, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				// the files of the replaced poster are deleted once the new one is recorded
+				for _, size := range posterSizes() {
+					_, err := test.app.storage.Open(posterKey(replaced, size))
+					require.ErrorIs(t, err, storage.ErrNotFound)
+
+					f, err := test.app.storage.Open(posterKey(uploaded, size))
+					require.NoError(t, err)
+					require.NoError(t, f.Close())
+				}
+			},
+		},
+		{
+			name: "Test Upload Movie Poster Handler - 200 OK SAME POSTER AGAIN",
+			body: func(t *testing.T) (io.Reader, string) {
+				return bytes.NewReader(poster), "image/png"
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().SetPoster(gomock.Any()).Return(uploaded.Checksum, nil)
+			},
+			checkResponse: func(t *testing.T, test test, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				for _, size := range posterSizes() {
+					f, err := test.app.storage.Open(posterKey(uploaded, size))
+					require.NoError(t, err)
+					require.NoError(t, f.Close())
+				}
+			},
+		},
+		{
+			name: "Test Upload Movie Poster Handler - 500 RECORD NOT SAVED",
+			body: func(t *testing.T) (io.Reader, string) {
+				return bytes.NewReader(poster), "image/png"
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().SetPoster(gomock.Any()).Return("", sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, test test, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, r.Code)
+
+				// the poster being served is left alone
+				f, err := test.app.storage.Open(posterKey(replaced, posterOriginal))
+				require.NoError(t, err)
+				require.NoError(t, f.Close())
+			},
+		},
+		{
+			name: "Test Upload Movie Poster Handler - 400 MULTIPART WITHOUT POSTER",
+			body: func(t *testing.T) (io.Reader, string) {
+				return multipartPoster(t, "image", "image/png", poster)
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, test test, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, r.Code)
+			},
+		},
+		{
+			name: "Test Upload Movie Poster Handler - 400 LARGER THAN THE UPLOAD LIMIT",
+			body: func(t *testing.T) (io.Reader, string) {
+				return bytes.NewReader(bytes.Repeat([]byte{0}, 2<<20)), "image/png"
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, test test, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, r.Code)
+			},
+		},
+		{
+			name: "Test Upload Movie Poster Handler - 415 NOT AN IMAGE",
+			body: func(t *testing.T) (io.Reader, string) {
+				return bytes.NewReader(poster), "application/pdf"
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, test test, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnsupportedMediaType, r.Code)
+			},
+		},
+		{
+			name: "Test Upload Movie Poster Handler - 422 CONTENT TYPE DOES NOT MATCH",
+			body: func(t *testing.T) (io.Reader, string) {
+				return bytes.NewReader(poster), "image/jpeg"
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, test test, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "content_type")
+			},
+		},
+		{
+			name: "Test Upload Movie Poster Handler - 422 TOO SMALL",
+			body: func(t *testing.T) (io.Reader, string) {
+				return bytes.NewReader(randomPoster(t, 50, 75)), "image/png"
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, test test, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "width")
+			},
+		},
+		{
+			name: "Test Upload Movie Poster Handler - 422 BROKEN IMAGE",
+			body: func(t *testing.T) (io.Reader, string) {
+				return bytes.NewReader([]byte("not an image at all")), "image/png"
+			},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, test test, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "poster")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, "/v1/movies/7/poster")
+			test.app.config.uploads.maxBytes = 1 << 20
+
+			for _, size := range posterSizes() {
+				err := test.app.storage.Put(posterKey(replaced, size), bytes.NewReader([]byte(size+" poster")))
+				require.NoError(t, err)
+			}
+
+			mockMovies := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+			mockMovies.EXPECT().Get(int64(7)).Return(&data.Movie{ID: 7}, nil)
+			tc.buildStubs(t, mockMovies)
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodPut, "/v1/movies/:id/poster", test.app.uploadMoviePosterHandler)
+
+			body, contentType := tc.body(t)
+
+			request := httptest.NewRequest(http.MethodPut, test.url, body)
+			request.Header.Set("Content-Type", contentType)
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestShowMoviePosterHandler(t *testing.T) {
+	poster := &data.Poster{
+		MovieID:     7,
+		ContentType: "image/jpeg",
+		Width:       400,
+		Height:      600,
+		Checksum:    "0123456789abcdef0123456789abcdef",
+		UpdatedAt:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	testCases := []struct {
+		name          string
+		url           string
+		header        http.Header
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test Show Movie Poster Handler - 200 OK CURRENT VERSION",
+			url:  "/v1/movies/7/poster?size=small&v=0123456789abcdef",
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, "image/jpeg", r.Header().Get("Content-Type"))
+				require.Equal(t, `"0123456789abcdef-small"`, r.Header().Get("ETag"))
+				require.Equal(t, "max-age=31536000, immutable", r.Header().Get("Cache-Control"))
+				require.Equal(t, "small poster", r.Body.String())
+			},
+		},
+		{
+			name: "Test Show Movie Poster Handler - 200 OK WITHOUT VERSION",
+			url:  "/v1/movies/7/poster",
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, "no-cache", r.Header().Get("Cache-Control"))
+				require.Equal(t, "original poster", r.Body.String())
+			},
+		},
+		{
+			name: "Test Show Movie Poster Handler - 200 OK UPLOADED BEFORE THE VERSIONED KEYS",
+			url:  "/v1/movies/7/poster?size=medium",
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, "medium poster", r.Body.String())
+			},
+		},
+		{
+			name:   "Test Show Movie Poster Handler - 304 NOT MODIFIED",
+			url:    "/v1/movies/7/poster",
+			header: http.Header{"If-None-Match": {`"0123456789abcdef-original"`}},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotModified, r.Code)
+				require.Empty(t, r.Body.String())
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, tc.url)
+
+			for size, contents := range map[string]string{"original": "original poster", "small": "small poster"} {
+				err := test.app.storage.Put(posterKey(poster, size), bytes.NewReader([]byte(contents)))
+				require.NoError(t, err)
+			}
+
+			err := test.app.storage.Put(legacyPosterKey(poster.MovieID, "medium"), bytes.NewReader([]byte("medium poster")))
+			require.NoError(t, err)
+
+			mockMovies := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+			mockMovies.EXPECT().GetPoster(int64(7)).Return(poster, nil)
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodGet, "/v1/movies/:id/poster", test.app.showMoviePosterHandler)
+
+			request := httptest.NewRequest(http.MethodGet, test.url, nil)
+			for key, values := range tc.header {
+				request.Header[key] = values
+			}
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		img.Set(x, 0, color.RGBA{uint8(x * 60), 0, 0, 255})
+		img.Set(x, 1, color.RGBA{0, uint8(x * 60), 0, 255})
+	}
+
+	thumb := thumbnail(img, 2)
+	require.Equal(t, image.Rect(0, 0, 2, 1), thumb.Bounds())
+
+	// each pixel averages the 2x2 block it covers
+	require.Equal(t, color.RGBA{15, 15, 0, 255}, thumb.RGBAAt(0, 0))
+	require.Equal(t, color.RGBA{75, 75, 0, 255}, thumb.RGBAAt(1, 0))
+
+	// smaller images keep their size
+	require.Equal(t, img.Bounds(), thumbnail(img, 10).Bounds())
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, thumb, nil))
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/djudju12/greenlight/internal/data"
+	"github.com/djudju12/greenlight/internal/validator"
+)
+
+// similarMoviesHandler returns the movies most similar to the movie, the most
+// similar first, with their similarity in their score. See
+// data.MovieModel.GetSimilar.
+func (app *application) similarMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	limit := app.readInt(r.URL.Query(), "limit", 10, v)
+	view := app.readMovieView(r, v)
+
+	if data.ValidateRecommendationLimit(v, limit); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movies, err := app.models.Movies.GetSimilar(id, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	app.writeRankedMovies(w, r, view, movies)
+}
+
+// recommendationsHandler returns the movies recommended to the authenticated
+// user from their reviews and lists, the most recommended first. See
+// data.MovieModel.GetRecommendations.
+func (app *application) recommendationsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	limit := app.readInt(r.URL.Query(), "limit", 10, v)
+	view := app.readMovieView(r, v)
+
+	if data.ValidateRecommendationLimit(v, limit); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movies, err := app.models.Movies.GetRecommendations(app.contextGetUser(r).ID, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeRankedMovies(w, r, view, movies)
+}
+
+// writeRankedMovies sends the movies in the view, like listMoviesHandles does,
+// keeping their order.
+func (app *application) writeRankedMovies(w http.ResponseWriter, r *http.Request, view MovieView, movies []*data.Movie) {
+	w.Header().Add("Vary", "Accept-Language")
+	w.Header().Add("Vary", runtimeFormatHeader)
+
+	if view.selects("title") {
+		_, err := app.localizeTitles(r, movies)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	table, err := view.renderTable(app, movies)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"movies": table}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/djudju12/greenlight/internal/data"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimilarMoviesHandler(t *testing.T) {
+	similar := []*data.Movie{
+		{ID: 4, Title: "The Godfather Part II", Year: 1974, Runtime: 202, Genres: []string{"crime", "drama"}, Score: 0.9},
+		{ID: 9, Title: "Goodfellas", Year: 1990, Runtime: 146, Genres: []string{"crime"}, Score: 0.45},
+	}
+
+	testCases := []struct {
+		name          string
+		url           string
+		buildStubs    func(t *testing.T, mockMovies *mockdb.MockMovieQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test Similar Movies Handler - 200 OK",
+			url:  "/v1/movies/7/similar?limit=2",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().GetSimilar(int64(7), 2).Return(similar, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				movies := requireRankedMovies(t, r)
+				require.Len(t, movies, 2)
+				require.EqualValues(t, 4, movies[0]["id"])
+				require.Equal(t, 0.9, movies[0]["score"])
+				require.EqualValues(t, 9, movies[1]["id"])
+			},
+		},
+		{
+			name: "Test Similar Movies Handler - 200 OK ONLY SELECTED FIELDS",
+			url:  "/v1/movies/7/similar?fields=title&runtime_format=hours",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().GetSimilar(int64(7), 10).Return(similar, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				movies := requireRankedMovies(t, r)
+				require.Equal(t, map[string]any{"title": "The Godfather Part II"}, movies[0])
+			},
+		},
+		{
+			name: "Test Similar Movies Handler - 404 MOVIE NOT FOUND",
+			url:  "/v1/movies/7/similar",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().GetSimilar(int64(7), 10).Return(nil, data.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, r.Code)
+			},
+		},
+		{
+			name: "Test Similar Movies Handler - 422 LIMIT TOO LARGE",
+			url:  "/v1/movies/7/similar?limit=500",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "limit")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, tc.url)
+
+			mockMovies := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+			tc.buildStubs(t, mockMovies)
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodGet, "/v1/movies/:id/similar", test.app.similarMoviesHandler)
+
+			request := httptest.NewRequest(http.MethodGet, test.url, nil)
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestRecommendationsHandler(t *testing.T) {
+	user := &data.User{ID: 3, Name: "Ana", Activated: true}
+
+	// given
+	test := newMovieTest(t, "/v1/users/me/recommendations?limit=5")
+
+	mockMovies := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+	mockMovies.EXPECT().
+		GetRecommendations(user.ID, 5).
+		Return([]*data.Movie{{ID: 4, Title: "Paris, Texas", Year: 1984, Genres: []string{"drama"}, Score: 0.6}}, nil)
+
+	router := httprouter.New()
+	router.HandlerFunc(http.MethodGet, "/v1/users/:id/recommendations", test.app.byIDOr(nil, map[string]http.HandlerFunc{
+		"me": test.app.recommendationsHandler,
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, test.url, nil)
+	request = test.app.contextSetUser(request, user)
+
+	// when
+	router.ServeHTTP(test.recorder, request)
+
+	// then
+	require.Equal(t, http.StatusOK, test.recorder.Code)
+
+	movies := requireRankedMovies(t, test.recorder)
+	require.Len(t, movies, 1)
+	require.Equal(t, "Paris, Texas", movies[0]["title"])
+	require.Equal(t, 0.6, movies[0]["score"])
+
+	test.close()
+}
+
+func requireRankedMovies(t *testing.T, r *httptest.ResponseRecorder) []map[string]any {
+	t.Helper()
+
+	var envelope struct {
+		Movies []map[string]any `json:"movies"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&envelope)
+	require.NoError(t, err)
+
+	return envelope.Movies
+}
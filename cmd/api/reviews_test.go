@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/djudju12/greenlight/internal/data"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newReviewTest(t *testing.T, url string) (test, *mockdb.MockReviewQuerier) {
+	test := newMovieTest(t, url)
+
+	mockReviews := mockdb.NewMockReviewQuerier(gomock.NewController(t))
+	test.app.models.Reviews = mockReviews
+
+	return test, mockReviews
+}
+
+func TestCreateMovieReviewHandler(t *testing.T) {
+	user := &data.User{ID: 3, Name: "reviewer", Activated: true}
+
+	testCases := []struct {
+		name          string
+		body          any
+		buildStubs    func(t *testing.T, mockReviews *mockdb.MockReviewQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test Create Movie Review Handler - 201 CREATED",
+			body: map[string]any{"score": 8, "body": "loved it"},
+			buildStubs: func(t *testing.T, mockReviews *mockdb.MockReviewQuerier) {
+				mockReviews.EXPECT().
+					Insert(&data.Review{MovieID: 1, UserID: user.ID, Author: user.Name, Score: 8, Body: "loved it"}).
+					DoAndReturn(func(review *data.Review) error {
+						review.ID = 4
+						review.Version = 1
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, r.Code)
+				require.Equal(t, "/v1/movies/1/reviews/4", r.Header().Get("Location"))
+
+				var envelope struct {
+					Review data.Review `json:"review"`
+				}
+				err := json.NewDecoder(r.Body).Decode(&envelope)
+				require.NoError(t, err)
+				require.Equal(t, int32(8), envelope.Review.Score)
+				require.Equal(t, user.Name, envelope.Review.Author)
+			},
+		},
+		{
+			name: "Test Create Movie Review Handler - 422 SCORE OUT OF RANGE",
+			body: map[string]any{"score": 11},
+			buildStubs: func(t *testing.T, mockReviews *mockdb.MockReviewQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "score")
+			},
+		},
+		{
+			name: "Test Create Movie Review Handler - 422 ALREADY REVIEWED",
+			body: map[string]any{"score": 5},
+			buildStubs: func(t *testing.T, mockReviews *mockdb.MockReviewQuerier) {
+				mockReviews.EXPECT().Insert(gomock.Any()).Return(data.ErrDuplicateReview)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "review")
+			},
+		},
+		{
+			name: "Test Create Movie Review Handler - 404 MOVIE NOT FOUND",
+			body: map[string]any{"score": 5},
+			buildStubs: func(t *testing.T, mockReviews *mockdb.MockReviewQuerier) {
+				mockReviews.EXPECT().Insert(gomock.Any()).Return(data.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, r.Code)
+			},
+		},
+		{
+			name: "Test Create Movie Review Handler - 500 DB RETURN ERROR",
+			body: map[string]any{"score": 5},
+			buildStubs: func(t *testing.T, mockReviews *mockdb.MockReviewQuerier) {
+				mockReviews.EXPECT().Insert(gomock.Any()).Return(errors.New("DB ERROR"))
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, r.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test, mockReviews := newReviewTest(t, "/v1/movies/1/reviews")
+			tc.buildStubs(t, mockReviews)
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodPost, "/v1/movies/:id/reviews", test.app.createMovieReviewHandler)
+
+			body, err := toReader(tc.body)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, test.url, body)
+			request = test.app.contextSetUser(request, user)
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestUpdateMovieReviewHandler(t *testing.T) {
+	user := &data.User{ID: 3, Name: "reviewer", Activated: true}
+
+	testCases := []struct {
+		name          string
+		body          any
+		buildStubs    func(t *testing.T, mockReviews *mockdb.MockReviewQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test Update Movie Review Handler - 200 OK",
+			body: map[string]any{"score": 9},
+			buildStubs: func(t *testing.T, mockReviews *mockdb.MockReviewQuerier) {
+				mockReviews.EXPECT().
+					Get(int64(1), int64(4)).
+					Return(&data.Review{ID: 4, MovieID: 1, UserID: user.ID, Score: 6, Body: "fine", Version: 1}, nil)
+
+				mockReviews.EXPECT().
+					Update(&data.Review{ID: 4, MovieID: 1, UserID: user.ID, Score: 9, Body: "fine", Version: 1}).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+			},
+		},
+		{
+			name: "Test Update Movie Review Handler - 403 REVIEW OF ANOTHER USER",
+			body: map[string]any{"score": 9},
+			buildStubs: func(t *testing.T, mockReviews *mockdb.MockReviewQuerier) {
+				mockReviews.EXPECT().
+					Get(int64(1), int64(4)).
+					Return(&data.Review{ID: 4, MovieID: 1, UserID: user.ID + 1, Score: 6, Version: 1}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, r.Code)
+			},
+		},
+		{
+			name: "Test Update Movie Review Handler - 404 NOT FOUND",
+			body: map[string]any{"score": 9},
+			buildStubs: func(t *testing.T, mockReviews *mockdb.MockReviewQuerier) {
+				mockReviews.EXPECT().Get(int64(1), int64(4)).Return(nil, data.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, r.Code)
+			},
+		},
+		{
+			name: "Test Update Movie Review Handler - 409 EDIT CONFLICT",
+			body: map[string]any{"body": "changed my mind"},
+			buildStubs: func(t *testing.T, mockReviews *mockdb.MockReviewQuerier) {
+				mockReviews.EXPECT().
+					Get(int64(1), int64(4)).
+					Return(&data.Review{ID: 4, MovieID: 1, UserID: user.ID, Score: 6, Version: 1}, nil)
+
+				mockReviews.EXPECT().Update(gomock.Any()).Return(data.ErrEditConflict)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, r.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test, mockReviews := newReviewTest(t, "/v1/movies/1/reviews/4")
+			tc.buildStubs(t, mockReviews)
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodPatch, "/v1/movies/:id/reviews/:review_id", test.app.updateMovieReviewHandler)
+
+			body, err := toReader(tc.body)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPatch, test.url, body)
+			request = test.app.contextSetUser(request, user)
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
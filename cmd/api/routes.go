@@ -16,17 +16,114 @@ func (app *application) routes() http.Handler {
 	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
 
 	router.HandlerFunc(http.MethodGet, "/v1/movies", app.requirePermission("movies:read", app.listMoviesHandles))
-	router.HandlerFunc(http.MethodGet, "/v1/movies/:id", app.requirePermission("movies:read", app.showMovieHandler))
-	router.HandlerFunc(http.MethodPost, "/v1/movies", app.requirePermission("movies:write", app.createMovieHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id", app.byIDOr(
+		app.requirePermission("movies:read", app.showMovieHandler),
+		map[string]http.HandlerFunc{
+			"suggest": app.requirePermission("movies:read", app.suggestMoviesHandler),
+			"stats":   app.requirePermission("movies:read", app.movieStatsHandler),
+			"export":  app.requirePermission("movies:read", app.exportMoviesHandler),
+			"events":  app.requirePermission("movies:read", app.movieEventsHandler),
+		}))
+	router.HandlerFunc(http.MethodPost, "/v1/movies", app.requirePermission("movies:write", app.idempotent(app.createMovieHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id", app.byIDOr(nil, map[string]http.HandlerFunc{
+		"import": app.requirePermission("movies:write", app.importMoviesHandler),
+		"batch":  app.requirePermission("movies:write", app.batchMoviesHandler),
+	}))
 	router.HandlerFunc(http.MethodPatch, "/v1/movies/:id", app.requirePermission("movies:write", app.updatesMovieHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/movies/:id", app.requirePermission("movies:write", app.replaceMovieHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id", app.requirePermission("movies:write", app.deleteMovieHandler))
 
-	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandle)
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/poster", app.requirePermission("movies:read", app.showMoviePosterHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/movies/:id/poster", app.requirePermission("movies:write", app.uploadMoviePosterHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id/poster", app.requirePermission("movies:write", app.deleteMoviePosterHandler))
+
+	router.HandlerFunc(http.MethodPut, "/v1/movies/:id/titles/:language", app.requirePermission("movies:write", app.setMovieTitleHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id/titles/:language", app.requirePermission("movies:write", app.deleteMovieTitleHandler))
+
+	router.HandlerFunc(http.MethodPut, "/v1/movies/:id/external_ids/:provider", app.requirePermission("movies:write", app.setMovieExternalIDHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id/external_ids/:provider", app.requirePermission("movies:write", app.deleteMovieExternalIDHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/merge", app.requirePermission("movies:write", app.mergeMovieHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/similar", app.requirePermission("movies:read", app.similarMoviesHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/reviews", app.requirePermission("movies:read", app.listMovieReviewsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/reviews", app.requireActivatedUser(app.createMovieReviewHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/movies/:id/reviews/:review_id", app.requireActivatedUser(app.updateMovieReviewHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id/reviews/:review_id", app.requireActivatedUser(app.deleteMovieReviewHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/people", app.requirePermission("movies:read", app.listPeopleHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/people", app.requirePermission("movies:write", app.createPersonHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/people/:id", app.requirePermission("movies:read", app.showPersonHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/people/:id", app.requirePermission("movies:write", app.updatePersonHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/people/:id", app.requirePermission("movies:write", app.deletePersonHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/people/:id/credits", app.requirePermission("movies:read", app.listPersonCreditsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/people/:id/credits", app.requirePermission("movies:write", app.createPersonCreditHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/people/:id/credits/:credit_id", app.requirePermission("movies:write", app.deletePersonCreditHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/lists", app.requireActivatedUser(app.listOwnListsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/lists", app.requirePermission("lists:write", app.createListHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/lists/:id", app.showListHandler)
+	router.HandlerFunc(http.MethodPatch, "/v1/lists/:id", app.requirePermission("lists:write", app.updateListHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/lists/:id", app.requirePermission("lists:write", app.deleteListHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/lists/:id/order", app.requirePermission("lists:write", app.reorderListHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/lists/:id/items", app.requirePermission("lists:write", app.addListItemHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/lists/:id/items/:movie_id", app.requirePermission("lists:write", app.updateListItemHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/lists/:id/items/:movie_id", app.requirePermission("lists:write", app.removeListItemHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/:id/lists", app.listUserListsHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/users/:id/recommendations", app.byIDOr(nil, map[string]http.HandlerFunc{
+		"me": app.requirePermission("movies:read", app.recommendationsHandler),
+	}))
+
+	router.HandlerFunc(http.MethodGet, "/v1/genres", app.requirePermission("movies:read", app.listGenresHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/genres", app.requirePermission("genres:manage", app.createGenreHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/genres/:slug/merge", app.requirePermission("genres:manage", app.mergeGenreHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/users", app.idempotent(app.registerUserHandle))
 	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandle)
 
 	router.HandlerFunc(http.MethodPost, "/v1/tokens/auth", app.createAuthenticationTokenHandler)
 
+	router.HandlerFunc(http.MethodGet, "/v1/webhooks", app.requirePermission("webhooks:manage", app.listWebhooksHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/webhooks", app.requirePermission("webhooks:manage", app.createWebhookHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/webhooks/:id", app.requirePermission("webhooks:manage", app.showWebhookHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/webhooks/:id", app.requirePermission("webhooks:manage", app.deleteWebhookHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/webhooks/:id/deliveries", app.requirePermission("webhooks:manage", app.listWebhookDeliveriesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/webhooks/:id/deliveries/:delivery_id/redeliver", app.requirePermission("webhooks:manage", app.redeliverWebhookHandler))
+
 	router.Handler(http.MethodGet, "/debug/vars", expvar.Handler())
 
-	return app.metrics(app.recoverPanic(app.enableCORS(app.rateLimit(app.authenticate(router)))))
+	limiter := app.config.limiter
+	handler := app.authenticate(app.negotiateResponse(router))
+
+	// the suggestions are requested on every keystroke, so they get their own and
+	// more generous limit instead of consuming the one shared by the other routes
+	limited := app.routeByPath(
+		app.rateLimit(limiter.rps, limiter.burst, handler),
+		map[string]http.Handler{
+			"/v1/movies/suggest": app.rateLimit(limiter.suggestRps, limiter.suggestBurst, handler),
+		})
+
+	return app.metrics(app.recoverPanic(app.enableCORS(limited)))
+}
+
+// httprouter doesn't accept a static segment in the same position as a named
+// parameter, so routes like /v1/movies/suggest can't be registered next to
+// /v1/movies/:id. byIDOr is registered for the :id route instead: it serves the
+// static handler when the :id value names one, and byID otherwise. A nil byID
+// means that there is no :id route for the method.
+func (app *application) byIDOr(byID http.HandlerFunc, static map[string]http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := httprouter.ParamsFromContext(r.Context())
+
+		if next, ok := static[params.ByName("id")]; ok {
+			next(w, r)
+			return
+		}
+
+		if byID == nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		byID(w, r)
+	}
 }
@@ -12,6 +12,10 @@ import (
 	"time"
 )
 
+// writeTimeout is how long a handler has to write its response. Streaming
+// handlers push the deadline forward while they are still sending data.
+const writeTimeout = 30 * time.Second
+
 func (app *application) serve() error {
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", app.config.port),
@@ -19,9 +23,13 @@ func (app *application) serve() error {
 		IdleTimeout:  time.Minute,
 		ErrorLog:     log.New(app.logger, "", 0),
 		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		WriteTimeout: writeTimeout,
 	}
 
+	// the event streams never become idle, so they are ended for the shutdown
+	// to complete
+	srv.RegisterOnShutdown(app.events.close)
+
 	shutdownError := make(chan error)
 	go func() {
 		quit := make(chan os.Signal, 1)
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/data"
+)
+
+// statsCache keeps the last computed catalog statistics, so the aggregations
+// over the whole movies table run at most once per cache interval.
+type statsCache struct {
+	mu     sync.Mutex
+	stats  *data.CatalogStats
+	expiry time.Time
+}
+
+func (app *application) catalogStats() (*data.CatalogStats, error) {
+	app.stats.mu.Lock()
+	defer app.stats.mu.Unlock()
+
+	// holding the lock while the stats are computed means that concurrent
+	// requests on an expired cache wait for a single query instead of all of them
+	// hitting the database at once
+	if app.stats.stats != nil && time.Now().Before(app.stats.expiry) {
+		return app.stats.stats, nil
+	}
+
+	stats, err := app.models.Movies.GetStats()
+	if err != nil {
+		return nil, err
+	}
+
+	app.stats.stats = stats
+	app.stats.expiry = time.Now().Add(app.config.stats.cacheTTL)
+
+	return stats, nil
+}
+
+func (app *application) movieStatsHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := app.catalogStats()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"stats": stats}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/data"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMovieStatsHandler(t *testing.T) {
+	stats := &data.CatalogStats{
+		TotalMovies:     3,
+		ByGenre:         []data.FacetCount{{Value: "drama", Count: 2}, {Value: "comedy", Count: 1}},
+		ByYear:          []data.YearCount{{Year: 1999, Count: 3}},
+		AverageRuntime:  110,
+		MedianRuntime:   105,
+		RecentAdditions: []*data.Movie{randomMovie()},
+		GeneratedAt:     time.Now().UTC().Truncate(time.Second),
+	}
+
+	testCases := []struct {
+		name          string
+		cacheTTL      time.Duration
+		requests      int
+		buildStubs    func(t *testing.T, app *application)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Test Movie Stats Handler - 200 OK SERVED FROM CACHE",
+			cacheTTL: time.Minute,
+			requests: 3,
+			buildStubs: func(t *testing.T, app *application) {
+				mockMovies, ok := app.models.Movies.(*mockdb.MockMovieQuerier)
+				require.True(t, ok)
+
+				mockMovies.EXPECT().
+					GetStats().
+					Times(1).
+					Return(stats, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+
+				var envelope struct {
+					Stats data.CatalogStats `json:"stats"`
+				}
+				err := json.NewDecoder(r.Body).Decode(&envelope)
+				require.NoError(t, err)
+
+				require.Equal(t, stats.TotalMovies, envelope.Stats.TotalMovies)
+				require.Equal(t, stats.ByGenre, envelope.Stats.ByGenre)
+				require.Equal(t, stats.ByYear, envelope.Stats.ByYear)
+				require.Equal(t, stats.AverageRuntime, envelope.Stats.AverageRuntime)
+				require.Equal(t, stats.MedianRuntime, envelope.Stats.MedianRuntime)
+				require.Len(t, envelope.Stats.RecentAdditions, 1)
+			},
+		},
+		{
+			name:     "Test Movie Stats Handler - 200 OK CACHE EXPIRED",
+			cacheTTL: 0,
+			requests: 2,
+			buildStubs: func(t *testing.T, app *application) {
+				mockMovies, ok := app.models.Movies.(*mockdb.MockMovieQuerier)
+				require.True(t, ok)
+
+				mockMovies.EXPECT().
+					GetStats().
+					Times(2).
+					Return(stats, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+			},
+		},
+		{
+			name:     "Test Movie Stats Handler - 500 DB RETURN ERROR",
+			cacheTTL: time.Minute,
+			requests: 1,
+			buildStubs: func(t *testing.T, app *application) {
+				mockMovies, ok := app.models.Movies.(*mockdb.MockMovieQuerier)
+				require.True(t, ok)
+
+				mockMovies.EXPECT().
+					GetStats().
+					Return(nil, errors.New("DB ERROR"))
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, r.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, "/v1/movies/stats")
+			test.app.config.stats.cacheTTL = tc.cacheTTL
+
+			tc.buildStubs(t, test.app)
+
+			// when
+			var recorder *httptest.ResponseRecorder
+			for i := 0; i < tc.requests; i++ {
+				recorder = httptest.NewRecorder()
+				request := httptest.NewRequest(http.MethodGet, test.url, nil)
+				test.app.movieStatsHandler(recorder, request)
+			}
+
+			// then
+			tc.checkResponse(t, recorder)
+			test.close()
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/djudju12/greenlight/internal/data"
+	"github.com/djudju12/greenlight/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+type SetMovieTitleRequest struct {
+	Title string `json:"title"`
+}
+
+// setMovieTitleHandler adds the title of the movie in the language of the URL,
+// or replaces it when the movie already has one in that language.
+func (app *application) setMovieTitleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input SetMovieTitleRequest
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	title := &data.MovieTitle{
+		MovieID:  id,
+		Language: httprouter.ParamsFromContext(r.Context()).ByName("language"),
+		Title:    input.Title,
+	}
+
+	v := validator.New()
+	if data.ValidateMovieTitle(v, title); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	title.Language = data.CanonicalLanguageTag(title.Language)
+
+	err = app.models.Movies.SetTitle(title)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"title": title}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteMovieTitleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	language := httprouter.ParamsFromContext(r.Context()).ByName("language")
+
+	err = app.models.Movies.DeleteTitle(id, data.CanonicalLanguageTag(language))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"message": "movie title successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// localizeTitles replaces the title of each movie with its localized title in
+// the language that best matches the Accept-Language header of the request.
+// The movies without an acceptable localized title keep their original title.
+// It returns the language of the title of each movie, "" for the original ones.
+func (app *application) localizeTitles(r *http.Request, movies []*data.Movie) ([]string, error) {
+	languages := make([]string, len(movies))
+
+	if r.Header.Get("Accept-Language") == "" || len(movies) == 0 {
+		return languages, nil
+	}
+
+	titles, err := app.models.Movies.GetTitles(movieIDs(movies))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, movie := range movies {
+		offers := make([]string, len(titles[movie.ID]))
+		for j, title := range titles[movie.ID] {
+			offers[j] = title.Language
+		}
+
+		language := app.negotiateLanguage(r, offers...)
+		if language == "" {
+			continue
+		}
+
+		for _, title := range titles[movie.ID] {
+			if title.Language == language {
+				movie.Title = title.Title
+			}
+		}
+
+		languages[i] = language
+	}
+
+	return languages, nil
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/djudju12/greenlight/internal/data"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateLanguage(t *testing.T) {
+	testCases := []struct {
+		name           string
+		acceptLanguage string
+		offers         []string
+		expected       string
+	}{
+		{
+			name:           "no header",
+			acceptLanguage: "",
+			offers:         []string{"fr"},
+			expected:       "",
+		},
+		{
+			name:           "exact match ignoring case",
+			acceptLanguage: "PT-br",
+			offers:         []string{"fr", "pt-BR"},
+			expected:       "pt-BR",
+		},
+		{
+			name:           "highest q-value wins",
+			acceptLanguage: "fr;q=0.5, de",
+			offers:         []string{"fr", "de"},
+			expected:       "de",
+		},
+		{
+			name:           "a range matches its more specific tags",
+			acceptLanguage: "pt",
+			offers:         []string{"pt-BR"},
+			expected:       "pt-BR",
+		},
+		{
+			name:           "a specific range falls back to its language",
+			acceptLanguage: "pt-BR",
+			offers:         []string{"pt"},
+			expected:       "pt",
+		},
+		{
+			name:           "ties go to the more specific match",
+			acceptLanguage: "pt-BR, pt;q=0.9",
+			offers:         []string{"pt-PT", "pt"},
+			expected:       "pt",
+		},
+		{
+			name:           "q=0 excludes the language",
+			acceptLanguage: "fr;q=0",
+			offers:         []string{"fr"},
+			expected:       "",
+		},
+		{
+			name:           "the wildcard keeps the original",
+			acceptLanguage: "*",
+			offers:         []string{"fr"},
+			expected:       "",
+		},
+	}
+
+	app := &application{}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/v1/movies/1", nil)
+			if tc.acceptLanguage != "" {
+				request.Header.Set("Accept-Language", tc.acceptLanguage)
+			}
+
+			require.Equal(t, tc.expected, app.negotiateLanguage(request, tc.offers...))
+		})
+	}
+}
+
+func TestShowMovieHandlerLocalized(t *testing.T) {
+	movie := randomMovie()
+	original := movie.Title
+
+	titles := map[int64][]*data.MovieTitle{
+		movie.ID: {
+			{MovieID: movie.ID, Language: "de", Title: "Der Pate"},
+			{MovieID: movie.ID, Language: "pt-BR", Title: "O Poderoso Chefão"},
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		acceptLanguage string
+		buildStubs     func(t *testing.T, mockMovies *mockdb.MockMovieQuerier)
+		checkResponse  func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name:           "Test Show Movie Handler - 200 OK LOCALIZED TITLE",
+			acceptLanguage: "pt, en;q=0.8",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().GetTitles([]int64{movie.ID}).Return(titles, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, "pt-BR", r.Header().Get("Content-Language"))
+				require.Contains(t, r.Header().Values("Vary"), "Accept-Language")
+				require.Equal(t, "O Poderoso Chefão", requireMovieTitle(t, r))
+			},
+		},
+		{
+			name:           "Test Show Movie Handler - 200 OK NO ACCEPTABLE TITLE",
+			acceptLanguage: "ja",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().GetTitles([]int64{movie.ID}).Return(titles, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Empty(t, r.Header().Get("Content-Language"))
+				require.Equal(t, original, requireMovieTitle(t, r))
+			},
+		},
+		{
+			name: "Test Show Movie Handler - 200 OK NO ACCEPT LANGUAGE",
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, original, requireMovieTitle(t, r))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, fmt.Sprintf("/v1/movies/%d", movie.ID))
+
+			mockMovies := test.app.models.Movies.(*mockdb.MockMovieQuerier)
+
+			stored := *movie
+			mockMovies.EXPECT().GetFields(movie.ID, nil).Return(&stored, nil)
+			tc.buildStubs(t, mockMovies)
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodGet, "/v1/movies/:id", test.app.showMovieHandler)
+
+			request := httptest.NewRequest(http.MethodGet, test.url, nil)
+			if tc.acceptLanguage != "" {
+				request.Header.Set("Accept-Language", tc.acceptLanguage)
+			}
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestSetMovieTitleHandler(t *testing.T) {
+	testCases := []struct {
+		name          string
+		language      string
+		body          any
+		buildStubs    func(t *testing.T, mockMovies *mockdb.MockMovieQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Test Set Movie Title Handler - 200 OK CANONICAL TAG",
+			language: "zh-hant-tw",
+			body:     map[string]any{"title": "教父"},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().
+					SetTitle(&data.MovieTitle{MovieID: 7, Language: "zh-Hant-TW", Title: "教父"}).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+			},
+		},
+		{
+			name:     "Test Set Movie Title Handler - 422 INVALID TAG",
+			language: "portuguese_BR",
+			body:     map[string]any{"title": "O Poderoso Chefão"},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "language")
+			},
+		},
+		{
+			name:     "Test Set Movie Title Handler - 404 UNKNOWN MOVIE",
+			language: "de",
+			body:     map[string]any{"title": "Der Pate"},
+			buildStubs: func(t *testing.T, mockMovies *mockdb.MockMovieQuerier) {
+				mockMovies.EXPECT().SetTitle(&data.MovieTitle{MovieID: 7, Language: "de", Title: "Der Pate"}).
+					Return(data.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, r.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newMovieTest(t, "/v1/movies/7/titles/"+tc.language)
+			tc.buildStubs(t, test.app.models.Movies.(*mockdb.MockMovieQuerier))
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodPut, "/v1/movies/:id/titles/:language", test.app.setMovieTitleHandler)
+
+			body, err := toReader(tc.body)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPut, test.url, body)
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func requireMovieTitle(t *testing.T, r *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	var envelope struct {
+		Movie data.Movie `json:"movie"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&envelope)
+	require.NoError(t, err)
+
+	return envelope.Movie.Title
+}
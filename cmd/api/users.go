@@ -60,7 +60,7 @@ func (app *application) registerUserHandle(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.models.Permissions.AddForUser(user.ID, "movies:read")
+	err = app.models.Permissions.AddForUser(user.ID, "movies:read", "lists:write")
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -88,7 +88,7 @@ func (app *application) registerUserHandle(w http.ResponseWriter, r *http.Reques
 		}
 	})
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"user": user}, nil)
+	err = app.writeResponse(w, r, http.StatusCreated, envelope{"user": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -144,7 +144,7 @@ func (app *application) activateUserHandle(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"user": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -100,7 +100,7 @@ func TestRegisterUserHandle(t *testing.T) {
 					})
 
 				mockPermissions.EXPECT().
-					AddForUser(gomock.Any(), "movies:read").
+					AddForUser(gomock.Any(), "movies:read", "lists:write").
 					Return(nil)
 
 				mockTokens.EXPECT().
@@ -246,7 +246,7 @@ func TestRegisterUserHandle(t *testing.T) {
 					})
 
 				mockPermissions.EXPECT().
-					AddForUser(gomock.Any(), "movies:read").
+					AddForUser(gomock.Any(), "movies:read", "lists:write").
 					Return(nil)
 
 				mockTokens.EXPECT().
@@ -280,7 +280,7 @@ func TestRegisterUserHandle(t *testing.T) {
 					})
 
 				mockPermissions.EXPECT().
-					AddForUser(gomock.Any(), "movies:read").
+					AddForUser(gomock.Any(), "movies:read", "lists:write").
 					Return(nil)
 
 				mockTokens.EXPECT().
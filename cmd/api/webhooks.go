@@ -0,0 +1,413 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/data"
+	"github.com/djudju12/greenlight/internal/validator"
+)
+
+const (
+	webhookEventHeader     = "X-Greenlight-Event"
+	webhookDeliveryHeader  = "X-Greenlight-Delivery"
+	webhookTimestampHeader = "X-Greenlight-Timestamp"
+	webhookSignatureHeader = "X-Greenlight-Signature"
+)
+
+// webhookResponseMaxBytes is how much of the response of a webhook is read
+// before the connection is closed. The body itself is never stored.
+const webhookResponseMaxBytes = 64 << 10
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+// createWebhookHandler registers a webhook. Without a secret one is generated,
+// and either way this is the only response the secret is sent in.
+func (app *application) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var input CreateWebhookRequest
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	webhook := &data.Webhook{
+		URL:    input.URL,
+		Events: input.Events,
+		Secret: input.Secret,
+	}
+
+	if webhook.Secret == "" {
+		webhook.Secret, err = generateWebhookSecret()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	v := validator.New()
+	if data.ValidateWebhook(v, webhook); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Webhooks.Insert(webhook)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/webhooks/%d", webhook.ID))
+
+	err = app.writeResponse(w, r, http.StatusCreated, envelope{"webhook": webhook, "secret": webhook.Secret}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := app.models.Webhooks.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"webhooks": webhooks}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	webhook, err := app.models.Webhooks.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"webhook": webhook}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Webhooks.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"message": "webhook successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listWebhookDeliveriesHandler is the delivery log of a webhook, newest first.
+func (app *application) listWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	// the log is always sorted by the newest
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         "-id",
+		SortSafelist: []string{"-id"},
+	}
+
+	if data.ValidateFilter(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, err = app.models.Webhooks.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	deliveries, metadata, err := app.models.Webhooks.GetDeliveries(id, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	if link := linkHeader(paginationLinks(r, metadata)); link != "" {
+		headers.Set("Link", link)
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"metadata": metadata, "deliveries": deliveries}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// redeliverWebhookHandler queues the payload of a delivery again, as a new
+// delivery that is sent right away.
+func (app *application) redeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhookID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	deliveryID, err := app.readNamedIDParam(r, "delivery_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	delivery := &data.WebhookDelivery{ID: deliveryID, WebhookID: webhookID}
+
+	err = app.models.Webhooks.Redeliver(delivery)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusAccepted, envelope{"delivery": delivery}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func generateWebhookSecret() (string, error) {
+	randomBytes := make([]byte, 32)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(randomBytes), nil
+}
+
+// signWebhookPayload signs the timestamp and the payload, joined by a dot, with
+// HMAC-SHA256. Signing the timestamp lets the receivers reject old deliveries
+// that are replayed to them.
+func signWebhookPayload(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		// a redirect is answered like any other response that is not a 2xx
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// deliverWebhooks is the worker that sends the queued deliveries. Any number
+// of instances can run it, as each delivery is claimed by one of them. A
+// delivery that fails is retried with exponential backoff until it runs out of
+// attempts.
+func (app *application) deliverWebhooks() {
+	cfg := app.config.webhooks
+	client := newWebhookClient(cfg.timeout)
+
+	for {
+		// the lease outlasts the attempt, so a delivery is only claimed again
+		// if the worker stopped before completing it
+		deliveries, err := app.models.Webhooks.ClaimDeliveries(cfg.batchSize, 2*cfg.timeout)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			time.Sleep(cfg.pollInterval)
+			continue
+		}
+
+		if len(deliveries) == 0 {
+			time.Sleep(cfg.pollInterval)
+			continue
+		}
+
+		var wg sync.WaitGroup
+		for _, delivery := range deliveries {
+			wg.Add(1)
+
+			go func(delivery *data.WebhookDelivery) {
+				defer wg.Done()
+
+				err := app.deliverWebhook(client, delivery)
+				if err != nil {
+					app.logger.PrintError(err, map[string]string{
+						"delivery_id": strconv.FormatInt(delivery.ID, 10),
+					})
+				}
+			}(delivery)
+		}
+
+		wg.Wait()
+	}
+}
+
+// deliverWebhook makes an attempt to send a claimed delivery and stores how it
+// went.
+func (app *application) deliverWebhook(client *http.Client, delivery *data.WebhookDelivery) error {
+	cfg := app.config.webhooks
+
+	if delivery.Payload == nil {
+		payload, err := app.movieWebhookPayload(delivery)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			delivery.LastStatusCode = nil
+			delivery.LastError = "the movie event was deleted before the delivery was sent"
+			delivery.Status = data.DeliveryFailed
+			return app.models.Webhooks.CompleteDelivery(delivery)
+		case err != nil:
+			return err
+		}
+
+		delivery.Payload = payload
+	}
+
+	status, err := sendWebhook(client, delivery, time.Now())
+	now := time.Now()
+
+	delivery.LastStatusCode = nil
+	delivery.LastError = ""
+	if status != 0 {
+		delivery.LastStatusCode = &status
+	}
+
+	switch {
+	case err != nil:
+		delivery.LastError = err.Error()
+	case status < 200 || status > 299:
+		delivery.LastError = fmt.Sprintf("unexpected response status %d", status)
+	}
+
+	switch {
+	case delivery.LastError == "":
+		delivery.Status = data.DeliverySucceeded
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= cfg.maxAttempts:
+		delivery.Status = data.DeliveryFailed
+	default:
+		next := now.Add(webhookBackoff(delivery.Attempts, cfg.backoff, cfg.maxBackoff))
+		delivery.Status = data.DeliveryPending
+		delivery.NextAttemptAt = &next
+	}
+
+	return app.models.Webhooks.CompleteDelivery(delivery)
+}
+
+// webhookBackoff is how long to wait after the given attempt failed: the base
+// delay, doubled for each attempt after the first, up to max.
+func webhookBackoff(attempts int, base, max time.Duration) time.Duration {
+	backoff := base
+	for i := 1; i < attempts && backoff < max; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, max)
+}
+
+// movieWebhookPayload builds the payload of a delivery of a movie event, with
+// the movie as the API sends it everywhere else. It's stored with the outcome
+// of the first attempt, so the retries send the same payload.
+func (app *application) movieWebhookPayload(delivery *data.WebhookDelivery) (json.RawMessage, error) {
+	event, err := app.models.MovieEvents.Get(delivery.MovieEventID)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(envelope{
+		"event":      delivery.Event,
+		"created_at": event.CreatedAt,
+		"data":       envelope{"movie": event.Movie},
+	})
+}
+
+// sendWebhook posts the payload of the delivery to its webhook and returns the
+// status code of the response.
+func sendWebhook(client *http.Client, delivery *data.WebhookDelivery, now time.Time) (int, error) {
+	timestamp := now.Unix()
+
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", mediaTypeJSON)
+	req.Header.Set("User-Agent", "Greenlight-Webhooks")
+	req.Header.Set(webhookEventHeader, delivery.Event)
+	req.Header.Set(webhookDeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(webhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhookSignatureHeader, signWebhookPayload(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+
+	// reading the body lets the connection be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, webhookResponseMaxBytes))
+
+	return resp.StatusCode, nil
+}
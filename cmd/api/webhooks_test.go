@@ -0,0 +1,513 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/data"
+	mockdb "github.com/djudju12/greenlight/internal/mocks"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newWebhookTest(t *testing.T, url string) (test, *mockdb.MockWebhookQuerier) {
+	test := newMovieTest(t, url)
+
+	mockWebhooks := mockdb.NewMockWebhookQuerier(gomock.NewController(t))
+	test.app.models.Webhooks = mockWebhooks
+
+	return test, mockWebhooks
+}
+
+func TestCreateWebhookHandler(t *testing.T) {
+	testCases := []struct {
+		name          string
+		body          any
+		buildStubs    func(t *testing.T, mockWebhooks *mockdb.MockWebhookQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test Create Webhook Handler - 201 CREATED GENERATED SECRET",
+			body: map[string]any{"url": "https://example.com/hooks", "events": []string{"movie.created", "user.activated"}},
+			buildStubs: func(t *testing.T, mockWebhooks *mockdb.MockWebhookQuerier) {
+				mockWebhooks.EXPECT().
+					Insert(gomock.Any()).
+					DoAndReturn(func(webhook *data.Webhook) error {
+						require.Len(t, webhook.Secret, 64)
+						webhook.ID = 7
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, r.Code)
+				require.Equal(t, "/v1/webhooks/7", r.Header().Get("Location"))
+
+				var envelope struct {
+					Webhook map[string]any `json:"webhook"`
+					Secret  string         `json:"secret"`
+				}
+				err := json.NewDecoder(r.Body).Decode(&envelope)
+				require.NoError(t, err)
+
+				require.Len(t, envelope.Secret, 64)
+				require.NotContains(t, envelope.Webhook, "secret")
+				require.Equal(t, "https://example.com/hooks", envelope.Webhook["url"])
+			},
+		},
+		{
+			name: "Test Create Webhook Handler - 201 CREATED GIVEN SECRET",
+			body: map[string]any{"url": "http://localhost:8080", "events": []string{"movie.deleted"}, "secret": "a-secret-of-some-length"},
+			buildStubs: func(t *testing.T, mockWebhooks *mockdb.MockWebhookQuerier) {
+				mockWebhooks.EXPECT().
+					Insert(gomock.Any()).
+					DoAndReturn(func(webhook *data.Webhook) error {
+						require.Equal(t, "a-secret-of-some-length", webhook.Secret)
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, r.Code)
+			},
+		},
+		{
+			name: "Test Create Webhook Handler - 422 INVALID WEBHOOK",
+			body: map[string]any{"url": "ftp://example.com", "events": []string{"movie.rated", "movie.rated"}, "secret": "short"},
+			buildStubs: func(t *testing.T, mockWebhooks *mockdb.MockWebhookQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+
+				errs := requireErrorMap(t, r)
+				require.Contains(t, errs, "url")
+				require.Contains(t, errs, "events")
+				require.Contains(t, errs, "secret")
+			},
+		},
+		{
+			name: "Test Create Webhook Handler - 400 UNKNOWN KEY",
+			body: map[string]any{"url": "https://example.com", "events": []string{"movie.created"}, "active": true},
+			buildStubs: func(t *testing.T, mockWebhooks *mockdb.MockWebhookQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, r.Code)
+			},
+		},
+		{
+			name: "Test Create Webhook Handler - 500 DB RETURN ERROR",
+			body: map[string]any{"url": "https://example.com", "events": []string{"movie.created"}},
+			buildStubs: func(t *testing.T, mockWebhooks *mockdb.MockWebhookQuerier) {
+				mockWebhooks.EXPECT().Insert(gomock.Any()).Return(errors.New("DB ERROR"))
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, r.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test, mockWebhooks := newWebhookTest(t, "/v1/webhooks")
+			tc.buildStubs(t, mockWebhooks)
+
+			body, err := toReader(tc.body)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, test.url, body)
+
+			// when
+			test.app.createWebhookHandler(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestListWebhookDeliveriesHandler(t *testing.T) {
+	status := http.StatusInternalServerError
+	deliveries := []*data.WebhookDelivery{
+		{ID: 2, WebhookID: 1, Event: "movie.created", Payload: json.RawMessage(`{"event":"movie.created"}`), Status: data.DeliverySucceeded},
+		{ID: 1, WebhookID: 1, Event: "movie.created", Payload: json.RawMessage(`{"event":"movie.created"}`), Status: data.DeliveryFailed, LastStatusCode: &status},
+	}
+
+	testCases := []struct {
+		name          string
+		url           string
+		buildStubs    func(t *testing.T, mockWebhooks *mockdb.MockWebhookQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test List Webhook Deliveries Handler - 200 OK",
+			url:  "/v1/webhooks/1/deliveries?page_size=2",
+			buildStubs: func(t *testing.T, mockWebhooks *mockdb.MockWebhookQuerier) {
+				mockWebhooks.EXPECT().Get(int64(1)).Return(&data.Webhook{ID: 1}, nil)
+				mockWebhooks.EXPECT().
+					GetDeliveries(int64(1), gomock.Any()).
+					DoAndReturn(func(webhookID int64, f data.Filters) ([]*data.WebhookDelivery, data.Metadata, error) {
+						require.Equal(t, 2, f.PageSize)
+						return deliveries, data.Metadata{CurrentPage: 1, PageSize: 2, FirstPage: 1, LastPage: 2, TotalRecords: 3}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Contains(t, r.Header().Get("Link"), `rel="next"`)
+
+				var envelope struct {
+					Deliveries []*data.WebhookDelivery `json:"deliveries"`
+				}
+				err := json.NewDecoder(r.Body).Decode(&envelope)
+				require.NoError(t, err)
+				require.Equal(t, deliveries, envelope.Deliveries)
+			},
+		},
+		{
+			name: "Test List Webhook Deliveries Handler - 404 WEBHOOK NOT FOUND",
+			url:  "/v1/webhooks/1/deliveries",
+			buildStubs: func(t *testing.T, mockWebhooks *mockdb.MockWebhookQuerier) {
+				mockWebhooks.EXPECT().Get(int64(1)).Return(nil, data.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, r.Code)
+			},
+		},
+		{
+			name: "Test List Webhook Deliveries Handler - 422 INVALID PAGE",
+			url:  "/v1/webhooks/1/deliveries?page=0",
+			buildStubs: func(t *testing.T, mockWebhooks *mockdb.MockWebhookQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, r.Code)
+				require.Contains(t, requireErrorMap(t, r), "page")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test, mockWebhooks := newWebhookTest(t, tc.url)
+			tc.buildStubs(t, mockWebhooks)
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodGet, "/v1/webhooks/:id/deliveries", test.app.listWebhookDeliveriesHandler)
+
+			request := httptest.NewRequest(http.MethodGet, test.url, nil)
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestRedeliverWebhookHandler(t *testing.T) {
+	testCases := []struct {
+		name          string
+		url           string
+		buildStubs    func(t *testing.T, mockWebhooks *mockdb.MockWebhookQuerier)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Test Redeliver Webhook Handler - 202 ACCEPTED",
+			url:  "/v1/webhooks/1/deliveries/5/redeliver",
+			buildStubs: func(t *testing.T, mockWebhooks *mockdb.MockWebhookQuerier) {
+				mockWebhooks.EXPECT().
+					Redeliver(&data.WebhookDelivery{ID: 5, WebhookID: 1}).
+					DoAndReturn(func(delivery *data.WebhookDelivery) error {
+						delivery.ID = 9
+						delivery.Status = data.DeliveryPending
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, r.Code)
+
+				var envelope struct {
+					Delivery data.WebhookDelivery `json:"delivery"`
+				}
+				err := json.NewDecoder(r.Body).Decode(&envelope)
+				require.NoError(t, err)
+				require.Equal(t, int64(9), envelope.Delivery.ID)
+				require.Equal(t, data.DeliveryPending, envelope.Delivery.Status)
+			},
+		},
+		{
+			name: "Test Redeliver Webhook Handler - 404 DELIVERY NOT FOUND",
+			url:  "/v1/webhooks/1/deliveries/5/redeliver",
+			buildStubs: func(t *testing.T, mockWebhooks *mockdb.MockWebhookQuerier) {
+				mockWebhooks.EXPECT().Redeliver(gomock.Any()).Return(data.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, r.Code)
+			},
+		},
+		{
+			name: "Test Redeliver Webhook Handler - 404 INVALID DELIVERY ID",
+			url:  "/v1/webhooks/1/deliveries/abc/redeliver",
+			buildStubs: func(t *testing.T, mockWebhooks *mockdb.MockWebhookQuerier) {
+				t.Log("no stubs for this test")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, r.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test, mockWebhooks := newWebhookTest(t, tc.url)
+			tc.buildStubs(t, mockWebhooks)
+
+			router := httprouter.New()
+			router.HandlerFunc(http.MethodPost, "/v1/webhooks/:id/deliveries/:delivery_id/redeliver", test.app.redeliverWebhookHandler)
+
+			request := httptest.NewRequest(http.MethodPost, test.url, nil)
+
+			// when
+			router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+			test.close()
+		})
+	}
+}
+
+func TestDeliverWebhook(t *testing.T) {
+	payload := json.RawMessage(`{"event":"movie.created","data":{"movie":{"id":1}}}`)
+	secret := "a-secret-of-some-length"
+
+	testCases := []struct {
+		name           string
+		receiverStatus int
+		attempts       int
+		closeReceiver  bool
+		checkDelivery  func(t *testing.T, delivery *data.WebhookDelivery)
+	}{
+		{
+			name:           "Test Deliver Webhook - SUCCEEDED",
+			receiverStatus: http.StatusNoContent,
+			attempts:       1,
+			checkDelivery: func(t *testing.T, delivery *data.WebhookDelivery) {
+				require.Equal(t, data.DeliverySucceeded, delivery.Status)
+				require.Equal(t, http.StatusNoContent, *delivery.LastStatusCode)
+				require.Empty(t, delivery.LastError)
+				require.NotNil(t, delivery.DeliveredAt)
+			},
+		},
+		{
+			name:           "Test Deliver Webhook - RETRIED WITH BACKOFF",
+			receiverStatus: http.StatusInternalServerError,
+			attempts:       3,
+			checkDelivery: func(t *testing.T, delivery *data.WebhookDelivery) {
+				require.Equal(t, data.DeliveryPending, delivery.Status)
+				require.Equal(t, http.StatusInternalServerError, *delivery.LastStatusCode)
+				require.NotEmpty(t, delivery.LastError)
+				require.WithinDuration(t, time.Now().Add(4*time.Second), *delivery.NextAttemptAt, time.Second)
+			},
+		},
+		{
+			name:           "Test Deliver Webhook - FAILED AFTER THE LAST ATTEMPT",
+			receiverStatus: http.StatusFound,
+			attempts:       5,
+			checkDelivery: func(t *testing.T, delivery *data.WebhookDelivery) {
+				require.Equal(t, data.DeliveryFailed, delivery.Status)
+				require.Equal(t, http.StatusFound, *delivery.LastStatusCode)
+			},
+		},
+		{
+			name:          "Test Deliver Webhook - RECEIVER UNREACHABLE",
+			closeReceiver: true,
+			attempts:      1,
+			checkDelivery: func(t *testing.T, delivery *data.WebhookDelivery) {
+				require.Equal(t, data.DeliveryPending, delivery.Status)
+				require.Nil(t, delivery.LastStatusCode)
+				require.NotEmpty(t, delivery.LastError)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				require.JSONEq(t, string(payload), string(body))
+
+				require.Equal(t, "movie.created", r.Header.Get(webhookEventHeader))
+				require.Equal(t, "3", r.Header.Get(webhookDeliveryHeader))
+
+				timestamp, err := strconv.ParseInt(r.Header.Get(webhookTimestampHeader), 10, 64)
+				require.NoError(t, err)
+				require.Equal(t, signWebhookPayload(secret, timestamp, body), r.Header.Get(webhookSignatureHeader))
+
+				w.Header().Set("Location", "/elsewhere")
+				w.WriteHeader(tc.receiverStatus)
+			}))
+
+			if tc.closeReceiver {
+				receiver.Close()
+			} else {
+				defer receiver.Close()
+			}
+
+			test, mockWebhooks := newWebhookTest(t, "")
+			test.app.config.webhooks.maxAttempts = 5
+			test.app.config.webhooks.backoff = time.Second
+			test.app.config.webhooks.maxBackoff = time.Minute
+
+			delivery := &data.WebhookDelivery{
+				ID:        3,
+				WebhookID: 1,
+				Event:     "movie.created",
+				Payload:   payload,
+				Status:    data.DeliveryPending,
+				Attempts:  tc.attempts,
+				URL:       receiver.URL,
+				Secret:    secret,
+			}
+
+			mockWebhooks.EXPECT().CompleteDelivery(delivery).Return(nil)
+
+			// when
+			err := test.app.deliverWebhook(newWebhookClient(time.Second), delivery)
+
+			// then
+			require.NoError(t, err)
+			tc.checkDelivery(t, delivery)
+			test.close()
+		})
+	}
+}
+
+func TestDeliverMovieWebhook(t *testing.T) {
+	createdAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	event := &data.MovieEvent{
+		ID:   9,
+		Type: "updated",
+		Movie: &data.Movie{
+			ID:      1,
+			Title:   "Casablanca",
+			Year:    1942,
+			Runtime: 102,
+			Genres:  []string{"drama"},
+			Version: 2,
+			Rating:  8.5,
+			Votes:   12,
+		},
+		CreatedAt: createdAt,
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(t *testing.T, mockEvents *mockdb.MockMovieEventQuerier)
+		sent          bool
+		checkDelivery func(t *testing.T, delivery *data.WebhookDelivery)
+	}{
+		{
+			name: "Test Deliver Movie Webhook - PAYLOAD BUILT FROM THE EVENT",
+			buildStubs: func(t *testing.T, mockEvents *mockdb.MockMovieEventQuerier) {
+				mockEvents.EXPECT().Get(event.ID).Return(event, nil)
+			},
+			sent: true,
+			checkDelivery: func(t *testing.T, delivery *data.WebhookDelivery) {
+				require.Equal(t, data.DeliverySucceeded, delivery.Status)
+				require.JSONEq(t, `{
+					"event": "movie.updated",
+					"created_at": "2024-03-01T12:00:00Z",
+					"data": {"movie": {"id": 1, "title": "Casablanca", "year": 1942, "runtime": "102 mins",
+						"genres": ["drama"], "version": 2, "rating": 8.5, "votes": 12}}
+				}`, string(delivery.Payload))
+			},
+		},
+		{
+			name: "Test Deliver Movie Webhook - FAILED WITHOUT THE EVENT",
+			buildStubs: func(t *testing.T, mockEvents *mockdb.MockMovieEventQuerier) {
+				mockEvents.EXPECT().Get(event.ID).Return(nil, data.ErrRecordNotFound)
+			},
+			checkDelivery: func(t *testing.T, delivery *data.WebhookDelivery) {
+				require.Equal(t, data.DeliveryFailed, delivery.Status)
+				require.NotEmpty(t, delivery.LastError)
+				require.Nil(t, delivery.Payload)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			sent := false
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sent = true
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer receiver.Close()
+
+			test, mockWebhooks := newWebhookTest(t, "")
+			test.app.config.webhooks.maxAttempts = 5
+
+			mockEvents := mockdb.NewMockMovieEventQuerier(gomock.NewController(t))
+			test.app.models.MovieEvents = mockEvents
+			tc.buildStubs(t, mockEvents)
+
+			delivery := &data.WebhookDelivery{
+				ID:           3,
+				WebhookID:    1,
+				Event:        "movie.updated",
+				Status:       data.DeliveryPending,
+				Attempts:     1,
+				MovieEventID: event.ID,
+				URL:          receiver.URL,
+				Secret:       "a-secret-of-some-length",
+			}
+
+			mockWebhooks.EXPECT().CompleteDelivery(delivery).Return(nil)
+
+			// when
+			err := test.app.deliverWebhook(newWebhookClient(time.Second), delivery)
+
+			// then
+			require.NoError(t, err)
+			require.Equal(t, tc.sent, sent)
+			tc.checkDelivery(t, delivery)
+			test.close()
+		})
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	testCases := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 1, expected: 30 * time.Second},
+		{attempts: 2, expected: time.Minute},
+		{attempts: 4, expected: 4 * time.Minute},
+		{attempts: 10, expected: time.Hour},
+		{attempts: 100, expected: time.Hour},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("Test Webhook Backoff - %d ATTEMPTS", tc.attempts), func(t *testing.T) {
+			require.Equal(t, tc.expected, webhookBackoff(tc.attempts, 30*time.Second, time.Hour))
+		})
+	}
+}
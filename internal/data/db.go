@@ -8,6 +8,15 @@ import (
 	"time"
 )
 
+// dbtx is implemented by both *sql.DB and *sql.Tx, so a model can run the same
+// queries inside or outside a transaction.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type DBCfg struct {
 	Dsn           string
 	MaxOpenConns  int
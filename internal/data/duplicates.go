@@ -0,0 +1,134 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FindDuplicates returns the movies that are likely the same as the movie: the
+// ones released the same year with the same title, ignoring case, punctuation,
+// spacing and a leading article. The movie itself is left out when it has an ID.
+// The normalized title is computed by the normalize_title function of the
+// database, and the movies_normalized_title_idx index is used to find them.
+func (m MovieModel) FindDuplicates(movie *Movie) ([]*Movie, error) {
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM movies
+	WHERE normalize_title(title) = normalize_title($1) AND year = $2 AND id <> $3
+	ORDER BY id`, strings.Join(movieColumns, ", "))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.conn().QueryContext(ctx, query, movie.Title, movie.Year, movie.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	duplicates := []*Movie{}
+	for rows.Next() {
+		var duplicate Movie
+
+		err = rows.Scan(movieScanDest(&duplicate, movieColumns)...)
+		if err != nil {
+			return nil, err
+		}
+
+		duplicates = append(duplicates, &duplicate)
+	}
+
+	return duplicates, rows.Err()
+}
+
+// Merge folds the source movie into the target one and deletes the source. The
+// reviews, credits, list entries, localized titles, external IDs and poster of
+// the source are moved to the target, unless the target already has its own:
+// a review by the same user, the same credit, an entry in the same list, a
+// title in the same language, an ID of the same provider or a poster. The
+// fields of the target are kept as they are. ErrRecordNotFound is returned when
+// either movie doesn't exist.
+func (m MovieModel) Merge(sourceID, targetID int64) error {
+	return m.InTx(func(q MovieQuerier) error {
+		conn := q.(MovieModel).conn()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		// both movies are locked in the order of their IDs, so that concurrent
+		// merges of the same movies can't deadlock
+		query := `
+		SELECT count(*) FROM (
+			SELECT id
+			FROM movies
+			WHERE id IN ($1, $2)
+			ORDER BY id
+			FOR UPDATE
+		) locked`
+
+		var found int
+		err := conn.QueryRowContext(ctx, query, sourceID, targetID).Scan(&found)
+		if err != nil {
+			return err
+		}
+
+		if found != 2 {
+			return ErrRecordNotFound
+		}
+
+		queries := []string{
+			`UPDATE reviews s SET movie_id = $2
+			WHERE s.movie_id = $1 AND NOT EXISTS (
+				SELECT 1 FROM reviews t WHERE t.movie_id = $2 AND t.user_id = s.user_id)`,
+
+			`UPDATE movie_credits s SET movie_id = $2
+			WHERE s.movie_id = $1 AND NOT EXISTS (
+				SELECT 1 FROM movie_credits t
+				WHERE t.movie_id = $2 AND t.person_id = s.person_id AND t.role = s.role)`,
+
+			`UPDATE list_items s SET movie_id = $2
+			WHERE s.movie_id = $1 AND NOT EXISTS (
+				SELECT 1 FROM list_items t WHERE t.movie_id = $2 AND t.list_id = s.list_id)`,
+
+			`UPDATE movie_titles s SET movie_id = $2
+			WHERE s.movie_id = $1 AND NOT EXISTS (
+				SELECT 1 FROM movie_titles t WHERE t.movie_id = $2 AND t.language = s.language)`,
+
+			`UPDATE movie_external_ids s SET movie_id = $2
+			WHERE s.movie_id = $1 AND NOT EXISTS (
+				SELECT 1 FROM movie_external_ids t WHERE t.movie_id = $2 AND t.provider = s.provider)`,
+
+			`UPDATE movie_posters SET movie_id = $2
+			WHERE movie_id = $1 AND NOT EXISTS (
+				SELECT 1 FROM movie_posters WHERE movie_id = $2)`,
+		}
+
+		for _, query := range queries {
+			_, err = conn.ExecContext(ctx, query, sourceID, targetID)
+			if err != nil {
+				return err
+			}
+		}
+
+		// the rating trigger only follows the changes of the scores, so the
+		// aggregates of the target are counted again with its new reviews
+		query = `
+		UPDATE movies
+		SET (votes, rating_total) = (
+			SELECT count(*), coalesce(sum(score), 0) FROM reviews WHERE movie_id = $1)
+		WHERE id = $1`
+
+		_, err = conn.ExecContext(ctx, query, targetID)
+		if err != nil {
+			return err
+		}
+
+		// whatever is left of the source goes away with it, and the lists it
+		// stays in close the gap it leaves
+		_, err = conn.ExecContext(ctx, `DELETE FROM movies WHERE id = $1`, sourceID)
+		return err
+	})
+}
@@ -0,0 +1,143 @@
+//go:build integration
+// +build integration
+
+package data
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/djudju12/greenlight/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMovieExternalIDs(t *testing.T) {
+	movie := randomMovie()
+	newMovie(t, &movie)
+
+	other := randomMovie()
+	newMovie(t, &other)
+
+	imdbID := fmt.Sprintf("tt%09d", util.RandomInt(1, 999999999))
+
+	err := testModels.Movies.SetExternalID(&ExternalID{MovieID: movie.ID, Provider: ProviderIMDb, ID: imdbID})
+	require.NoError(t, err)
+
+	// each ID belongs to a single movie
+	err = testModels.Movies.SetExternalID(&ExternalID{MovieID: other.ID, Provider: ProviderIMDb, ID: imdbID})
+	require.ErrorIs(t, err, ErrDuplicateExternalID)
+
+	err = testModels.Movies.SetExternalID(&ExternalID{MovieID: movie.ID + 1000000, Provider: ProviderTMDB, ID: "278"})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	movies, _, err := testModels.Movies.GetAll(
+		MovieFilters{SearchMode: SearchFullText, ExternalProvider: ProviderIMDb, ExternalID: imdbID},
+		Filters{Page: 1, PageSize: 5, Sort: "id", SortSafelist: []string{"id"}},
+	)
+	require.NoError(t, err)
+	require.Len(t, movies, 1)
+	require.Equal(t, movie.ID, movies[0].ID)
+
+	externalIDs, err := testModels.Movies.GetExternalIDs([]int64{movie.ID, other.ID})
+	require.NoError(t, err)
+	require.Len(t, externalIDs[movie.ID], 1)
+	require.Empty(t, externalIDs[other.ID])
+
+	err = testModels.Movies.DeleteExternalID(movie.ID, ProviderIMDb)
+	require.NoError(t, err)
+
+	err = testModels.Movies.DeleteExternalID(movie.ID, ProviderIMDb)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestFindDuplicates(t *testing.T) {
+	word := util.RandomString(12)
+
+	movie := Movie{Title: "The " + word + ": Part II", Year: 1974, Runtime: 202, Genres: []string{"drama"}}
+	newMovie(t, &movie)
+
+	// case, punctuation and the leading article don't matter, the year does
+	duplicates, err := testModels.Movies.FindDuplicates(&Movie{Title: word + " part ii", Year: 1974})
+	require.NoError(t, err)
+	require.Len(t, duplicates, 1)
+	require.Equal(t, movie.ID, duplicates[0].ID)
+
+	duplicates, err = testModels.Movies.FindDuplicates(&Movie{Title: word + " part ii", Year: 1975})
+	require.NoError(t, err)
+	require.Empty(t, duplicates)
+
+	// a movie is not a duplicate of itself
+	duplicates, err = testModels.Movies.FindDuplicates(&movie)
+	require.NoError(t, err)
+	require.Empty(t, duplicates)
+}
+
+func TestMergeMovies(t *testing.T) {
+	source := randomMovie()
+	newMovie(t, &source)
+
+	target := randomMovie()
+	newMovie(t, &target)
+
+	users := make([]User, 2)
+	for i := range users {
+		users[i] = randomUser()
+		err := testModels.Users.Insert(&users[i])
+		require.NoError(t, err)
+	}
+
+	// both users reviewed the source, and the first one the target too
+	for _, review := range []*Review{
+		{MovieID: source.ID, UserID: users[0].ID, Score: 2},
+		{MovieID: source.ID, UserID: users[1].ID, Score: 8},
+		{MovieID: target.ID, UserID: users[0].ID, Score: 6},
+	} {
+		err := testModels.Reviews.Insert(review)
+		require.NoError(t, err)
+	}
+
+	list := &List{UserID: users[0].ID, Name: util.RandomString(10), Visibility: ListUnlisted}
+	err := testModels.Lists.Insert(list)
+	require.NoError(t, err)
+
+	other := randomMovie()
+	newMovie(t, &other)
+
+	for _, movieID := range []int64{source.ID, target.ID, other.ID} {
+		err = testModels.Lists.AddItem(&ListItem{ListID: list.ID, MovieID: movieID})
+		require.NoError(t, err)
+	}
+
+	err = testModels.Movies.SetTitle(&MovieTitle{MovieID: source.ID, Language: "de", Title: util.RandomString(10)})
+	require.NoError(t, err)
+
+	tmdbID := fmt.Sprint(util.RandomInt(1, 999999999))
+	err = testModels.Movies.SetExternalID(&ExternalID{MovieID: source.ID, Provider: ProviderTMDB, ID: tmdbID})
+	require.NoError(t, err)
+
+	err = testModels.Movies.Merge(source.ID, target.ID)
+	require.NoError(t, err)
+
+	_, err = testModels.Movies.Get(source.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// the target keeps the review of the first user and gets the one of the
+	// second user
+	merged, err := testModels.Movies.Get(target.ID)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), merged.Votes)
+	require.Equal(t, 7.0, merged.Rating)
+
+	requireListOrder(t, list.ID, target.ID, other.ID)
+
+	titles, err := testModels.Movies.GetTitles([]int64{target.ID})
+	require.NoError(t, err)
+	require.Len(t, titles[target.ID], 1)
+
+	externalIDs, err := testModels.Movies.GetExternalIDs([]int64{target.ID})
+	require.NoError(t, err)
+	require.Equal(t, tmdbID, externalIDs[target.ID][0].ID)
+
+	err = testModels.Movies.Merge(source.ID, target.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
@@ -0,0 +1,167 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MovieEventsChannel is the channel the database notifies once a transaction
+// that logged movie events commits.
+const MovieEventsChannel = "movie_events"
+
+const (
+	MovieCreated = "created"
+	MovieUpdated = "updated"
+	MovieDeleted = "deleted"
+)
+
+// MovieEvent is a change to a movie. The events are logged by the database,
+// with the movie as it is after the change, or as it was when deleted. The
+// position orders the events by when they were committed, and is only set once
+// they are sequenced.
+type MovieEvent struct {
+	ID        int64     `json:"id"`
+	Position  int64     `json:"position"`
+	Type      string    `json:"type"`
+	Movie     *Movie    `json:"movie"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type MovieEventQuerier interface {
+	Get(id int64) (*MovieEvent, error)
+	Sequence() (int64, error)
+	GetAfter(position int64, limit int) ([]*MovieEvent, error)
+	DeleteOlderThan(t time.Time) (int64, error)
+}
+
+type MovieEventModel struct {
+	DB *sql.DB
+}
+
+var _ MovieEventQuerier = (*MovieEventModel)(nil)
+
+const movieEventColumns = `id, position, type, movie_id, version, title, year, runtime, genres, rating, votes, created_at`
+
+func scanMovieEvent(row interface{ Scan(dest ...any) error }) (*MovieEvent, error) {
+	event := MovieEvent{Movie: &Movie{}}
+
+	err := row.Scan(
+		&event.ID,
+		&event.Position,
+		&event.Type,
+		&event.Movie.ID,
+		&event.Movie.Version,
+		&event.Movie.Title,
+		&event.Movie.Year,
+		&event.Movie.Runtime,
+		pq.Array(&event.Movie.Genres),
+		&event.Movie.Rating,
+		&event.Movie.Votes,
+		&event.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+func (m MovieEventModel) Get(id int64) (*MovieEvent, error) {
+	query := `
+	SELECT ` + movieEventColumns + `
+	FROM movie_events
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	event, err := scanMovieEvent(m.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return event, nil
+}
+
+// Sequence positions the events committed since it last ran, and returns the
+// position of the last event.
+func (m MovieEventModel) Sequence() (int64, error) {
+	query := `SELECT sequence_movie_events()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var position int64
+	err := m.DB.QueryRowContext(ctx, query).Scan(&position)
+	if err != nil {
+		return 0, err
+	}
+
+	return position, nil
+}
+
+// GetAfter returns up to limit events sequenced after the given position, in
+// the order they were committed.
+func (m MovieEventModel) GetAfter(position int64, limit int) ([]*MovieEvent, error) {
+	query := `
+	SELECT ` + movieEventColumns + `
+	FROM movie_events
+	WHERE position > $1
+	ORDER BY position
+	LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, position, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	events := []*MovieEvent{}
+	for rows.Next() {
+		event, err := scanMovieEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// DeleteOlderThan deletes the events logged before t, which can no longer be
+// resumed from. The events of webhook deliveries that were never sent are
+// kept, as their payloads are still to be built from them.
+func (m MovieEventModel) DeleteOlderThan(t time.Time) (int64, error) {
+	query := `
+	DELETE FROM movie_events e
+	WHERE e.created_at < $1 AND NOT EXISTS (
+		SELECT 1
+		FROM webhook_deliveries d
+		WHERE d.movie_event_id = e.id AND d.payload IS NULL
+	)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, t)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,76 @@
+//go:build integration
+// +build integration
+
+package data
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMovieEvents(t *testing.T) {
+	movie := randomMovie()
+	newMovie(t, &movie)
+
+	movie.Title = util.RandomFullName()
+	err := testModels.Movies.Update(&movie)
+	require.NoError(t, err)
+
+	err = testModels.Movies.Delete(movie.ID)
+	require.NoError(t, err)
+
+	last, err := testModels.MovieEvents.Sequence()
+	require.NoError(t, err)
+
+	logged, err := testModels.MovieEvents.GetAfter(0, math.MaxInt32)
+	require.NoError(t, err)
+
+	var events []*MovieEvent
+	for _, event := range logged {
+		if event.Movie.ID == movie.ID {
+			events = append(events, event)
+		}
+	}
+
+	require.Len(t, events, 3)
+	require.Equal(t, MovieCreated, events[0].Type)
+	require.Equal(t, MovieUpdated, events[1].Type)
+	require.Equal(t, MovieDeleted, events[2].Type)
+
+	// the deleted event has the movie as it was when deleted
+	require.Equal(t, movie.Title, events[2].Movie.Title)
+	require.Equal(t, movie.Version, events[2].Movie.Version)
+
+	// the events are positioned in the order they were committed
+	require.Less(t, events[0].Position, events[1].Position)
+	require.Less(t, events[1].Position, events[2].Position)
+	require.Equal(t, last, logged[len(logged)-1].Position)
+
+	event, err := testModels.MovieEvents.Get(events[1].ID)
+	require.NoError(t, err)
+	require.Equal(t, events[1], event)
+
+	_, err = testModels.MovieEvents.Get(0)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	after, err := testModels.MovieEvents.GetAfter(events[0].Position, 1)
+	require.NoError(t, err)
+	require.Len(t, after, 1)
+	require.Equal(t, events[1], after[0])
+
+	// sequencing again positions nothing new
+	again, err := testModels.MovieEvents.Sequence()
+	require.NoError(t, err)
+	require.Equal(t, last, again)
+
+	after, err = testModels.MovieEvents.GetAfter(last, math.MaxInt32)
+	require.NoError(t, err)
+	require.Empty(t, after)
+
+	_, err = testModels.MovieEvents.DeleteOlderThan(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+}
@@ -0,0 +1,138 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/validator"
+	"github.com/lib/pq"
+)
+
+var (
+	SqlErrDupExternalID   = `pq: duplicate key value violates unique constraint "movie_external_ids_pkey"`
+	SqlErrExternalIDMovie = `pq: insert or update on table "movie_external_ids" violates foreign key constraint "movie_external_ids_movie_id_fkey"`
+)
+
+var ErrDuplicateExternalID = errors.New("duplicate external id")
+
+const (
+	ProviderIMDb = "imdb"
+	ProviderTMDB = "tmdb"
+)
+
+// ExternalIDRx holds the format of the IDs of each provider: IMDb tt-IDs, like
+// "tt0111161", and the numeric IDs of TMDB.
+var ExternalIDRx = map[string]*regexp.Regexp{
+	ProviderIMDb: regexp.MustCompile(`^tt[0-9]{7,10}$`),
+	ProviderTMDB: regexp.MustCompile(`^[1-9][0-9]{0,9}$`),
+}
+
+var ExternalProviders = []string{ProviderIMDb, ProviderTMDB}
+
+// ExternalID is the ID of a movie in another catalog. Each external ID belongs
+// to a single movie, and a movie has at most one ID of each provider.
+type ExternalID struct {
+	MovieID  int64  `json:"-"`
+	Provider string `json:"provider"`
+	ID       string `json:"id"`
+}
+
+func ValidateExternalID(v *validator.Validator, externalID *ExternalID) {
+	rx, ok := ExternalIDRx[externalID.Provider]
+	v.Check(ok, "provider", fmt.Sprintf("must be one of %v", ExternalProviders))
+
+	v.Check(externalID.ID != "", "id", "must be provided")
+	if ok {
+		v.Check(validator.Matches(externalID.ID, rx), "id", fmt.Sprintf("must be a valid %s ID", externalID.Provider))
+	}
+}
+
+// GetExternalIDs returns the external IDs of each of the movies, sorted by
+// provider. Movies without external IDs are not in the returned map.
+func (m MovieModel) GetExternalIDs(movieIDs []int64) (map[int64][]*ExternalID, error) {
+	query := `
+	SELECT movie_id, provider, external_id
+	FROM movie_external_ids
+	WHERE movie_id = ANY($1)
+	ORDER BY movie_id, provider`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.conn().QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	externalIDs := make(map[int64][]*ExternalID)
+	for rows.Next() {
+		var externalID ExternalID
+
+		err = rows.Scan(&externalID.MovieID, &externalID.Provider, &externalID.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		externalIDs[externalID.MovieID] = append(externalIDs[externalID.MovieID], &externalID)
+	}
+
+	return externalIDs, rows.Err()
+}
+
+// SetExternalID sets the ID of the movie for the provider, replacing the one it
+// had. ErrDuplicateExternalID is returned when another movie has the ID, and
+// ErrRecordNotFound when the movie doesn't exist.
+func (m MovieModel) SetExternalID(externalID *ExternalID) error {
+	query := `
+	INSERT INTO movie_external_ids (movie_id, provider, external_id)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (movie_id, provider) DO UPDATE
+	SET external_id = EXCLUDED.external_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := m.conn().ExecContext(ctx, query, externalID.MovieID, externalID.Provider, externalID.ID)
+	if err != nil {
+		switch {
+		case err.Error() == SqlErrDupExternalID:
+			return ErrDuplicateExternalID
+		case err.Error() == SqlErrExternalIDMovie:
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m MovieModel) DeleteExternalID(movieID int64, provider string) error {
+	query := `
+	DELETE FROM movie_external_ids
+	WHERE movie_id = $1 AND provider = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := m.conn().ExecContext(ctx, query, movieID, provider)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
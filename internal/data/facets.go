@@ -0,0 +1,130 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/validator"
+)
+
+const (
+	FacetGenres  = "genres"
+	FacetDecade  = "decade"
+	FacetRuntime = "runtime"
+)
+
+var FacetSafelist = []string{FacetGenres, FacetDecade, FacetRuntime}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Facets maps each requested facet to its counts, e.g.
+//
+//	{"decade": [{"value": "1980s", "count": 12}, {"value": "1990s", "count": 31}]}
+type Facets map[string][]FacetCount
+
+func ValidateFacets(v *validator.Validator, facets []string) {
+	for _, facet := range facets {
+		v.Check(validator.In(facet, FacetSafelist...), "facets", "invalid facet value")
+	}
+
+	v.Check(validator.Unique(facets), "facets", "must not contain duplicate values")
+}
+
+// Every facet query reads from the filtered CTE and returns the facet name, the
+// bucket value, the count and a position used to order the buckets.
+var facetQueries = map[string]string{
+	FacetGenres: `
+	SELECT 'genres', genre, count(*), row_number() OVER (ORDER BY count(*) DESC, genre)
+	FROM filtered, unnest(genres) AS genre
+	GROUP BY genre`,
+
+	FacetDecade: `
+	SELECT 'decade', (year / 10 * 10)::text || 's', count(*), year / 10 * 10
+	FROM filtered
+	GROUP BY year / 10 * 10`,
+
+	FacetRuntime: `
+	SELECT 'runtime', bucket.label, count(*), bucket.position
+	FROM filtered
+	CROSS JOIN LATERAL (
+		SELECT
+			CASE
+				WHEN runtime < 90 THEN '0-89'
+				WHEN runtime < 120 THEN '90-119'
+				WHEN runtime < 150 THEN '120-149'
+				ELSE '150+'
+			END AS label,
+			CASE
+				WHEN runtime < 90 THEN 1
+				WHEN runtime < 120 THEN 2
+				WHEN runtime < 150 THEN 3
+				ELSE 4
+			END AS position
+	) AS bucket
+	GROUP BY bucket.label, bucket.position`,
+}
+
+// GetFacets counts the movies matching mf per bucket of each requested facet,
+// in a single round trip to the database.
+func (m MovieModel) GetFacets(mf MovieFilters, facets []string) (Facets, error) {
+	result := Facets{}
+	if len(facets) == 0 {
+		return result, nil
+	}
+
+	parts := make([]string, 0, len(facets))
+	for _, facet := range facets {
+		part, ok := facetQueries[facet]
+		if !ok {
+			// unreachable, unless the facets weren't validated
+			panic("unsafe facet parameter: " + facet)
+		}
+
+		parts = append(parts, part)
+		result[facet] = []FacetCount{}
+	}
+
+	query := fmt.Sprintf(`
+	WITH filtered AS (
+		SELECT genres, year, runtime
+		FROM movies
+		WHERE %s
+	)
+	SELECT facet, value, count
+	FROM (%s) AS facets (facet, value, count, position)
+	ORDER BY facet, position`,
+		movieFilterConditions, strings.Join(parts, "\n\tUNION ALL"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.conn().QueryContext(ctx, query, mf.args()...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var facet string
+		var count FacetCount
+
+		err = rows.Scan(&facet, &count.Value, &count.Count)
+		if err != nil {
+			return nil, err
+		}
+
+		result[facet] = append(result[facet], count)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -35,6 +35,10 @@ type Filters struct {
 	PageSize     int
 	Sort         string
 	SortSafelist []string
+
+	// Fields selects the fields read for each record. When empty, every field
+	// is read.
+	Fields []string
 }
 
 var (
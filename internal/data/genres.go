@@ -0,0 +1,310 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/djudju12/greenlight/internal/validator"
+	"github.com/lib/pq"
+)
+
+var (
+	SqlErrDupGenreAlias = `pq: duplicate key value violates unique constraint "genre_aliases_pkey"`
+)
+
+var ErrDuplicateGenre = errors.New("duplicate genre")
+
+// Genre is a genre of the catalog. Movies are stored with the slug of their
+// genres, and any of the aliases of a genre can be used in its place when
+// writing or filtering movies. Slugs and aliases ignore case, spacing and
+// punctuation, see the genre_key function of the database.
+type Genre struct {
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	Aliases   []string  `json:"aliases"`
+	CreatedAt time.Time `json:"-"`
+}
+
+var (
+	maxBytesGenreName = 100
+	maxGenreAliases   = 20
+)
+
+// hasAlphanumeric reports whether s has a letter or a digit, without which a
+// genre name has an empty key.
+func hasAlphanumeric(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0
+}
+
+func ValidateGenre(v *validator.Validator, genre *Genre) {
+	v.Check(genre.Name != "", "name", "must be provided")
+	v.Check(genre.Name == "" || hasAlphanumeric(genre.Name), "name", "must contain a letter or a digit")
+	v.Check(len(genre.Name) <= maxBytesGenreName, "name", fmt.Sprintf("must not be more than %d bytes long", maxBytesGenreName))
+
+	v.Check(len(genre.Aliases) <= maxGenreAliases, "aliases", fmt.Sprintf("must not contain more than %d aliases", maxGenreAliases))
+	v.Check(validator.Unique(genre.Aliases), "aliases", "must not contain duplicate values")
+	for _, alias := range genre.Aliases {
+		v.Check(hasAlphanumeric(alias), "aliases", "must only contain values with a letter or a digit")
+		v.Check(len(alias) <= maxBytesGenreName, "aliases", fmt.Sprintf("must not contain values more than %d bytes long", maxBytesGenreName))
+	}
+}
+
+// NormalizeGenres replaces the genres of the movie with their slugs, as given
+// by GenreQuerier.Resolve. Genres missing from slugs are not in the catalog.
+func NormalizeGenres(v *validator.Validator, movie *Movie, slugs map[string]string) {
+	normalized := make([]string, 0, len(movie.Genres))
+
+	for _, genre := range movie.Genres {
+		slug, ok := slugs[genre]
+		v.Check(ok, "genres", fmt.Sprintf("unknown genre %q", genre))
+
+		normalized = append(normalized, slug)
+	}
+
+	if !v.Valid() {
+		return
+	}
+
+	v.Check(validator.Unique(normalized), "genres", "must not contain the same genre more than once")
+	movie.Genres = normalized
+}
+
+type GenreQuerier interface {
+	Insert(genre *Genre) error
+	GetAll() ([]*Genre, error)
+	Resolve(names []string) (map[string]string, error)
+	Merge(source, target string) (int64, error)
+}
+
+type GenreModel struct {
+	DB *sql.DB
+}
+
+var _ GenreQuerier = (*GenreModel)(nil)
+
+// Insert adds the genre with its aliases, filling its slug. ErrDuplicateGenre
+// is returned when the name or any of the aliases already stand for a genre.
+func (m GenreModel) Insert(genre *Genre) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// a no-op once the transaction is committed
+	defer tx.Rollback()
+
+	query := `
+	SELECT count(*)
+	FROM unnest(array_prepend($1, $2::text[])) AS name
+	WHERE resolve_genre(name) IS NOT NULL`
+
+	var taken int
+	err = tx.QueryRowContext(ctx, query, genre.Name, pq.Array(genre.Aliases)).Scan(&taken)
+	if err != nil {
+		return err
+	}
+
+	if taken > 0 {
+		return ErrDuplicateGenre
+	}
+
+	query = `
+	INSERT INTO genres (slug, name)
+	VALUES (genre_key($1), $1)
+	RETURNING slug, created_at`
+
+	err = tx.QueryRowContext(ctx, query, genre.Name).Scan(&genre.Slug, &genre.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	query = `
+	INSERT INTO genre_aliases (alias, slug)
+	SELECT DISTINCT genre_key(alias), $1
+	FROM unnest($2::text[]) AS alias
+	WHERE genre_key(alias) <> $1
+	RETURNING alias`
+
+	rows, err := tx.QueryContext(ctx, query, genre.Slug, pq.Array(genre.Aliases))
+	if err != nil {
+		return duplicateGenreError(err)
+	}
+
+	defer rows.Close()
+
+	genre.Aliases = []string{}
+	for rows.Next() {
+		var alias string
+		if err = rows.Scan(&alias); err != nil {
+			return err
+		}
+
+		genre.Aliases = append(genre.Aliases, alias)
+	}
+
+	if err = rows.Err(); err != nil {
+		return duplicateGenreError(err)
+	}
+
+	return tx.Commit()
+}
+
+// duplicateGenreError turns the errors of aliases inserted at the same time by
+// another genre into ErrDuplicateGenre.
+func duplicateGenreError(err error) error {
+	switch {
+	case err.Error() == SqlErrDupGenreAlias:
+		return ErrDuplicateGenre
+	default:
+		return err
+	}
+}
+
+// GetAll returns every genre of the catalog with its aliases, sorted by slug.
+func (m GenreModel) GetAll() ([]*Genre, error) {
+	query := `
+	SELECT g.slug, g.name, coalesce(array_agg(a.alias ORDER BY a.alias) FILTER (WHERE a.alias IS NOT NULL), '{}'), g.created_at
+	FROM genres g
+	LEFT JOIN genre_aliases a ON a.slug = g.slug
+	GROUP BY g.slug
+	ORDER BY g.slug`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	genres := []*Genre{}
+	for rows.Next() {
+		var genre Genre
+
+		err = rows.Scan(&genre.Slug, &genre.Name, pq.Array(&genre.Aliases), &genre.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		genres = append(genres, &genre)
+	}
+
+	return genres, rows.Err()
+}
+
+// Resolve returns the slug each of the names stands for. The names that are
+// not in the catalog are left out of the returned map.
+func (m GenreModel) Resolve(names []string) (map[string]string, error) {
+	query := `
+	SELECT name, slug
+	FROM unnest($1::text[]) AS name, resolve_genre(name) AS slug
+	WHERE slug IS NOT NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(names))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	slugs := make(map[string]string, len(names))
+	for rows.Next() {
+		var name, slug string
+
+		if err = rows.Scan(&name, &slug); err != nil {
+			return nil, err
+		}
+
+		slugs[name] = slug
+	}
+
+	return slugs, rows.Err()
+}
+
+// Merge folds the source genre into the target one. The source slug and its
+// aliases become aliases of the target, and the movies of the source are moved
+// to the target. The genres the movies are sent with change, so their versions
+// are bumped and each change is logged like any other update, with a revision,
+// an event and the webhooks. It returns how many movies were changed.
+func (m GenreModel) Merge(source, target string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// a no-op once the transaction is committed
+	defer tx.Rollback()
+
+	query := `
+	SELECT slug
+	FROM genres
+	WHERE slug IN ($1, $2)
+	FOR UPDATE`
+
+	rows, err := tx.QueryContext(ctx, query, source, target)
+	if err != nil {
+		return 0, err
+	}
+
+	found := 0
+	for rows.Next() {
+		found++
+	}
+
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if found != 2 {
+		return 0, ErrRecordNotFound
+	}
+
+	queries := []string{
+		`UPDATE genre_aliases SET slug = $2 WHERE slug = $1`,
+		`INSERT INTO genre_aliases (alias, slug) VALUES ($1, $2)`,
+		`DELETE FROM genres WHERE slug = $1`,
+	}
+
+	for _, query := range queries {
+		_, err = tx.ExecContext(ctx, query, source, target)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// with the source now an alias, normalizing the genres moves the movies
+	// to the target. The @> condition can use the movies_genres_idx index.
+	query = `
+	UPDATE movies
+	SET genres = normalize_genres(genres), version = version + 1
+	WHERE genres @> ARRAY[$1]`
+
+	result, err := tx.ExecContext(ctx, query, source)
+	if err != nil {
+		return 0, err
+	}
+
+	moved, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return moved, tx.Commit()
+}
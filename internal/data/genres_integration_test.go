@@ -0,0 +1,70 @@
+//go:build integration
+// +build integration
+
+package data
+
+import (
+	"testing"
+
+	"github.com/djudju12/greenlight/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenres(t *testing.T) {
+	source := &Genre{Name: "Space " + util.RandomString(10), Aliases: []string{"outer " + util.RandomString(10)}}
+	err := testModels.Genres.Insert(source)
+	require.NoError(t, err)
+	require.Len(t, source.Aliases, 1)
+
+	target := &Genre{Name: "Sci " + util.RandomString(10)}
+	err = testModels.Genres.Insert(target)
+	require.NoError(t, err)
+
+	// the key of the name is taken by the genre itself
+	err = testModels.Genres.Insert(&Genre{Name: source.Slug})
+	require.ErrorIs(t, err, ErrDuplicateGenre)
+
+	unknown := util.RandomString(12)
+	slugs, err := testModels.Genres.Resolve([]string{source.Name, source.Aliases[0], unknown})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{source.Name: source.Slug, source.Aliases[0]: source.Slug}, slugs)
+
+	movie := randomMovie()
+	movie.Genres = []string{source.Slug, target.Slug}
+	newMovie(t, &movie)
+
+	f := Filters{Page: 1, PageSize: 5, Sort: "id", SortSafelist: []string{"id"}}
+
+	// an alias filters the movies like the slug
+	movies, _, err := testModels.Movies.GetAll(MovieFilters{SearchMode: SearchFullText, Genres: []string{source.Aliases[0]}}, f)
+	require.NoError(t, err)
+	require.Len(t, movies, 1)
+	require.Equal(t, movie.ID, movies[0].ID)
+
+	moved, err := testModels.Genres.Merge(source.Slug, target.Slug)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), moved)
+
+	merged, err := testModels.Movies.Get(movie.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{target.Slug}, merged.Genres)
+	require.Equal(t, movie.Version+1, merged.Version)
+
+	// the move is logged like any other update
+	revisions, err := testModels.Movies.GetRevisions([]int64{movie.ID})
+	require.NoError(t, err)
+	require.Len(t, revisions[movie.ID], 1)
+	require.Equal(t, movie.Genres, revisions[movie.ID][0].Genres)
+
+	// the merged genre and its aliases now stand for the target
+	slugs, err = testModels.Genres.Resolve([]string{source.Name, source.Aliases[0]})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{source.Name: target.Slug, source.Aliases[0]: target.Slug}, slugs)
+
+	movies, _, err = testModels.Movies.GetAll(MovieFilters{SearchMode: SearchFullText, Genres: []string{source.Name}}, f)
+	require.NoError(t, err)
+	require.Len(t, movies, 1)
+
+	_, err = testModels.Genres.Merge(source.Slug, target.Slug)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
@@ -0,0 +1,173 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/validator"
+)
+
+var ErrIdempotencyKeyExists = errors.New("idempotency key already exists")
+
+// IdempotencyKey is the response stored for a request made with an
+// Idempotency-Key header. A nil Status means that the first request with the
+// key is still being processed.
+type IdempotencyKey struct {
+	Key         string
+	UserID      int64
+	RequestPath string
+	RequestHash []byte
+	Status      *int
+	Header      http.Header
+	Body        []byte
+	Expiry      time.Time
+}
+
+func ValidateIdempotencyKey(v *validator.Validator, key string) {
+	v.Check(key != "", "Idempotency-Key", "must not be empty")
+	v.Check(len(key) <= 255, "Idempotency-Key", "must not be more than 255 bytes long")
+}
+
+type IdempotencyQuerier interface {
+	Reserve(key *IdempotencyKey) error
+	Get(key string, userID int64, requestPath string) (*IdempotencyKey, error)
+	Complete(key *IdempotencyKey) error
+	Delete(key *IdempotencyKey) error
+	DeleteExpired() (int64, error)
+}
+
+type IdempotencyModel struct {
+	DB *sql.DB
+}
+
+var _ IdempotencyQuerier = (*IdempotencyModel)(nil)
+
+// Reserve claims the key for a new request until its expiry. An expired key,
+// including one whose request never completed, is claimed again as if it never
+// existed, while a live one makes Reserve return ErrIdempotencyKeyExists.
+func (m IdempotencyModel) Reserve(key *IdempotencyKey) error {
+	query := `
+	INSERT INTO idempotency_keys (key, user_id, request_path, request_hash, expiry)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (key, user_id, request_path) DO UPDATE
+	SET request_hash = EXCLUDED.request_hash, expiry = EXCLUDED.expiry,
+		status = NULL, headers = NULL, body = NULL, created_at = NOW()
+	WHERE idempotency_keys.expiry < NOW()
+	RETURNING key`
+
+	args := []any{key.Key, key.UserID, key.RequestPath, key.RequestHash, key.Expiry}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&key.Key)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrIdempotencyKeyExists
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m IdempotencyModel) Get(key string, userID int64, requestPath string) (*IdempotencyKey, error) {
+	query := `
+	SELECT key, user_id, request_path, request_hash, status, headers, body, expiry
+	FROM idempotency_keys
+	WHERE key = $1 AND user_id = $2 AND request_path = $3 AND expiry >= NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var (
+		idempotencyKey IdempotencyKey
+		header         []byte
+	)
+
+	err := m.DB.QueryRowContext(ctx, query, key, userID, requestPath).Scan(
+		&idempotencyKey.Key,
+		&idempotencyKey.UserID,
+		&idempotencyKey.RequestPath,
+		&idempotencyKey.RequestHash,
+		&idempotencyKey.Status,
+		&header,
+		&idempotencyKey.Body,
+		&idempotencyKey.Expiry,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if header != nil {
+		err = json.Unmarshal(header, &idempotencyKey.Header)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &idempotencyKey, nil
+}
+
+// Complete stores the response of the request that reserved the key, and keeps
+// it until the key's new expiry.
+func (m IdempotencyModel) Complete(key *IdempotencyKey) error {
+	query := `
+	UPDATE idempotency_keys
+	SET status = $1, headers = $2, body = $3, expiry = $4
+	WHERE key = $5 AND user_id = $6 AND request_path = $7`
+
+	header, err := json.Marshal(key.Header)
+	if err != nil {
+		return err
+	}
+
+	args := []any{key.Status, header, key.Body, key.Expiry, key.Key, key.UserID, key.RequestPath}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = m.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// Delete releases a key, so the request can be retried with it.
+func (m IdempotencyModel) Delete(key *IdempotencyKey) error {
+	query := `
+	DELETE FROM idempotency_keys
+	WHERE key = $1 AND user_id = $2 AND request_path = $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, key.Key, key.UserID, key.RequestPath)
+	return err
+}
+
+func (m IdempotencyModel) DeleteExpired() (int64, error) {
+	query := `
+	DELETE FROM idempotency_keys
+	WHERE expiry < NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
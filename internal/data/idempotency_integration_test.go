@@ -0,0 +1,88 @@
+//go:build integration
+// +build integration
+
+package data
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	key := &IdempotencyKey{
+		Key:         util.RandomString(20),
+		UserID:      util.RandomInt(1, 1000),
+		RequestPath: "/v1/movies",
+		RequestHash: []byte(util.RandomString(32)),
+		Expiry:      time.Now().Add(time.Hour),
+	}
+
+	err := testModels.Idempotency.Reserve(key)
+	require.NoError(t, err)
+
+	err = testModels.Idempotency.Reserve(key)
+	require.ErrorIs(t, err, ErrIdempotencyKeyExists)
+
+	stored, err := testModels.Idempotency.Get(key.Key, key.UserID, key.RequestPath)
+	require.NoError(t, err)
+	require.Nil(t, stored.Status)
+	require.Equal(t, key.RequestHash, stored.RequestHash)
+
+	status := http.StatusCreated
+	key.Status = &status
+	key.Header = http.Header{"Location": {"/v1/movies/1"}}
+	key.Body = []byte(`{"movie": {}}`)
+	key.Expiry = time.Now().Add(24 * time.Hour)
+
+	err = testModels.Idempotency.Complete(key)
+	require.NoError(t, err)
+
+	stored, err = testModels.Idempotency.Get(key.Key, key.UserID, key.RequestPath)
+	require.NoError(t, err)
+	require.Equal(t, status, *stored.Status)
+	require.WithinDuration(t, key.Expiry, stored.Expiry, time.Second)
+	require.Equal(t, key.Header, stored.Header)
+	require.Equal(t, key.Body, stored.Body)
+
+	err = testModels.Idempotency.Delete(key)
+	require.NoError(t, err)
+
+	_, err = testModels.Idempotency.Get(key.Key, key.UserID, key.RequestPath)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestIdempotencyKeysExpired(t *testing.T) {
+	key := &IdempotencyKey{
+		Key:         util.RandomString(20),
+		UserID:      util.RandomInt(1, 1000),
+		RequestPath: "/v1/users",
+		RequestHash: []byte(util.RandomString(32)),
+		Expiry:      time.Now().Add(-time.Hour),
+	}
+
+	err := testModels.Idempotency.Reserve(key)
+	require.NoError(t, err)
+
+	_, err = testModels.Idempotency.Get(key.Key, key.UserID, key.RequestPath)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// an expired key can be reserved again
+	key.Expiry = time.Now().Add(time.Hour)
+	err = testModels.Idempotency.Reserve(key)
+	require.NoError(t, err)
+
+	key.Expiry = time.Now().Add(-time.Hour)
+	err = testModels.Idempotency.Delete(key)
+	require.NoError(t, err)
+
+	err = testModels.Idempotency.Reserve(key)
+	require.NoError(t, err)
+
+	deleted, err := testModels.Idempotency.DeleteExpired()
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, deleted, int64(1))
+}
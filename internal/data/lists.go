@@ -0,0 +1,566 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/validator"
+	"github.com/lib/pq"
+)
+
+const (
+	// ListPrivate lists are only seen by their owner.
+	ListPrivate = "private"
+	// ListUnlisted lists are seen by anyone who knows their share token.
+	ListUnlisted = "unlisted"
+	// ListPublic lists are also listed with the lists of their owner.
+	ListPublic = "public"
+)
+
+var ListVisibilities = []string{ListPrivate, ListUnlisted, ListPublic}
+
+var (
+	SqlErrDupListItem     = `pq: duplicate key value violates unique constraint "list_items_pkey"`
+	SqlErrListItemMovieFK = `pq: insert or update on table "list_items" violates foreign key constraint "list_items_movie_id_fkey"`
+)
+
+var (
+	ErrDuplicateListItem = errors.New("duplicate list item")
+	ErrInvalidListOrder  = errors.New("invalid list order")
+)
+
+// List is a named and ordered collection of movies of an user, like a
+// watchlist. Only unlisted lists have a share token.
+type List struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"user_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Visibility  string    `json:"visibility"`
+	ShareToken  string    `json:"share_token,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	Version     int32     `json:"version"`
+}
+
+var (
+	maxBytesListName        = 200
+	maxBytesListDescription = 2000
+)
+
+func ValidateList(v *validator.Validator, list *List) {
+	v.Check(list.Name != "", "name", "must be provided")
+	v.Check(len(list.Name) <= maxBytesListName, "name", fmt.Sprintf("must not be more than %d bytes long", maxBytesListName))
+	v.Check(len(list.Description) <= maxBytesListDescription, "description", fmt.Sprintf("must not be more than %d bytes long", maxBytesListDescription))
+	v.Check(validator.In(list.Visibility, ListVisibilities...), "visibility", fmt.Sprintf("must be one of %v", ListVisibilities))
+}
+
+// VisibleTo reports whether the user can see the list, with the share token
+// they sent, if any.
+func (l *List) VisibleTo(user *User, shareToken string) bool {
+	switch {
+	case l.UserID == user.ID, l.Visibility == ListPublic:
+		return true
+	case l.Visibility == ListUnlisted:
+		return l.ShareToken != "" && subtle.ConstantTimeCompare([]byte(l.ShareToken), []byte(shareToken)) == 1
+	default:
+		return false
+	}
+}
+
+// setShareToken gives the list a share token when it becomes unlisted, and
+// removes it otherwise. An unlisted list keeps its token, so the links already
+// shared keep working.
+func (l *List) setShareToken() error {
+	if l.Visibility != ListUnlisted {
+		l.ShareToken = ""
+		return nil
+	}
+
+	if l.ShareToken != "" {
+		return nil
+	}
+
+	randomBytes := make([]byte, 16)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return err
+	}
+
+	l.ShareToken = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+	return nil
+}
+
+// ListItem is a movie in a list. The positions of the items of a list start
+// at 1 and have no gaps.
+type ListItem struct {
+	ListID    int64            `json:"-"`
+	MovieID   int64            `json:"movie_id"`
+	Movie     *MovieSuggestion `json:"movie,omitempty"`
+	Position  int              `json:"position"`
+	Watched   bool             `json:"watched"`
+	WatchedAt *time.Time       `json:"watched_at,omitempty"`
+	AddedAt   time.Time        `json:"added_at"`
+}
+
+type ListQuerier interface {
+	Insert(list *List) error
+	Get(id int64) (*List, error)
+	GetAllForUser(userID int64, visibilities []string) ([]*List, error)
+	Update(list *List) error
+	Delete(id int64) error
+	GetItems(listID int64) ([]*ListItem, error)
+	GetItem(listID, movieID int64) (*ListItem, error)
+	AddItem(item *ListItem) error
+	UpdateItem(item *ListItem) error
+	RemoveItem(listID, movieID int64) error
+	Reorder(listID int64, movieIDs []int64) error
+}
+
+type ListModel struct {
+	DB *sql.DB
+}
+
+var _ ListQuerier = (*ListModel)(nil)
+
+func (m ListModel) Insert(list *List) error {
+	err := list.setShareToken()
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO lists (user_id, name, description, visibility, share_token)
+	VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+	RETURNING id, created_at, version`
+
+	args := []any{list.UserID, list.Name, list.Description, list.Visibility, list.ShareToken}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&list.ID, &list.CreatedAt, &list.Version)
+}
+
+func (m ListModel) Get(id int64) (*List, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	SELECT id, user_id, name, description, visibility, COALESCE(share_token, ''), created_at, version
+	FROM lists
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var list List
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&list.ID,
+		&list.UserID,
+		&list.Name,
+		&list.Description,
+		&list.Visibility,
+		&list.ShareToken,
+		&list.CreatedAt,
+		&list.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &list, nil
+}
+
+// GetAllForUser returns the lists of the user with one of the visibilities.
+func (m ListModel) GetAllForUser(userID int64, visibilities []string) ([]*List, error) {
+	query := `
+	SELECT id, user_id, name, description, visibility, COALESCE(share_token, ''), created_at, version
+	FROM lists
+	WHERE user_id = $1 AND visibility = ANY($2)
+	ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, pq.Array(visibilities))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	lists := []*List{}
+	for rows.Next() {
+		var list List
+
+		err = rows.Scan(
+			&list.ID,
+			&list.UserID,
+			&list.Name,
+			&list.Description,
+			&list.Visibility,
+			&list.ShareToken,
+			&list.CreatedAt,
+			&list.Version,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		lists = append(lists, &list)
+	}
+
+	return lists, rows.Err()
+}
+
+func (m ListModel) Update(list *List) error {
+	err := list.setShareToken()
+	if err != nil {
+		return err
+	}
+
+	query := `
+	UPDATE lists
+	SET name = $1, description = $2, visibility = $3, share_token = NULLIF($4, ''), version = version + 1
+	WHERE id = $5 AND version = $6
+	RETURNING version`
+
+	args := []any{list.Name, list.Description, list.Visibility, list.ShareToken, list.ID, list.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = m.DB.QueryRowContext(ctx, query, args...).Scan(&list.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the list along with its items.
+func (m ListModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+	DELETE FROM lists
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+const listItemColumns = `
+	i.list_id, i.movie_id, m.title, m.year, i.position, i.watched, i.watched_at, i.added_at`
+
+func scanListItem(row interface{ Scan(...any) error }) (*ListItem, error) {
+	item := ListItem{Movie: &MovieSuggestion{}}
+
+	err := row.Scan(
+		&item.ListID,
+		&item.MovieID,
+		&item.Movie.Title,
+		&item.Movie.Year,
+		&item.Position,
+		&item.Watched,
+		&item.WatchedAt,
+		&item.AddedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	item.Movie.ID = item.MovieID
+	return &item, nil
+}
+
+// GetItems returns the items of the list, in order.
+func (m ListModel) GetItems(listID int64) ([]*ListItem, error) {
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM list_items i
+	JOIN movies m ON m.id = i.movie_id
+	WHERE i.list_id = $1
+	ORDER BY i.position`, listItemColumns)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	items := []*ListItem{}
+	for rows.Next() {
+		item, err := scanListItem(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func (m ListModel) GetItem(listID, movieID int64) (*ListItem, error) {
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM list_items i
+	JOIN movies m ON m.id = i.movie_id
+	WHERE i.list_id = $1 AND i.movie_id = $2`, listItemColumns)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	item, err := scanListItem(m.DB.QueryRowContext(ctx, query, listID, movieID))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return item, nil
+}
+
+// withListLock runs fn in a transaction holding the lock of the list, so the
+// changes to the positions of its items are made one at a time.
+func (m ListModel) withListLock(listID int64, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// a no-op once the transaction is committed
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM lists WHERE id = $1 FOR UPDATE`, listID).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	err = fn(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// countItems returns how many items the list has.
+func countItems(ctx context.Context, tx *sql.Tx, listID int64) (int, error) {
+	var count int
+	err := tx.QueryRowContext(ctx, `SELECT count(*) FROM list_items WHERE list_id = $1`, listID).Scan(&count)
+	return count, err
+}
+
+// AddItem inserts the movie in the list at the position of the item, shifting
+// the items after it. Without a position, or with one past the end, the movie
+// is added at the end. ErrUnknownMovie is returned when the movie doesn't
+// exist, and ErrDuplicateListItem when it's already in the list.
+func (m ListModel) AddItem(item *ListItem) error {
+	return m.withListLock(item.ListID, func(ctx context.Context, tx *sql.Tx) error {
+		count, err := countItems(ctx, tx, item.ListID)
+		if err != nil {
+			return err
+		}
+
+		if item.Position < 1 || item.Position > count+1 {
+			item.Position = count + 1
+		}
+
+		query := `
+		UPDATE list_items
+		SET position = position + 1
+		WHERE list_id = $1 AND position >= $2`
+
+		_, err = tx.ExecContext(ctx, query, item.ListID, item.Position)
+		if err != nil {
+			return err
+		}
+
+		query = `
+		INSERT INTO list_items (list_id, movie_id, position, watched, watched_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING added_at`
+
+		args := []any{item.ListID, item.MovieID, item.Position, item.Watched, item.WatchedAt}
+
+		err = tx.QueryRowContext(ctx, query, args...).Scan(&item.AddedAt)
+		if err != nil {
+			switch {
+			case err.Error() == SqlErrDupListItem:
+				return ErrDuplicateListItem
+			case err.Error() == SqlErrListItemMovieFK:
+				return ErrUnknownMovie
+			default:
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// UpdateItem stores the watched flag of the item and moves it to its position,
+// shifting the items in between. A position past the end moves it to the end.
+func (m ListModel) UpdateItem(item *ListItem) error {
+	return m.withListLock(item.ListID, func(ctx context.Context, tx *sql.Tx) error {
+		var current int
+		query := `SELECT position FROM list_items WHERE list_id = $1 AND movie_id = $2`
+
+		err := tx.QueryRowContext(ctx, query, item.ListID, item.MovieID).Scan(&current)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrRecordNotFound
+			default:
+				return err
+			}
+		}
+
+		count, err := countItems(ctx, tx, item.ListID)
+		if err != nil {
+			return err
+		}
+
+		item.Position = max(1, min(item.Position, count))
+
+		// the items between both positions move one place towards the old one
+		query = `
+		UPDATE list_items
+		SET position = position + sign($3::int - $2::int)::int
+		WHERE list_id = $1 AND position BETWEEN least($2, $3) AND greatest($2, $3)`
+
+		_, err = tx.ExecContext(ctx, query, item.ListID, item.Position, current)
+		if err != nil {
+			return err
+		}
+
+		query = `
+		UPDATE list_items
+		SET position = $3, watched = $4, watched_at = $5
+		WHERE list_id = $1 AND movie_id = $2`
+
+		args := []any{item.ListID, item.MovieID, item.Position, item.Watched, item.WatchedAt}
+
+		_, err = tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
+
+// RemoveItem removes the movie from the list. The items after it are moved
+// back by the list_items_close_gap trigger.
+func (m ListModel) RemoveItem(listID, movieID int64) error {
+	query := `
+	DELETE FROM list_items
+	WHERE list_id = $1 AND movie_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, listID, movieID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Reorder sorts the items of the list in the order of movieIDs, which must hold
+// every movie of the list exactly once. ErrInvalidListOrder is returned when it
+// doesn't.
+func (m ListModel) Reorder(listID int64, movieIDs []int64) error {
+	return m.withListLock(listID, func(ctx context.Context, tx *sql.Tx) error {
+		query := `
+		UPDATE list_items i
+		SET position = o.position
+		FROM unnest($2::bigint[]) WITH ORDINALITY AS o (movie_id, position)
+		WHERE i.list_id = $1 AND i.movie_id = o.movie_id`
+
+		result, err := tx.ExecContext(ctx, query, listID, pq.Array(movieIDs))
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		count, err := countItems(ctx, tx, listID)
+		if err != nil {
+			return err
+		}
+
+		// with a movie missing or given twice, fewer items than there are are
+		// updated, or the positions go past the end
+		if int(rowsAffected) != count || len(movieIDs) != count {
+			return ErrInvalidListOrder
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,121 @@
+//go:build integration
+// +build integration
+
+package data
+
+import (
+	"testing"
+
+	"github.com/djudju12/greenlight/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListItems(t *testing.T) {
+	user := randomUser()
+	err := testModels.Users.Insert(&user)
+	require.NoError(t, err)
+
+	list := &List{UserID: user.ID, Name: util.RandomString(10), Visibility: ListUnlisted}
+	err = testModels.Lists.Insert(list)
+	require.NoError(t, err)
+
+	movies := make([]Movie, 4)
+	for i := range movies {
+		movies[i] = randomMovie()
+		newMovie(t, &movies[i])
+	}
+
+	// 0 and 1 are appended, 2 goes first and 3 in the middle
+	for _, item := range []*ListItem{
+		{ListID: list.ID, MovieID: movies[0].ID},
+		{ListID: list.ID, MovieID: movies[1].ID},
+		{ListID: list.ID, MovieID: movies[2].ID, Position: 1},
+		{ListID: list.ID, MovieID: movies[3].ID, Position: 3},
+	} {
+		err = testModels.Lists.AddItem(item)
+		require.NoError(t, err)
+	}
+
+	requireListOrder(t, list.ID, movies[2].ID, movies[0].ID, movies[3].ID, movies[1].ID)
+
+	err = testModels.Lists.AddItem(&ListItem{ListID: list.ID, MovieID: movies[0].ID})
+	require.ErrorIs(t, err, ErrDuplicateListItem)
+
+	item, err := testModels.Lists.GetItem(list.ID, movies[2].ID)
+	require.NoError(t, err)
+
+	item.Position = 100
+	item.Watched = true
+	err = testModels.Lists.UpdateItem(item)
+	require.NoError(t, err)
+	require.Equal(t, 4, item.Position)
+
+	requireListOrder(t, list.ID, movies[0].ID, movies[3].ID, movies[1].ID, movies[2].ID)
+
+	err = testModels.Lists.Reorder(list.ID, []int64{movies[1].ID, movies[0].ID})
+	require.ErrorIs(t, err, ErrInvalidListOrder)
+
+	err = testModels.Lists.Reorder(list.ID, []int64{movies[1].ID, movies[0].ID, movies[2].ID, movies[3].ID})
+	require.NoError(t, err)
+
+	requireListOrder(t, list.ID, movies[1].ID, movies[0].ID, movies[2].ID, movies[3].ID)
+
+	err = testModels.Lists.RemoveItem(list.ID, movies[1].ID)
+	require.NoError(t, err)
+
+	// deleting a movie removes it from the list without leaving a gap
+	err = testModels.Movies.Delete(movies[2].ID)
+	require.NoError(t, err)
+
+	requireListOrder(t, list.ID, movies[0].ID, movies[3].ID)
+
+	err = testModels.Lists.Delete(list.ID)
+	require.NoError(t, err)
+
+	_, err = testModels.Lists.GetItem(list.ID, movies[0].ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestListShareToken(t *testing.T) {
+	user := randomUser()
+	err := testModels.Users.Insert(&user)
+	require.NoError(t, err)
+
+	list := &List{UserID: user.ID, Name: util.RandomString(10), Visibility: ListUnlisted}
+	err = testModels.Lists.Insert(list)
+	require.NoError(t, err)
+	require.NotEmpty(t, list.ShareToken)
+
+	got, err := testModels.Lists.Get(list.ID)
+	require.NoError(t, err)
+	require.Equal(t, list.ShareToken, got.ShareToken)
+	require.True(t, got.VisibleTo(AnonymousUser, list.ShareToken))
+	require.False(t, got.VisibleTo(AnonymousUser, ""))
+
+	// the token is kept while the list stays unlisted
+	got.Name = util.RandomString(10)
+	err = testModels.Lists.Update(got)
+	require.NoError(t, err)
+	require.Equal(t, list.ShareToken, got.ShareToken)
+
+	got.Visibility = ListPublic
+	err = testModels.Lists.Update(got)
+	require.NoError(t, err)
+
+	got, err = testModels.Lists.Get(list.ID)
+	require.NoError(t, err)
+	require.Empty(t, got.ShareToken)
+}
+
+func requireListOrder(t *testing.T, listID int64, movieIDs ...int64) {
+	t.Helper()
+
+	items, err := testModels.Lists.GetItems(listID)
+	require.NoError(t, err)
+	require.Len(t, items, len(movieIDs))
+
+	for i, item := range items {
+		require.Equal(t, movieIDs[i], item.MovieID)
+		require.Equal(t, i+1, item.Position)
+	}
+}
@@ -15,6 +15,13 @@ type Models struct {
 	Users       UserQuerier
 	Tokens      TokenQuerier
 	Permissions PermissionQuerier
+	Idempotency IdempotencyQuerier
+	MovieEvents MovieEventQuerier
+	Webhooks    WebhookQuerier
+	People      PersonQuerier
+	Reviews     ReviewQuerier
+	Lists       ListQuerier
+	Genres      GenreQuerier
 }
 
 func NewModels(db *sql.DB) *Models {
@@ -23,5 +30,12 @@ func NewModels(db *sql.DB) *Models {
 		Users:       UserModel{DB: db},
 		Tokens:      TokenModel{DB: db},
 		Permissions: PermissionModel{DB: db},
+		Idempotency: IdempotencyModel{DB: db},
+		MovieEvents: MovieEventModel{DB: db},
+		Webhooks:    WebhookModel{DB: db},
+		People:      PersonModel{DB: db},
+		Reviews:     ReviewModel{DB: db},
+		Lists:       ListModel{DB: db},
+		Genres:      GenreModel{DB: db},
 	}
 }
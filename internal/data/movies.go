@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/djudju12/greenlight/internal/validator"
@@ -18,7 +20,21 @@ type Movie struct {
 	Year      int32     `json:"year,omitempty"`
 	Runtime   Runtime   `json:"runtime,omitempty"`
 	Genres    []string  `json:"genres,omitempty"`
-	Version   int32     `json:"-"`
+
+	// Version is sent with the movie, in the responses as well as in the
+	// webhook and event payloads, so that the clients can send it back with
+	// their changes, which only apply while the movie is still at it.
+	Version int32 `json:"version"`
+
+	// Rating is the average score of the reviews of the movie, and Votes how
+	// many reviews it has. Both are kept by the database, see reviews.go.
+	Rating float64 `json:"rating"`
+	Votes  int32   `json:"votes"`
+
+	// Score is how well the movie matched the title search. It is only
+	// filled by GetAll, and only when a title was searched for. GetSimilar and
+	// GetRecommendations fill it with how similar or recommended it is.
+	Score float64 `json:"score,omitempty"`
 }
 
 var maxBytesTitle = 500
@@ -41,16 +57,339 @@ func ValidateMovie(v *validator.Validator, movie *Movie) {
 	v.Check(validator.Unique(movie.Genres), "genres", "must not contain duplicate values")
 }
 
+// MovieFieldSafelist holds the fields of a movie that can be selected when
+// reading movies.
+var MovieFieldSafelist = []string{"id", "title", "year", "runtime", "genres", "version", "rating", "votes"}
+
+func ValidateMovieFields(v *validator.Validator, fields []string) {
+	for _, field := range fields {
+		v.Check(validator.In(field, MovieFieldSafelist...), "fields", fmt.Sprintf("invalid field %q", field))
+	}
+
+	v.Check(validator.Unique(fields), "fields", "must not contain duplicate values")
+}
+
+// movieColumns are all the columns read for a movie.
+var movieColumns = []string{"id", "created_at", "title", "year", "runtime", "genres", "version", "rating", "votes"}
+
+// selectMovieColumns returns the columns to read for the selected fields, with
+// no fields meaning every column. The id is always read, as it's needed to
+// identify the movie.
+func selectMovieColumns(fields []string) []string {
+	if len(fields) == 0 {
+		return movieColumns
+	}
+
+	columns := []string{"id"}
+	for _, column := range movieColumns[1:] {
+		if slices.Contains(fields, column) {
+			columns = append(columns, column)
+		}
+	}
+
+	return columns
+}
+
+// movieScanDest returns the destinations to scan the columns into.
+func movieScanDest(movie *Movie, columns []string) []any {
+	dest := make([]any, 0, len(columns))
+
+	for _, column := range columns {
+		switch column {
+		case "id":
+			dest = append(dest, &movie.ID)
+		case "created_at":
+			dest = append(dest, &movie.CreatedAt)
+		case "title":
+			dest = append(dest, &movie.Title)
+		case "year":
+			dest = append(dest, &movie.Year)
+		case "runtime":
+			dest = append(dest, &movie.Runtime)
+		case "genres":
+			dest = append(dest, pq.Array(&movie.Genres))
+		case "version":
+			dest = append(dest, &movie.Version)
+		case "rating":
+			dest = append(dest, &movie.Rating)
+		case "votes":
+			dest = append(dest, &movie.Votes)
+		}
+	}
+
+	return dest
+}
+
+const (
+	// SearchFullText matches whole words of the title, ranked with ts_rank.
+	SearchFullText = "fulltext"
+	// SearchFuzzy matches misspelled and partial words using pg_trgm, ranked by
+	// the trigram similarity.
+	SearchFuzzy = "fuzzy"
+)
+
+// MovieFilters holds the optional conditions used when listing movies. Zero
+// values mean that the condition is not applied.
+type MovieFilters struct {
+	Title         string
+	SearchMode    string
+	Genres        []string
+	GenresAny     []string
+	GenresExclude []string
+	YearMin       int
+	YearMax       int
+	RuntimeMin    int
+	RuntimeMax    int
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Person        int64
+	PersonRole    string
+
+	// ExternalProvider and ExternalID select the movie with the ID in the
+	// catalog of the provider.
+	ExternalProvider string
+	ExternalID       string
+}
+
+func ValidateMovieFilters(v *validator.Validator, mf MovieFilters) {
+	v.Check(validator.In(mf.SearchMode, SearchFullText, SearchFuzzy), "search_mode", "invalid search mode")
+
+	v.Check(mf.YearMin >= 0, "year_min", "must not be negative")
+	v.Check(mf.YearMax >= 0, "year_max", "must not be negative")
+	if mf.YearMin != 0 && mf.YearMax != 0 {
+		v.Check(mf.YearMin <= mf.YearMax, "year_min", "must not be greater than year_max")
+	}
+
+	v.Check(mf.RuntimeMin >= 0, "runtime_min", "must not be negative")
+	v.Check(mf.RuntimeMax >= 0, "runtime_max", "must not be negative")
+	if mf.RuntimeMin != 0 && mf.RuntimeMax != 0 {
+		v.Check(mf.RuntimeMin <= mf.RuntimeMax, "runtime_min", "must not be greater than runtime_max")
+	}
+
+	if !mf.CreatedAfter.IsZero() && !mf.CreatedBefore.IsZero() {
+		v.Check(!mf.CreatedAfter.After(mf.CreatedBefore), "created_after", "must not be after created_before")
+	}
+
+	v.Check(len(mf.Genres) <= 5, "genres", "must not contain more than 5 genres")
+	v.Check(len(mf.GenresAny) <= 20, "genres_any", "must not contain more than 20 genres")
+	v.Check(len(mf.GenresExclude) <= 20, "genres_exclude", "must not contain more than 20 genres")
+
+	v.Check(mf.Person >= 0, "person", "must not be negative")
+	if mf.PersonRole != "" {
+		v.Check(mf.Person != 0, "person_role", "must only be provided with person")
+		v.Check(validator.In(mf.PersonRole, CreditRoles...), "person_role", fmt.Sprintf("must be one of %v", CreditRoles))
+	}
+
+	if mf.ExternalProvider != "" || mf.ExternalID != "" {
+		rx, ok := ExternalIDRx[mf.ExternalProvider]
+		v.Check(ok && validator.Matches(mf.ExternalID, rx), "external_id", fmt.Sprintf("must be a valid ID of one of %v, like imdb:tt0111161", ExternalProviders))
+	}
+}
+
+// The title condition depends on the search mode ($11). In fuzzy mode the %
+// operator is true when the whole title is similar to the search value, and <%
+// when the search value is similar to some part of the title, which is what
+// makes partial words match. Both operators use the movies_titles_trgm_idx index.
+//
+// The search also matches the localized titles of the movies. Their search
+// vectors hold the words stemmed in the language of the title, so the search
+// value is matched both as it is and stemmed in that language.
+//
+// Each condition behaves like it is optional, the same way the title and genres
+// filters always did: when the placeholder holds its zero value the condition
+// evaluates to true and is essentially skipped.
+//
+// The && symbol is the 'overlaps' operator for PostgreSQL arrays, so
+// (genres && $3) is true when the movie has at least one of the genres in $3.
+// Both @> and && can use the movies_genres_idx GIN index. The genres given are
+// resolved to their slugs first, and as resolve_genres is stable it's only
+// called once per query, so the index is still used.
+//
+// The person condition ($12) keeps the movies the person is credited on, in
+// the role $13 when there is one.
+const movieFilterConditions = `
+	($1 = ''
+		OR ($11 = 'fuzzy' AND (title % $1 OR $1 <% title OR EXISTS (
+			SELECT 1 FROM movie_titles t
+			WHERE t.movie_id = movies.id AND (t.title % $1 OR $1 <% t.title))))
+		OR ($11 <> 'fuzzy' AND (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR EXISTS (
+			SELECT 1 FROM movie_titles t
+			WHERE t.movie_id = movies.id
+				AND t.search @@ (plainto_tsquery('simple', $1) || plainto_tsquery(title_search_config(t.language), $1))))))
+	AND (genres @> resolve_genres($2) OR $2 = '{}')
+	AND (genres && resolve_genres($3) OR $3 = '{}')
+	AND NOT (genres && resolve_genres($4))
+	AND (year >= $5 OR $5 = 0)
+	AND (year <= $6 OR $6 = 0)
+	AND (runtime >= $7 OR $7 = 0)
+	AND (runtime <= $8 OR $8 = 0)
+	AND (created_at >= $9 OR $9 IS NULL)
+	AND (created_at <= $10 OR $10 IS NULL)
+	AND ($12::bigint = 0 OR EXISTS (
+		SELECT 1 FROM movie_credits c
+		WHERE c.movie_id = movies.id AND c.person_id = $12 AND ($13 = '' OR c.role = $13)))
+	AND ($14 = '' OR EXISTS (
+		SELECT 1 FROM movie_external_ids e
+		WHERE e.movie_id = movies.id AND e.provider = $14 AND e.external_id = $15))`
+
+// args returns the values for the placeholders of movieFilterConditions, in order.
+func (mf MovieFilters) args() []any {
+	return []any{
+		mf.Title,                   // $1
+		pq.Array(mf.Genres),        // $2
+		pq.Array(mf.GenresAny),     // $3
+		pq.Array(mf.GenresExclude), // $4
+		mf.YearMin,                 // $5
+		mf.YearMax,                 // $6
+		mf.RuntimeMin,              // $7
+		mf.RuntimeMax,              // $8
+		nullTime(mf.CreatedAfter),  // $9
+		nullTime(mf.CreatedBefore), // $10
+		mf.SearchMode,              // $11
+		mf.Person,                  // $12
+		mf.PersonRole,              // $13
+		mf.ExternalProvider,        // $14
+		mf.ExternalID,              // $15
+	}
+}
+
+// movieRelevance scores each row against the title search using the same
+// placeholders as movieFilterConditions. A movie scores as its best matching
+// title, the original or a localized one.
+const movieRelevance = `
+	CASE
+		WHEN $1 = '' THEN 0
+		WHEN $11 = 'fuzzy' THEN greatest(word_similarity($1, title), (
+			SELECT max(word_similarity($1, t.title)) FROM movie_titles t WHERE t.movie_id = movies.id))
+		ELSE greatest(ts_rank(to_tsvector('simple', title), plainto_tsquery('simple', $1)), (
+			SELECT max(ts_rank(t.search, plainto_tsquery('simple', $1) || plainto_tsquery(title_search_config(t.language), $1)))
+			FROM movie_titles t WHERE t.movie_id = movies.id))
+	END`
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// MovieSuggestion is the trimmed down movie returned while a client is typing.
+type MovieSuggestion struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	Year  int32  `json:"year"`
+}
+
+var (
+	maxBytesSuggestion = 100
+	maxSuggestions     = 20
+)
+
+func ValidateSuggestionQuery(v *validator.Validator, prefix string, limit int) {
+	v.Check(prefix != "", "q", "must be provided")
+	v.Check(len(prefix) <= maxBytesSuggestion, "q", fmt.Sprintf("must not be more than %d bytes long", maxBytesSuggestion))
+
+	v.Check(limit > 0, "limit", "must be greater than 0")
+	v.Check(limit <= maxSuggestions, "limit", fmt.Sprintf("must be a maximum of %d", maxSuggestions))
+}
+
 type MovieQuerier interface {
 	Get(id int64) (*Movie, error)
-	GetAll(title string, genres []string, f Filters) ([]*Movie, Metadata, error)
+	GetFields(id int64, fields []string) (*Movie, error)
+	GetAll(mf MovieFilters, f Filters) ([]*Movie, Metadata, error)
+	GetFacets(mf MovieFilters, facets []string) (Facets, error)
+	GetRevisions(movieIDs []int64) (map[int64][]*MovieRevision, error)
+	GetTitles(movieIDs []int64) (map[int64][]*MovieTitle, error)
+	SetTitle(title *MovieTitle) error
+	DeleteTitle(movieID int64, language string) error
+	GetPoster(movieID int64) (*Poster, error)
+	SetPoster(poster *Poster) (string, error)
+	DeletePoster(movieID int64) (*Poster, error)
+	GetExternalIDs(movieIDs []int64) (map[int64][]*ExternalID, error)
+	SetExternalID(externalID *ExternalID) error
+	DeleteExternalID(movieID int64, provider string) error
+	FindDuplicates(movie *Movie) ([]*Movie, error)
+	GetStats() (*CatalogStats, error)
+	Stream(mf MovieFilters, f Filters, fn func(movie *Movie) error) error
+	Suggest(prefix string, limit int) ([]*MovieSuggestion, error)
+	GetSimilar(movieID int64, limit int) ([]*Movie, error)
+	GetRecommendations(userID int64, limit int) ([]*Movie, error)
 	Insert(movie *Movie) error
+	InsertWithID(movie *Movie) error
+	InsertMany(movies []*Movie, batchSize int) error
 	Update(movie *Movie) error
 	Delete(id int64) error
+	DeleteVersion(id int64, version int32) error
+	Merge(sourceID, targetID int64) error
+	InTx(fn func(q MovieQuerier) error) error
 }
 
 type MovieModel struct {
 	DB *sql.DB
+
+	// tx is only set on the models handed to the InTx callbacks, and savepoints
+	// counts how many InTx calls are nested inside that transaction.
+	tx         *sql.Tx
+	savepoints int
+}
+
+// conn returns where the queries must run: the transaction when there is one,
+// the connection pool otherwise.
+func (m MovieModel) conn() dbtx {
+	if m.tx != nil {
+		return m.tx
+	}
+
+	return m.DB
+}
+
+// InTx runs fn inside a transaction. Every query of the MovieQuerier given to fn
+// is part of it. The transaction is committed when fn returns nil and rolled back
+// otherwise.
+//
+// Calling InTx on the MovieQuerier given to fn doesn't start a new transaction,
+// it creates a savepoint instead, so a nested fn that fails only undoes its own
+// changes and the outer transaction can carry on.
+func (m MovieModel) InTx(fn func(q MovieQuerier) error) error {
+	if m.tx != nil {
+		return m.savepoint(fn)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// a no-op once the transaction is committed
+	defer tx.Rollback()
+
+	err = fn(MovieModel{DB: m.DB, tx: tx})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m MovieModel) savepoint(fn func(q MovieQuerier) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	name := fmt.Sprintf("movies_savepoint_%d", m.savepoints+1)
+
+	_, err := m.tx.ExecContext(ctx, "SAVEPOINT "+name)
+	if err != nil {
+		return err
+	}
+
+	err = fn(MovieModel{DB: m.DB, tx: m.tx, savepoints: m.savepoints + 1})
+	if err != nil {
+		_, rollbackErr := m.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		return errors.Join(err, rollbackErr)
+	}
+
+	_, err = m.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
 }
 
 func (m MovieModel) Insert(movie *Movie) error {
@@ -64,33 +403,126 @@ func (m MovieModel) Insert(movie *Movie) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+	return m.conn().QueryRowContext(ctx, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+}
+
+// ErrUnissuedMovieID is returned by InsertWithID for an ID the sequence of
+// the movies hasn't handed out yet.
+var ErrUnissuedMovieID = errors.New("unissued movie id")
+
+// InsertWithID inserts a movie with the ID it already has, instead of one taken
+// from the sequence. Only the IDs the sequence already handed out are accepted,
+// like the ones of deleted movies, as the sequence is shared with Insert and
+// can't be moved past an ID that a client chose without racing with it or
+// being used up. ErrUnissuedMovieID is returned for a greater ID, and
+// ErrEditConflict when a movie with the ID exists.
+func (m MovieModel) InsertWithID(movie *Movie) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the sequence only grows, so an ID it handed out stays valid
+	query := `
+	SELECT CASE WHEN is_called THEN last_value ELSE last_value - 1 END
+	FROM movies_id_seq`
+
+	var lastID int64
+	err := m.conn().QueryRowContext(ctx, query).Scan(&lastID)
+	if err != nil {
+		return err
+	}
+
+	if movie.ID > lastID {
+		return ErrUnissuedMovieID
+	}
+
+	query = `
+	INSERT INTO movies (id, title, year, runtime, genres)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (id) DO NOTHING
+	RETURNING created_at, version`
+
+	args := []any{movie.ID, movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres)}
+
+	err = m.conn().QueryRowContext(ctx, query, args...).Scan(&movie.CreatedAt, &movie.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// InsertMany inserts all the movies in a single transaction, sending them with
+// the COPY protocol in batches of batchSize rows. Unlike Insert, the ID, version
+// and creation date of the movies are not read back.
+func (m MovieModel) InsertMany(movies []*Movie, batchSize int) error {
+	return m.InTx(func(q MovieQuerier) error {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		tx := q.(MovieModel).tx
+
+		for start := 0; start < len(movies); start += batchSize {
+			end := min(start+batchSize, len(movies))
+
+			err := copyMovies(ctx, tx, movies[start:end])
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func copyMovies(ctx context.Context, tx *sql.Tx, movies []*Movie) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("movies", "title", "year", "runtime", "genres"))
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	for _, movie := range movies {
+		_, err = stmt.ExecContext(ctx, movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres))
+		if err != nil {
+			return err
+		}
+	}
+
+	// an Exec without arguments flushes the buffered rows and ends the COPY
+	_, err = stmt.ExecContext(ctx)
+	return err
 }
 
 func (m MovieModel) Get(id int64) (*Movie, error) {
+	return m.GetFields(id, nil)
+}
+
+// GetFields reads only the columns of the selected fields of the movie, see
+// selectMovieColumns.
+func (m MovieModel) GetFields(id int64, fields []string) (*Movie, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
-	query := `
-	SELECT id, created_at, title, year, runtime, genres, version
+	columns := selectMovieColumns(fields)
+
+	query := fmt.Sprintf(`
+	SELECT %s
 	FROM movies
-	WHERE id=$1`
+	WHERE id=$1`, strings.Join(columns, ", "))
 
 	var movie Movie
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
-		&movie.ID,
-		&movie.CreatedAt,
-		&movie.Title,
-		&movie.Year,
-		&movie.Runtime,
-		pq.Array(&movie.Genres),
-		&movie.Version,
-	)
+	err := m.conn().QueryRowContext(ctx, query, id).Scan(movieScanDest(&movie, columns)...)
 
 	if err != nil {
 		switch {
@@ -123,7 +555,7 @@ func (m MovieModel) Update(movie *Movie) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
+	err := m.conn().QueryRowContext(ctx, query, args...).Scan(&movie.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -136,6 +568,9 @@ func (m MovieModel) Update(movie *Movie) error {
 	return nil
 }
 
+// Delete removes the movie. Its reviews, credits, localized titles and list
+// items are removed along with it, and the lists it was in close the gap it
+// leaves.
 func (m MovieModel) Delete(id int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
@@ -148,7 +583,7 @@ func (m MovieModel) Delete(id int64) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	result, err := m.conn().ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -165,7 +600,45 @@ func (m MovieModel) Delete(id int64) error {
 	return nil
 }
 
-func (m MovieModel) GetAll(title string, genres []string, f Filters) ([]*Movie, Metadata, error) {
+// DeleteVersion deletes the movie only while it is still at the given version.
+// Like Update, it returns ErrEditConflict when the movie was changed or no
+// longer exists.
+func (m MovieModel) DeleteVersion(id int64, version int32) error {
+	query := `
+	DELETE FROM movies
+	WHERE id=$1 AND version=$2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := m.conn().ExecContext(ctx, query, id, version)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
+	return nil
+}
+
+// movieOrderBy builds the ORDER BY clause for the queries listing movies.
+// Higher scores are better matches, so sort=relevance lists the best ones first.
+func movieOrderBy(f Filters) string {
+	if f.sortColumn() == "relevance" {
+		return "relevance DESC"
+	}
+
+	return fmt.Sprintf("%s %s", f.sortColumn(), f.sortDirection())
+}
+
+func (m MovieModel) GetAll(mf MovieFilters, f Filters) ([]*Movie, Metadata, error) {
 	// This SQL query is designed so that each of the filters behaves like it is ‘optional’. For
 	// example, the condition (LOWER(title) = LOWER($1) OR $1 = '') will evaluate as true if
 	// the placeholder parameter $1 is a case-insensitive match for the movie title or the
@@ -199,26 +672,27 @@ func (m MovieModel) GetAll(title string, genres []string, f Filters) ([]*Movie,
 	// the generated query term matches the lexemes. To continue the example, the query term
 	// 'the' & 'club' will match rows which contain both lexemes 'the' and 'club' .
 
+	args := mf.args()
+	args = append(args, f.limit(), f.offset())
+
+	orderBy := movieOrderBy(f)
+
+	columns := selectMovieColumns(f.Fields)
+
 	query := fmt.Sprintf(`
-	SELECT count(*) OVER(), id, created_at, title, year, runtime, genres, version
+	SELECT count(*) OVER(), %s,
+		%s AS relevance
 	FROM movies
-	WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
-	AND (genres @> $2 OR $2 = '{}')
-	ORDER BY %s %s, id ASC
-	LIMIT $3 OFFSET $4`, f.sortColumn(), f.sortDirection())
+	WHERE %s
+	ORDER BY %s, id ASC
+	LIMIT $%d OFFSET $%d`,
+		strings.Join(columns, ", "), movieRelevance, movieFilterConditions, orderBy, len(args)-1, len(args))
 	////////////////////////
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	args := []any{
-		title,            // $1
-		pq.Array(genres), // $2
-		f.limit(),        // $3
-		f.offset(),       // $4
-	}
-
-	rows, err := m.DB.QueryContext(ctx, query, args...)
+	rows, err := m.conn().QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, Metadata{}, err
 	}
@@ -229,16 +703,12 @@ func (m MovieModel) GetAll(title string, genres []string, f Filters) ([]*Movie,
 	movies := []*Movie{}
 	for rows.Next() {
 		var movie Movie
-		err = rows.Scan(
-			&totalRecords,
-			&movie.ID,
-			&movie.CreatedAt,
-			&movie.Title,
-			&movie.Year,
-			&movie.Runtime,
-			pq.Array(&movie.Genres),
-			&movie.Version,
-		)
+
+		dest := []any{&totalRecords}
+		dest = append(dest, movieScanDest(&movie, columns)...)
+		dest = append(dest, &movie.Score)
+
+		err = rows.Scan(dest...)
 
 		if err != nil {
 			return nil, Metadata{}, err
@@ -254,3 +724,101 @@ func (m MovieModel) GetAll(title string, genres []string, f Filters) ([]*Movie,
 	metadata := calculateMetadata(totalRecords, f.Page, f.PageSize)
 	return movies, metadata, nil
 }
+
+// Suggest returns the movies whose title starts with prefix. The lower(title)
+// LIKE 'prefix%' condition can use the movies_titles_prefix_idx index (built with
+// text_pattern_ops), so this stays fast enough to be called on every keystroke.
+// Shorter titles come first since they are the closest to what was typed.
+func (m MovieModel) Suggest(prefix string, limit int) ([]*MovieSuggestion, error) {
+	query := `
+	SELECT id, title, year
+	FROM movies
+	WHERE lower(title) LIKE $1
+	ORDER BY length(title), title, id
+	LIMIT $2`
+
+	pattern := escapeLike(strings.ToLower(prefix)) + "%"
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	rows, err := m.conn().QueryContext(ctx, query, pattern, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	suggestions := []*MovieSuggestion{}
+	for rows.Next() {
+		var suggestion MovieSuggestion
+		err = rows.Scan(&suggestion.ID, &suggestion.Title, &suggestion.Year)
+		if err != nil {
+			return nil, err
+		}
+
+		suggestions = append(suggestions, &suggestion)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+// escapeLike escapes the LIKE wildcards so they are matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Stream calls fn for every movie matching mf, in the order given by f. The
+// pagination fields of f are ignored. Rows are read from the database one at a
+// time, so the result set is never held in memory. If fn returns an error the
+// iteration stops and the error is returned.
+func (m MovieModel) Stream(mf MovieFilters, f Filters, fn func(movie *Movie) error) error {
+	orderBy := movieOrderBy(f)
+
+	query := fmt.Sprintf(`
+	SELECT id, created_at, title, year, runtime, genres, version, rating, votes, %s AS relevance
+	FROM movies
+	WHERE %s
+	ORDER BY %s, id ASC`, movieRelevance, movieFilterConditions, orderBy)
+
+	// an export of the whole catalog can take a while, the timeout only protects
+	// against a stuck connection
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	rows, err := m.conn().QueryContext(ctx, query, mf.args()...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var movie Movie
+		err = rows.Scan(
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.Version,
+			&movie.Rating,
+			&movie.Votes,
+			&movie.Score,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err = fn(&movie); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -4,6 +4,8 @@
 package data
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,6 +18,109 @@ func TestInsertMovies(t *testing.T) {
 	newMovie(t, &movie)
 }
 
+func TestInsertMovieWithID(t *testing.T) {
+	deleted := randomMovie()
+	newMovie(t, &deleted)
+
+	err := testModels.Movies.Delete(deleted.ID)
+	require.NoError(t, err)
+
+	movie := randomMovie()
+	movie.ID = deleted.ID
+
+	err = testModels.Movies.InsertWithID(&movie)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), movie.Version)
+
+	actualMovie, err := testModels.Movies.Get(movie.ID)
+	require.NoError(t, err)
+	verifyMovies(t, movie, *actualMovie)
+
+	err = testModels.Movies.InsertWithID(&movie)
+	require.ErrorIs(t, err, ErrEditConflict)
+
+	// the IDs the sequence didn't hand out yet are left to it
+	unissued := randomMovie()
+	unissued.ID = movie.ID + util.RandomInt(10, 100)
+
+	err = testModels.Movies.InsertWithID(&unissued)
+	require.ErrorIs(t, err, ErrUnissuedMovieID)
+
+	next := randomMovie()
+	newMovie(t, &next)
+	require.Greater(t, next.ID, movie.ID)
+}
+
+func TestInsertManyMovies(t *testing.T) {
+	genre := util.RandomString(10)
+
+	n := 5
+	var movies []*Movie
+	for i := 0; i < n; i++ {
+		movie := randomMovie()
+		movie.Genres = []string{genre}
+		movies = append(movies, &movie)
+	}
+
+	err := testModels.Movies.InsertMany(movies, 2)
+	require.NoError(t, err)
+
+	f := Filters{
+		Page:         1,
+		PageSize:     n,
+		Sort:         "id",
+		SortSafelist: []string{"id"},
+	}
+
+	mf := MovieFilters{
+		SearchMode: SearchFullText,
+		Genres:     []string{genre},
+	}
+
+	actualMovies, _, err := testModels.Movies.GetAll(mf, f)
+	require.NoError(t, err)
+	require.Len(t, actualMovies, n)
+
+	for i, movie := range actualMovies {
+		require.Equal(t, movies[i].Title, movie.Title)
+		require.Equal(t, movies[i].Year, movie.Year)
+		require.Equal(t, movies[i].Runtime, movie.Runtime)
+	}
+}
+
+func TestStreamMovies(t *testing.T) {
+	genre := util.RandomString(10)
+
+	var expected []int64
+	for i := 0; i < 3; i++ {
+		movie := randomMovie()
+		movie.Genres = []string{genre}
+		newMovie(t, &movie)
+		expected = append(expected, movie.ID)
+	}
+
+	mf := MovieFilters{
+		SearchMode: SearchFullText,
+		Genres:     []string{genre},
+	}
+
+	f := Filters{Sort: "-id", SortSafelist: []string{"-id"}}
+
+	var actual []int64
+	err := testModels.Movies.Stream(mf, f, func(movie *Movie) error {
+		actual = append(actual, movie.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int64{expected[2], expected[1], expected[0]}, actual)
+
+	stop := errors.New("stop")
+	err = testModels.Movies.Stream(mf, f, func(movie *Movie) error {
+		return stop
+	})
+	require.ErrorIs(t, err, stop)
+}
+
 func TestGetAllMovie(t *testing.T) {
 	n := 5
 	var expectedMovies []*Movie
@@ -33,7 +138,13 @@ func TestGetAllMovie(t *testing.T) {
 		SortSafelist: []string{"title"},
 	}
 
-	actualMovies, _, err := testModels.Movies.GetAll(movie.Title, movie.Genres, f)
+	mf := MovieFilters{
+		Title:      movie.Title,
+		SearchMode: SearchFullText,
+		Genres:     movie.Genres,
+	}
+
+	actualMovies, _, err := testModels.Movies.GetAll(mf, f)
 
 	require.NoError(t, err)
 
@@ -41,6 +152,76 @@ func TestGetAllMovie(t *testing.T) {
 	require.ElementsMatch(t, expectedMovies, actualMovies)
 }
 
+func TestGetAllMovieFuzzySearch(t *testing.T) {
+	movie := randomMovie()
+	movie.Title = "Breakfast " + util.RandomString(10)
+	newMovie(t, &movie)
+
+	f := Filters{
+		Page:         1,
+		PageSize:     10,
+		Sort:         "relevance",
+		SortSafelist: []string{"relevance"},
+	}
+
+	mf := MovieFilters{
+		Title:      "brekfast",
+		SearchMode: SearchFuzzy,
+		Genres:     movie.Genres,
+	}
+
+	actualMovies, _, err := testModels.Movies.GetAll(mf, f)
+	require.NoError(t, err)
+	require.Len(t, actualMovies, 1)
+	require.Equal(t, movie.ID, actualMovies[0].ID)
+	require.Greater(t, actualMovies[0].Score, 0.0)
+
+	mf.SearchMode = SearchFullText
+	actualMovies, _, err = testModels.Movies.GetAll(mf, f)
+	require.NoError(t, err)
+	require.Empty(t, actualMovies)
+}
+
+func TestGetAllMovieRangeFilters(t *testing.T) {
+	movie := randomMovie()
+	movie.Year = 1995
+	movie.Runtime = 100
+	newMovie(t, &movie)
+
+	f := Filters{
+		Page:         1,
+		PageSize:     10,
+		Sort:         "id",
+		SortSafelist: []string{"id"},
+	}
+
+	mf := MovieFilters{
+		Title:      movie.Title,
+		SearchMode: SearchFullText,
+		GenresAny:  []string{movie.Genres[0], util.RandomString(10)},
+		YearMin:    1990,
+		YearMax:    2000,
+		RuntimeMin: 90,
+		RuntimeMax: 110,
+	}
+
+	actualMovies, _, err := testModels.Movies.GetAll(mf, f)
+	require.NoError(t, err)
+	require.Len(t, actualMovies, 1)
+	verifyMovies(t, movie, *actualMovies[0])
+
+	mf.GenresExclude = []string{movie.Genres[1]}
+	actualMovies, _, err = testModels.Movies.GetAll(mf, f)
+	require.NoError(t, err)
+	require.Empty(t, actualMovies)
+
+	mf.GenresExclude = nil
+	mf.CreatedAfter = movie.CreatedAt.Add(time.Hour)
+	actualMovies, _, err = testModels.Movies.GetAll(mf, f)
+	require.NoError(t, err)
+	require.Empty(t, actualMovies)
+}
+
 func TestDeleteMovie(t *testing.T) {
 	movie := randomMovie()
 	newMovie(t, &movie)
@@ -55,6 +236,61 @@ func TestDeleteMovie(t *testing.T) {
 	require.ErrorIs(t, ErrRecordNotFound, err)
 }
 
+func TestDeleteMovieVersion(t *testing.T) {
+	movie := randomMovie()
+	newMovie(t, &movie)
+
+	err := testModels.Movies.DeleteVersion(movie.ID, movie.Version+1)
+	require.ErrorIs(t, err, ErrEditConflict)
+
+	err = testModels.Movies.DeleteVersion(movie.ID, movie.Version)
+	require.NoError(t, err)
+
+	_, err = testModels.Movies.Get(movie.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestMoviesInTx(t *testing.T) {
+	committed := randomMovie()
+	rolledBack := randomMovie()
+	errRollback := errors.New("rollback")
+
+	err := testModels.Movies.InTx(func(q MovieQuerier) error {
+		err := q.Insert(&committed)
+		require.NoError(t, err)
+
+		// a failing nested transaction only rolls back to its savepoint
+		err = q.InTx(func(q MovieQuerier) error {
+			err := q.Insert(&rolledBack)
+			require.NoError(t, err)
+
+			return errRollback
+		})
+		require.ErrorIs(t, err, errRollback)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	_, err = testModels.Movies.Get(committed.ID)
+	require.NoError(t, err)
+
+	_, err = testModels.Movies.Get(rolledBack.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	discarded := randomMovie()
+	err = testModels.Movies.InTx(func(q MovieQuerier) error {
+		err := q.Insert(&discarded)
+		require.NoError(t, err)
+
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	_, err = testModels.Movies.Get(discarded.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
 func TestGetMovie(t *testing.T) {
 	expectedMovie := randomMovie()
 	newMovie(t, &expectedMovie)
@@ -68,6 +304,47 @@ func TestGetMovie(t *testing.T) {
 	require.ErrorIs(t, err, ErrRecordNotFound)
 }
 
+func TestGetMovieFields(t *testing.T) {
+	expectedMovie := randomMovie()
+	newMovie(t, &expectedMovie)
+
+	actualMovie, err := testModels.Movies.GetFields(expectedMovie.ID, []string{"title", "genres"})
+	require.NoError(t, err)
+
+	require.Equal(t, expectedMovie.ID, actualMovie.ID)
+	require.Equal(t, expectedMovie.Title, actualMovie.Title)
+	require.ElementsMatch(t, expectedMovie.Genres, actualMovie.Genres)
+	require.Zero(t, actualMovie.Year)
+	require.Zero(t, actualMovie.Runtime)
+	require.Zero(t, actualMovie.Version)
+}
+
+func TestGetMovieRevisions(t *testing.T) {
+	movie := randomMovie()
+	newMovie(t, &movie)
+	original := movie
+
+	for i := 0; i < 2; i++ {
+		movie.Title = util.RandomFullName()
+		err := testModels.Movies.Update(&movie)
+		require.NoError(t, err)
+	}
+
+	unchanged := randomMovie()
+	newMovie(t, &unchanged)
+
+	revisions, err := testModels.Movies.GetRevisions([]int64{movie.ID, unchanged.ID})
+	require.NoError(t, err)
+
+	require.NotContains(t, revisions, unchanged.ID)
+	require.Len(t, revisions[movie.ID], 2)
+
+	// newest first, the oldest being the movie as it was inserted
+	require.Equal(t, original.Version+1, revisions[movie.ID][0].Version)
+	require.Equal(t, original.Version, revisions[movie.ID][1].Version)
+	require.Equal(t, original.Title, revisions[movie.ID][1].Title)
+}
+
 func TestUpdateMvoie(t *testing.T) {
 	beforeMovie := randomMovie()
 	newMovie(t, &beforeMovie)
@@ -95,6 +372,68 @@ func TestUpdateMvoie(t *testing.T) {
 	require.ErrorIs(t, err, ErrEditConflict)
 }
 
+func TestGetMovieFacets(t *testing.T) {
+	genre := util.RandomString(10)
+
+	years := []int32{1985, 1987, 1992}
+	runtimes := []Runtime{85, 100, 160}
+	for i := range years {
+		movie := randomMovie()
+		movie.Genres = []string{genre, util.RandomString(10)}
+		movie.Year = years[i]
+		movie.Runtime = runtimes[i]
+		newMovie(t, &movie)
+	}
+
+	mf := MovieFilters{
+		SearchMode: SearchFullText,
+		Genres:     []string{genre},
+	}
+
+	facets, err := testModels.Movies.GetFacets(mf, FacetSafelist)
+	require.NoError(t, err)
+
+	require.Len(t, facets[FacetGenres], 4)
+	require.Equal(t, FacetCount{Value: genre, Count: 3}, facets[FacetGenres][0])
+
+	require.Equal(t, []FacetCount{
+		{Value: "1980s", Count: 2},
+		{Value: "1990s", Count: 1},
+	}, facets[FacetDecade])
+
+	require.Equal(t, []FacetCount{
+		{Value: "0-89", Count: 1},
+		{Value: "90-119", Count: 1},
+		{Value: "150+", Count: 1},
+	}, facets[FacetRuntime])
+}
+
+func TestSuggestMovies(t *testing.T) {
+	prefix := util.RandomString(12)
+
+	short := randomMovie()
+	short.Title = prefix + " 2"
+	newMovie(t, &short)
+
+	long := randomMovie()
+	long.Title = prefix + " and the long title"
+	newMovie(t, &long)
+
+	suggestions, err := testModels.Movies.Suggest(strings.ToUpper(prefix), 10)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 2)
+	require.Equal(t, short.ID, suggestions[0].ID)
+	require.Equal(t, long.ID, suggestions[1].ID)
+
+	suggestions, err = testModels.Movies.Suggest(prefix, 1)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+
+	suggestions, err = testModels.Movies.Suggest(prefix+"%", 10)
+	require.NoError(t, err)
+	require.Empty(t, suggestions)
+}
+
 func verifyMovies(t *testing.T, expected Movie, actual Movie) {
 	require.Equal(t, actual.ID, expected.ID)
 	require.Equal(t, actual.Title, expected.Title)
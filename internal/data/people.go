@@ -0,0 +1,398 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/validator"
+	"github.com/lib/pq"
+)
+
+const (
+	RoleDirector = "director"
+	RoleActor    = "actor"
+	RoleWriter   = "writer"
+)
+
+// CreditRoles are the roles a person can be credited with on a movie.
+var CreditRoles = []string{RoleDirector, RoleActor, RoleWriter}
+
+var (
+	SqlErrDupCredit      = `pq: duplicate key value violates unique constraint "movie_credits_movie_id_person_id_role_key"`
+	SqlErrCreditMovieFK  = `pq: insert or update on table "movie_credits" violates foreign key constraint "movie_credits_movie_id_fkey"`
+	SqlErrCreditPersonFK = `pq: insert or update on table "movie_credits" violates foreign key constraint "movie_credits_person_id_fkey"`
+)
+
+var (
+	ErrDuplicateCredit = errors.New("duplicate credit")
+	ErrUnknownMovie    = errors.New("unknown movie")
+)
+
+// Person is someone credited on movies, like a director or an actor.
+type Person struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	Name      string    `json:"name"`
+	Version   int32     `json:"version"`
+}
+
+var maxBytesPersonName = 500
+
+func ValidatePerson(v *validator.Validator, person *Person) {
+	v.Check(person.Name != "", "name", "must be provided")
+	v.Check(len(person.Name) <= maxBytesPersonName, "name", fmt.Sprintf("must not be more than %d bytes long", maxBytesPersonName))
+}
+
+// PersonSummary is the trimmed down person embedded in the credits of a movie.
+type PersonSummary struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// Credit is the role of a person on a movie. Depending on where the credit is
+// read from, either the person or the movie is filled, as the other side is
+// already known.
+type Credit struct {
+	ID        int64            `json:"id"`
+	MovieID   int64            `json:"-"`
+	PersonID  int64            `json:"-"`
+	Person    *PersonSummary   `json:"person,omitempty"`
+	Movie     *MovieSuggestion `json:"movie,omitempty"`
+	Role      string           `json:"role"`
+	Character string           `json:"character,omitempty"`
+}
+
+var maxBytesCharacter = 500
+
+func ValidateCredit(v *validator.Validator, credit *Credit) {
+	v.Check(credit.MovieID > 0, "movie_id", "must be provided")
+
+	v.Check(credit.Role != "", "role", "must be provided")
+	v.Check(validator.In(credit.Role, CreditRoles...), "role", fmt.Sprintf("must be one of %v", CreditRoles))
+
+	v.Check(credit.Character == "" || credit.Role == RoleActor, "character", "must only be provided for actors")
+	v.Check(len(credit.Character) <= maxBytesCharacter, "character", fmt.Sprintf("must not be more than %d bytes long", maxBytesCharacter))
+}
+
+type PersonQuerier interface {
+	Insert(person *Person) error
+	Get(id int64) (*Person, error)
+	GetAll(name string, f Filters) ([]*Person, Metadata, error)
+	Update(person *Person) error
+	Delete(id int64) error
+	GetCredits(personID int64) ([]*Credit, error)
+	GetCreditsForMovies(movieIDs []int64) (map[int64][]*Credit, error)
+	AddCredit(credit *Credit) error
+	DeleteCredit(personID, id int64) error
+}
+
+type PersonModel struct {
+	DB *sql.DB
+}
+
+var _ PersonQuerier = (*PersonModel)(nil)
+
+func (m PersonModel) Insert(person *Person) error {
+	query := `
+	INSERT INTO people (name)
+	VALUES ($1)
+	RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, person.Name).Scan(&person.ID, &person.CreatedAt, &person.Version)
+}
+
+func (m PersonModel) Get(id int64) (*Person, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	SELECT id, created_at, name, version
+	FROM people
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var person Person
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&person.ID,
+		&person.CreatedAt,
+		&person.Name,
+		&person.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &person, nil
+}
+
+// GetAll lists the people, optionally only the ones with every word of name in
+// theirs.
+func (m PersonModel) GetAll(name string, f Filters) ([]*Person, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, created_at, name, version
+	FROM people
+	WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
+	ORDER BY %s %s, id ASC
+	LIMIT $2 OFFSET $3`, f.sortColumn(), f.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, name, f.limit(), f.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	people := []*Person{}
+	for rows.Next() {
+		var person Person
+
+		err = rows.Scan(
+			&totalRecords,
+			&person.ID,
+			&person.CreatedAt,
+			&person.Name,
+			&person.Version,
+		)
+
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		people = append(people, &person)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return people, calculateMetadata(totalRecords, f.Page, f.PageSize), nil
+}
+
+func (m PersonModel) Update(person *Person) error {
+	query := `
+	UPDATE people
+	SET name = $1, version = version + 1
+	WHERE id = $2 AND version = $3
+	RETURNING version`
+
+	args := []any{person.Name, person.ID, person.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&person.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the person along with their credits.
+func (m PersonModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+	DELETE FROM people
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetCredits returns the credits of the person, with the movie of each of them,
+// newest movies first.
+func (m PersonModel) GetCredits(personID int64) ([]*Credit, error) {
+	query := `
+	SELECT c.id, c.movie_id, c.person_id, c.role, coalesce(c.character, ''), m.title, m.year
+	FROM movie_credits c
+	JOIN movies m ON m.id = c.movie_id
+	WHERE c.person_id = $1
+	ORDER BY m.year DESC, m.id, c.role`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, personID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	credits := []*Credit{}
+	for rows.Next() {
+		credit := Credit{Movie: &MovieSuggestion{}}
+
+		err = rows.Scan(
+			&credit.ID,
+			&credit.MovieID,
+			&credit.PersonID,
+			&credit.Role,
+			&credit.Character,
+			&credit.Movie.Title,
+			&credit.Movie.Year,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		credit.Movie.ID = credit.MovieID
+		credits = append(credits, &credit)
+	}
+
+	return credits, rows.Err()
+}
+
+// GetCreditsForMovies returns the credits of each of the movies, with the
+// person of each of them. Directors come first, then the writers and the cast,
+// each in the order they were credited. Movies without credits are not in the
+// returned map.
+func (m PersonModel) GetCreditsForMovies(movieIDs []int64) (map[int64][]*Credit, error) {
+	query := `
+	SELECT c.id, c.movie_id, c.person_id, c.role, coalesce(c.character, ''), p.name
+	FROM movie_credits c
+	JOIN people p ON p.id = c.person_id
+	WHERE c.movie_id = ANY($1)
+	ORDER BY c.movie_id, array_position($2::text[], c.role), c.id`
+
+	roles := []string{RoleDirector, RoleWriter, RoleActor}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs), pq.Array(roles))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	credits := make(map[int64][]*Credit)
+	for rows.Next() {
+		credit := Credit{Person: &PersonSummary{}}
+
+		err = rows.Scan(
+			&credit.ID,
+			&credit.MovieID,
+			&credit.PersonID,
+			&credit.Role,
+			&credit.Character,
+			&credit.Person.Name,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		credit.Person.ID = credit.PersonID
+		credits[credit.MovieID] = append(credits[credit.MovieID], &credit)
+	}
+
+	return credits, rows.Err()
+}
+
+// AddCredit credits the person on the movie. ErrRecordNotFound is returned when
+// the person doesn't exist, ErrUnknownMovie when the movie doesn't, and
+// ErrDuplicateCredit when the person already has the role on the movie.
+func (m PersonModel) AddCredit(credit *Credit) error {
+	query := `
+	INSERT INTO movie_credits (movie_id, person_id, role, character)
+	VALUES ($1, $2, $3, NULLIF($4, ''))
+	RETURNING id`
+
+	args := []any{credit.MovieID, credit.PersonID, credit.Role, credit.Character}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&credit.ID)
+	if err != nil {
+		switch {
+		case err.Error() == SqlErrDupCredit:
+			return ErrDuplicateCredit
+		case err.Error() == SqlErrCreditMovieFK:
+			return ErrUnknownMovie
+		case err.Error() == SqlErrCreditPersonFK:
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteCredit removes a credit of the person. A credit of someone else is not
+// found.
+func (m PersonModel) DeleteCredit(personID, id int64) error {
+	if personID < 1 || id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+	DELETE FROM movie_credits
+	WHERE id = $1 AND person_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, personID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+//go:build integration
+// +build integration
+
+package data
+
+import (
+	"testing"
+
+	"github.com/djudju12/greenlight/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeopleAndCredits(t *testing.T) {
+	person := &Person{Name: util.RandomFullName()}
+	err := testModels.People.Insert(person)
+	require.NoError(t, err)
+
+	person.Name = util.RandomFullName()
+	err = testModels.People.Update(person)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), person.Version)
+
+	stored, err := testModels.People.Get(person.ID)
+	require.NoError(t, err)
+	require.Equal(t, person.Name, stored.Name)
+
+	directed, acted := randomMovie(), randomMovie()
+	newMovie(t, &directed)
+	newMovie(t, &acted)
+
+	director := &Credit{MovieID: directed.ID, PersonID: person.ID, Role: RoleDirector}
+	err = testModels.People.AddCredit(director)
+	require.NoError(t, err)
+
+	actor := &Credit{MovieID: acted.ID, PersonID: person.ID, Role: RoleActor, Character: util.RandomString(8)}
+	err = testModels.People.AddCredit(actor)
+	require.NoError(t, err)
+
+	err = testModels.People.AddCredit(&Credit{MovieID: directed.ID, PersonID: person.ID, Role: RoleDirector})
+	require.ErrorIs(t, err, ErrDuplicateCredit)
+
+	err = testModels.People.AddCredit(&Credit{MovieID: acted.ID + 1_000_000, PersonID: person.ID, Role: RoleWriter})
+	require.ErrorIs(t, err, ErrUnknownMovie)
+
+	credits, err := testModels.People.GetCredits(person.ID)
+	require.NoError(t, err)
+	require.Len(t, credits, 2)
+
+	byMovie, err := testModels.People.GetCreditsForMovies([]int64{directed.ID, acted.ID})
+	require.NoError(t, err)
+	require.Len(t, byMovie[acted.ID], 1)
+	require.Equal(t, actor.Character, byMovie[acted.ID][0].Character)
+	require.Equal(t, person.Name, byMovie[acted.ID][0].Person.Name)
+
+	// only the movie the person directed is listed when filtering by the role
+	movies, _, err := testModels.Movies.GetAll(
+		MovieFilters{SearchMode: SearchFullText, Person: person.ID, PersonRole: RoleDirector},
+		Filters{Page: 1, PageSize: 10, Sort: "id", SortSafelist: []string{"id"}},
+	)
+	require.NoError(t, err)
+	require.Len(t, movies, 1)
+	require.Equal(t, directed.ID, movies[0].ID)
+
+	err = testModels.People.DeleteCredit(person.ID, actor.ID)
+	require.NoError(t, err)
+
+	err = testModels.People.DeleteCredit(person.ID, actor.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	err = testModels.People.Delete(person.ID)
+	require.NoError(t, err)
+
+	byMovie, err = testModels.People.GetCreditsForMovies([]int64{directed.ID})
+	require.NoError(t, err)
+	require.Empty(t, byMovie)
+}
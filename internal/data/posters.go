@@ -0,0 +1,163 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/validator"
+)
+
+var (
+	SqlErrMoviePosterFK = `pq: insert or update on table "movie_posters" violates foreign key constraint "movie_posters_movie_id_fkey"`
+)
+
+// Poster describes the uploaded poster of a movie. The image itself is kept in
+// the storage of the API, and Checksum is the SHA-256 of its contents.
+type Poster struct {
+	MovieID     int64     `json:"-"`
+	ContentType string    `json:"content_type"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	Size        int64     `json:"size"`
+	Checksum    string    `json:"-"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PosterContentTypes are the image formats accepted for posters.
+var PosterContentTypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+var (
+	minPosterSide   = 100
+	maxPosterWidth  = 4000
+	maxPosterHeight = 6000
+)
+
+func ValidatePoster(v *validator.Validator, poster *Poster) {
+	v.Check(validator.In(poster.ContentType, PosterContentTypes...), "content_type", fmt.Sprintf("must be one of %v", PosterContentTypes))
+
+	v.Check(poster.Width >= minPosterSide, "width", fmt.Sprintf("must be at least %d pixels", minPosterSide))
+	v.Check(poster.Width <= maxPosterWidth, "width", fmt.Sprintf("must not be more than %d pixels", maxPosterWidth))
+	v.Check(poster.Height >= minPosterSide, "height", fmt.Sprintf("must be at least %d pixels", minPosterSide))
+	v.Check(poster.Height <= maxPosterHeight, "height", fmt.Sprintf("must not be more than %d pixels", maxPosterHeight))
+}
+
+func (m MovieModel) GetPoster(movieID int64) (*Poster, error) {
+	query := `
+	SELECT movie_id, content_type, width, height, size, checksum, updated_at
+	FROM movie_posters
+	WHERE movie_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var poster Poster
+
+	err := m.conn().QueryRowContext(ctx, query, movieID).Scan(
+		&poster.MovieID,
+		&poster.ContentType,
+		&poster.Width,
+		&poster.Height,
+		&poster.Size,
+		&poster.Checksum,
+		&poster.UpdatedAt,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &poster, nil
+}
+
+// SetPoster records the poster of the movie, replacing the one it had, and
+// returns the checksum of the replaced poster, if there was one. It returns
+// ErrRecordNotFound when the movie doesn't exist.
+func (m MovieModel) SetPoster(poster *Poster) (string, error) {
+	// the replaced poster is locked, so that of two uploads at once the last
+	// one gets the poster of the first
+	query := `
+	WITH replaced AS (
+		SELECT checksum
+		FROM movie_posters
+		WHERE movie_id = $1
+		FOR UPDATE
+	)
+	INSERT INTO movie_posters (movie_id, content_type, width, height, size, checksum)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (movie_id) DO UPDATE
+	SET content_type = EXCLUDED.content_type,
+		width = EXCLUDED.width,
+		height = EXCLUDED.height,
+		size = EXCLUDED.size,
+		checksum = EXCLUDED.checksum,
+		updated_at = NOW()
+	RETURNING updated_at, COALESCE((SELECT checksum FROM replaced), '')`
+
+	args := []any{
+		poster.MovieID,
+		poster.ContentType,
+		poster.Width,
+		poster.Height,
+		poster.Size,
+		poster.Checksum,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var replaced string
+
+	err := m.conn().QueryRowContext(ctx, query, args...).Scan(&poster.UpdatedAt, &replaced)
+	if err != nil {
+		switch {
+		case err.Error() == SqlErrMoviePosterFK:
+			return "", ErrRecordNotFound
+		default:
+			return "", err
+		}
+	}
+
+	return replaced, nil
+}
+
+// DeletePoster removes the poster of the movie and returns it.
+func (m MovieModel) DeletePoster(movieID int64) (*Poster, error) {
+	query := `
+	DELETE FROM movie_posters
+	WHERE movie_id = $1
+	RETURNING movie_id, content_type, width, height, size, checksum, updated_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var poster Poster
+
+	err := m.conn().QueryRowContext(ctx, query, movieID).Scan(
+		&poster.MovieID,
+		&poster.ContentType,
+		&poster.Width,
+		&poster.Height,
+		&poster.Size,
+		&poster.Checksum,
+		&poster.UpdatedAt,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &poster, nil
+}
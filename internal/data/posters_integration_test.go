@@ -0,0 +1,54 @@
+//go:build integration
+// +build integration
+
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoviePosters(t *testing.T) {
+	movie := randomMovie()
+	newMovie(t, &movie)
+
+	poster := &Poster{
+		MovieID:     movie.ID,
+		ContentType: "image/png",
+		Width:       400,
+		Height:      600,
+		Size:        1024,
+		Checksum:    "0123456789abcdef",
+	}
+
+	replaced, err := testModels.Movies.SetPoster(poster)
+	require.NoError(t, err)
+	require.Empty(t, replaced)
+	require.False(t, poster.UpdatedAt.IsZero())
+
+	// a new upload replaces the poster
+	poster.ContentType = "image/jpeg"
+	poster.Checksum = "fedcba9876543210"
+	replaced, err = testModels.Movies.SetPoster(poster)
+	require.NoError(t, err)
+	require.Equal(t, "0123456789abcdef", replaced)
+
+	stored, err := testModels.Movies.GetPoster(movie.ID)
+	require.NoError(t, err)
+	require.Equal(t, "image/jpeg", stored.ContentType)
+	require.Equal(t, "fedcba9876543210", stored.Checksum)
+
+	_, err = testModels.Movies.SetPoster(&Poster{MovieID: movie.ID + 1000000, ContentType: "image/png", Checksum: "0"})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	deleted, err := testModels.Movies.DeletePoster(movie.ID)
+	require.NoError(t, err)
+	require.Equal(t, stored, deleted)
+
+	_, err = testModels.Movies.GetPoster(movie.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	_, err = testModels.Movies.DeletePoster(movie.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
@@ -0,0 +1,188 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/validator"
+)
+
+var (
+	maxRecommendations = 50
+	// minLikedScore is the lowest score of a review that counts as liking the
+	// movie.
+	minLikedScore = 7
+	// maxRecommendationSeeds caps how many of the movies of an user are used
+	// to recommend others, the ones liked the most and then the latest ones.
+	maxRecommendationSeeds = 50
+)
+
+func ValidateRecommendationLimit(v *validator.Validator, limit int) {
+	v.Check(limit > 0, "limit", "must be greater than 0")
+	v.Check(limit <= maxRecommendations, "limit", fmt.Sprintf("must be a maximum of %d", maxRecommendations))
+}
+
+// GetSimilar returns the movies most similar to the movie, with how similar
+// they are in their Score, from 0 to 1. The similarity adds up:
+//
+//   - 0.6 of the Jaccard index of the genres of the movies,
+//   - 0.2 of how close their years are, halving every 5 years apart,
+//   - 0.2 of their co-rating: how many users liked both movies, relative to
+//     how many liked each of them (the cosine of their likes).
+//
+// Only the movies that share a genre with the movie, or that were liked by
+// someone who liked it, are ranked. ErrRecordNotFound is returned when the
+// movie doesn't exist.
+func (m MovieModel) GetSimilar(movieID int64, limit int) ([]*Movie, error) {
+	query := fmt.Sprintf(`
+	WITH target AS (
+		SELECT id, genres, year FROM movies WHERE id = $1
+	),
+	likers AS (
+		SELECT user_id FROM reviews WHERE movie_id = $1 AND score >= $2
+	),
+	co_ratings AS (
+		SELECT r.movie_id, count(*) AS liked_both
+		FROM reviews r
+		JOIN likers l ON l.user_id = r.user_id
+		WHERE r.movie_id <> $1 AND r.score >= $2
+		GROUP BY r.movie_id
+	)
+	SELECT %s, similarity
+	FROM (
+		SELECT m.*,
+			0.6 * genre_jaccard(m.genres, t.genres)
+			+ 0.2 / (1 + abs(m.year - t.year) / 5.0)
+			+ 0.2 * coalesce(c.liked_both / sqrt(
+				(SELECT count(*) FROM likers) *
+				(SELECT count(*) FROM reviews WHERE movie_id = m.id AND score >= $2)), 0) AS similarity
+		FROM movies m
+		CROSS JOIN target t
+		LEFT JOIN co_ratings c ON c.movie_id = m.id
+		WHERE m.id <> t.id AND (m.genres && t.genres OR c.movie_id IS NOT NULL)
+	) ranked
+	ORDER BY similarity DESC, id
+	LIMIT $3`, strings.Join(movieColumns, ", "))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	movies, err := m.queryRanked(ctx, query, movieID, minLikedScore, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// a movie without similar ones and one that doesn't exist both have no rows
+	if len(movies) == 0 {
+		_, err = m.Get(movieID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return movies, nil
+}
+
+// GetRecommendations returns the movies recommended to the user, with how
+// strongly in their Score, from 0 to 1. The recommendations start from the
+// movies the user liked and the ones in their lists, the seeds, and add up:
+//
+//   - 0.7 of how similar the movie is to the seeds, by genres and years like
+//     in GetSimilar, weighted by how much the user liked each seed,
+//   - 0.3 of how many times the movie was liked by the users who liked the
+//     same seeds, relative to the most liked movie among them.
+//
+// The movies the user reviewed or has in a list are never recommended. Users
+// without reviews nor lists get no recommendations.
+func (m MovieModel) GetRecommendations(userID int64, limit int) ([]*Movie, error) {
+	query := fmt.Sprintf(`
+	WITH seen AS (
+		SELECT movie_id FROM reviews WHERE user_id = $1
+		UNION
+		SELECT i.movie_id FROM list_items i JOIN lists l ON l.id = i.list_id WHERE l.user_id = $1
+	),
+	seeds AS (
+		SELECT movie_id, max(weight) AS weight, max(added_at) AS added_at
+		FROM (
+			SELECT movie_id, score / 10.0 AS weight, updated_at AS added_at
+			FROM reviews WHERE user_id = $1 AND score >= $2
+			UNION ALL
+			SELECT i.movie_id, 0.5, i.added_at
+			FROM list_items i JOIN lists l ON l.id = i.list_id WHERE l.user_id = $1
+		) liked
+		GROUP BY movie_id
+		ORDER BY weight DESC, added_at DESC
+		LIMIT $4
+	),
+	content AS (
+		SELECT m.id AS movie_id, sum(s.weight * (
+			0.75 * genre_jaccard(m.genres, sm.genres)
+			+ 0.25 / (1 + abs(m.year - sm.year) / 5.0))) / (SELECT sum(weight) FROM seeds) AS affinity
+		FROM seeds s
+		JOIN movies sm ON sm.id = s.movie_id
+		JOIN movies m ON m.genres && sm.genres
+		GROUP BY m.id
+	),
+	neighbours AS (
+		SELECT r.user_id, count(*) AS shared
+		FROM reviews r
+		JOIN seeds s ON s.movie_id = r.movie_id
+		WHERE r.user_id <> $1 AND r.score >= $2
+		GROUP BY r.user_id
+	),
+	collaborative AS (
+		SELECT r.movie_id, sum(n.shared) AS likes
+		FROM reviews r
+		JOIN neighbours n ON n.user_id = r.user_id
+		WHERE r.score >= $2
+		GROUP BY r.movie_id
+	)
+	SELECT %s, recommendation
+	FROM (
+		SELECT m.*,
+			0.7 * coalesce(c.affinity, 0)
+			+ 0.3 * coalesce(f.likes::double precision / (SELECT max(likes) FROM collaborative), 0) AS recommendation
+		FROM movies m
+		LEFT JOIN content c ON c.movie_id = m.id
+		LEFT JOIN collaborative f ON f.movie_id = m.id
+		WHERE (c.movie_id IS NOT NULL OR f.movie_id IS NOT NULL)
+			AND m.id NOT IN (SELECT movie_id FROM seen)
+	) ranked
+	ORDER BY recommendation DESC, id
+	LIMIT $3`, strings.Join(movieColumns, ", "))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return m.queryRanked(ctx, query, userID, minLikedScore, limit, maxRecommendationSeeds)
+}
+
+// queryRanked runs a query that selects the movieColumns followed by a score,
+// which is read into the Score of the movies.
+func (m MovieModel) queryRanked(ctx context.Context, query string, args ...any) ([]*Movie, error) {
+	rows, err := m.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	movies := []*Movie{}
+	for rows.Next() {
+		var movie Movie
+		var score sql.NullFloat64
+
+		err = rows.Scan(append(movieScanDest(&movie, movieColumns), &score)...)
+		if err != nil {
+			return nil, err
+		}
+
+		movie.Score = score.Float64
+		movies = append(movies, &movie)
+	}
+
+	return movies, rows.Err()
+}
@@ -0,0 +1,86 @@
+//go:build integration
+// +build integration
+
+package data
+
+import (
+	"testing"
+
+	"github.com/djudju12/greenlight/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimilarAndRecommendedMovies(t *testing.T) {
+	g1, g2, g3, g4 := util.RandomString(10), util.RandomString(10), util.RandomString(10), util.RandomString(10)
+
+	target := Movie{Title: util.RandomString(8), Year: 2000, Runtime: 100, Genres: []string{g1, g2}}
+	sameGenres := Movie{Title: util.RandomString(8), Year: 2001, Runtime: 100, Genres: []string{g1, g2}}
+	oneGenre := Movie{Title: util.RandomString(8), Year: 2000, Runtime: 100, Genres: []string{g1}}
+	unrelated := Movie{Title: util.RandomString(8), Year: 2000, Runtime: 100, Genres: []string{g3}}
+	coRated := Movie{Title: util.RandomString(8), Year: 1950, Runtime: 100, Genres: []string{g4}}
+
+	for _, movie := range []*Movie{&target, &sameGenres, &oneGenre, &unrelated, &coRated} {
+		newMovie(t, movie)
+	}
+
+	critic := randomUser()
+	err := testModels.Users.Insert(&critic)
+	require.NoError(t, err)
+
+	for _, review := range []*Review{
+		{MovieID: target.ID, UserID: critic.ID, Score: 9},
+		{MovieID: coRated.ID, UserID: critic.ID, Score: 8},
+	} {
+		err = testModels.Reviews.Insert(review)
+		require.NoError(t, err)
+	}
+
+	similar, err := testModels.Movies.GetSimilar(target.ID, 10)
+	require.NoError(t, err)
+	require.Equal(t, []int64{sameGenres.ID, oneGenre.ID, coRated.ID}, movieIDsOf(similar))
+	require.InDelta(t, 0.6+0.2/1.2, similar[0].Score, 0.001)
+	require.InDelta(t, 0.2/11+0.2, similar[2].Score, 0.001)
+
+	similar, err = testModels.Movies.GetSimilar(unrelated.ID, 10)
+	require.NoError(t, err)
+	require.Empty(t, similar)
+
+	_, err = testModels.Movies.GetSimilar(target.ID+1000000, 10)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// the user liked the target and keeps the movie with one genre in a list
+	user := randomUser()
+	err = testModels.Users.Insert(&user)
+	require.NoError(t, err)
+
+	recommended, err := testModels.Movies.GetRecommendations(user.ID, 10)
+	require.NoError(t, err)
+	require.Empty(t, recommended)
+
+	err = testModels.Reviews.Insert(&Review{MovieID: target.ID, UserID: user.ID, Score: 9})
+	require.NoError(t, err)
+
+	list := &List{UserID: user.ID, Name: "watchlist", Visibility: ListPrivate}
+	err = testModels.Lists.Insert(list)
+	require.NoError(t, err)
+
+	err = testModels.Lists.AddItem(&ListItem{ListID: list.ID, MovieID: oneGenre.ID})
+	require.NoError(t, err)
+
+	recommended, err = testModels.Movies.GetRecommendations(user.ID, 10)
+	require.NoError(t, err)
+
+	// the movie with the same genres is the closest to what the user liked, and
+	// the critic, who liked the target too, liked the co-rated one
+	require.Equal(t, []int64{sameGenres.ID, coRated.ID}, movieIDsOf(recommended))
+	require.Greater(t, recommended[0].Score, recommended[1].Score)
+}
+
+func movieIDsOf(movies []*Movie) []int64 {
+	ids := make([]int64, len(movies))
+	for i, movie := range movies {
+		ids[i] = movie.ID
+	}
+
+	return ids
+}
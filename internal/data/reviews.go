@@ -0,0 +1,237 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/validator"
+)
+
+var (
+	SqlErrDupReview     = `pq: duplicate key value violates unique constraint "reviews_movie_id_user_id_key"`
+	SqlErrReviewMovieFK = `pq: insert or update on table "reviews" violates foreign key constraint "reviews_movie_id_fkey"`
+)
+
+var ErrDuplicateReview = errors.New("duplicate review")
+
+// Review is the score, from 1 to 10, an user gave to a movie. Each user can
+// review a movie once. The votes and the average score of the movie are kept
+// by the database as the reviews are posted, changed and deleted.
+type Review struct {
+	ID        int64     `json:"id"`
+	MovieID   int64     `json:"movie_id"`
+	UserID    int64     `json:"user_id"`
+	Author    string    `json:"author"`
+	Score     int32     `json:"score"`
+	Body      string    `json:"body,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   int32     `json:"version"`
+}
+
+var (
+	minReviewScore     = 1
+	maxReviewScore     = 10
+	maxBytesReviewBody = 5000
+)
+
+func ValidateReview(v *validator.Validator, review *Review) {
+	v.Check(review.Score >= int32(minReviewScore), "score", fmt.Sprintf("must be at least %d", minReviewScore))
+	v.Check(review.Score <= int32(maxReviewScore), "score", fmt.Sprintf("must be a maximum of %d", maxReviewScore))
+	v.Check(len(review.Body) <= maxBytesReviewBody, "body", fmt.Sprintf("must not be more than %d bytes long", maxBytesReviewBody))
+}
+
+type ReviewQuerier interface {
+	Insert(review *Review) error
+	Get(movieID, id int64) (*Review, error)
+	GetAll(movieID int64, f Filters) ([]*Review, Metadata, error)
+	Update(review *Review) error
+	Delete(movieID, id int64) error
+}
+
+type ReviewModel struct {
+	DB *sql.DB
+}
+
+var _ ReviewQuerier = (*ReviewModel)(nil)
+
+// Insert adds the review of the user. ErrRecordNotFound is returned when the
+// movie doesn't exist, and ErrDuplicateReview when the user already reviewed it.
+func (m ReviewModel) Insert(review *Review) error {
+	query := `
+	INSERT INTO reviews (movie_id, user_id, score, body)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at, updated_at, version`
+
+	args := []any{review.MovieID, review.UserID, review.Score, review.Body}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&review.ID,
+		&review.CreatedAt,
+		&review.UpdatedAt,
+		&review.Version,
+	)
+
+	if err != nil {
+		switch {
+		case err.Error() == SqlErrDupReview:
+			return ErrDuplicateReview
+		case err.Error() == SqlErrReviewMovieFK:
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m ReviewModel) Get(movieID, id int64) (*Review, error) {
+	if movieID < 1 || id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	SELECT r.id, r.movie_id, r.user_id, u.name, r.score, r.body, r.created_at, r.updated_at, r.version
+	FROM reviews r
+	JOIN users u ON u.id = r.user_id
+	WHERE r.id = $1 AND r.movie_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var review Review
+	err := m.DB.QueryRowContext(ctx, query, id, movieID).Scan(
+		&review.ID,
+		&review.MovieID,
+		&review.UserID,
+		&review.Author,
+		&review.Score,
+		&review.Body,
+		&review.CreatedAt,
+		&review.UpdatedAt,
+		&review.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &review, nil
+}
+
+func (m ReviewModel) GetAll(movieID int64, f Filters) ([]*Review, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), r.id, r.movie_id, r.user_id, u.name, r.score, r.body, r.created_at, r.updated_at, r.version
+	FROM reviews r
+	JOIN users u ON u.id = r.user_id
+	WHERE r.movie_id = $1
+	ORDER BY r.%s %s, r.id ASC
+	LIMIT $2 OFFSET $3`, f.sortColumn(), f.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID, f.limit(), f.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	reviews := []*Review{}
+	for rows.Next() {
+		var review Review
+
+		err = rows.Scan(
+			&totalRecords,
+			&review.ID,
+			&review.MovieID,
+			&review.UserID,
+			&review.Author,
+			&review.Score,
+			&review.Body,
+			&review.CreatedAt,
+			&review.UpdatedAt,
+			&review.Version,
+		)
+
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		reviews = append(reviews, &review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return reviews, calculateMetadata(totalRecords, f.Page, f.PageSize), nil
+}
+
+func (m ReviewModel) Update(review *Review) error {
+	query := `
+	UPDATE reviews
+	SET score = $1, body = $2, updated_at = NOW(), version = version + 1
+	WHERE id = $3 AND version = $4
+	RETURNING updated_at, version`
+
+	args := []any{review.Score, review.Body, review.ID, review.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&review.UpdatedAt, &review.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m ReviewModel) Delete(movieID, id int64) error {
+	if movieID < 1 || id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+	DELETE FROM reviews
+	WHERE id = $1 AND movie_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, movieID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
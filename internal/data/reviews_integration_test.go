@@ -0,0 +1,79 @@
+//go:build integration
+// +build integration
+
+package data
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMovieReviews(t *testing.T) {
+	movie := randomMovie()
+	newMovie(t, &movie)
+
+	// every user votes at the same time, none of the votes must be lost
+	scores := []int32{10, 9, 8, 7, 6, 5, 4, 3}
+	reviews := make([]*Review, len(scores))
+
+	for i, score := range scores {
+		user := randomUser()
+		err := testModels.Users.Insert(&user)
+		require.NoError(t, err)
+
+		reviews[i] = &Review{MovieID: movie.ID, UserID: user.ID, Score: score}
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(reviews))
+	for i, review := range reviews {
+		wg.Add(1)
+
+		go func(i int, review *Review) {
+			defer wg.Done()
+			errs[i] = testModels.Reviews.Insert(review)
+		}(i, review)
+	}
+
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	stored, err := testModels.Movies.Get(movie.ID)
+	require.NoError(t, err)
+	require.Equal(t, int32(len(scores)), stored.Votes)
+	require.Equal(t, 6.5, stored.Rating)
+
+	// the votes don't change the version of the movie
+	require.Equal(t, movie.Version, stored.Version)
+
+	err = testModels.Reviews.Insert(&Review{MovieID: movie.ID, UserID: reviews[0].UserID, Score: 1})
+	require.ErrorIs(t, err, ErrDuplicateReview)
+
+	review, err := testModels.Reviews.Get(movie.ID, reviews[0].ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, review.Author)
+
+	review.Score = 2
+	err = testModels.Reviews.Update(review)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), review.Version)
+
+	err = testModels.Reviews.Delete(movie.ID, reviews[1].ID)
+	require.NoError(t, err)
+
+	// 10 was changed to 2 and 9 was deleted
+	stored, err = testModels.Movies.Get(movie.ID)
+	require.NoError(t, err)
+	require.Equal(t, int32(len(scores)-1), stored.Votes)
+	require.Equal(t, 5.0, stored.Rating)
+
+	listed, metadata, err := testModels.Reviews.GetAll(movie.ID, Filters{Page: 1, PageSize: 3, Sort: "-score", SortSafelist: []string{"-score"}})
+	require.NoError(t, err)
+	require.Len(t, listed, 3)
+	require.Equal(t, len(scores)-1, metadata.TotalRecords)
+	require.Equal(t, int32(8), listed[0].Score)
+}
@@ -0,0 +1,65 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MovieRevision is a previous version of a movie. The revisions are recorded
+// by the database whenever a movie is updated.
+type MovieRevision struct {
+	Version   int32     `json:"version"`
+	Title     string    `json:"title"`
+	Year      int32     `json:"year"`
+	Runtime   Runtime   `json:"runtime"`
+	Genres    []string  `json:"genres"`
+	RevisedAt time.Time `json:"revised_at"`
+}
+
+// GetRevisions returns the revisions of each of the movies, newest first. Movies
+// that were never updated are not in the returned map.
+func (m MovieModel) GetRevisions(movieIDs []int64) (map[int64][]*MovieRevision, error) {
+	query := `
+	SELECT movie_id, version, title, year, runtime, genres, revised_at
+	FROM movie_revisions
+	WHERE movie_id = ANY($1)
+	ORDER BY movie_id, version DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.conn().QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	revisions := make(map[int64][]*MovieRevision)
+	for rows.Next() {
+		var (
+			movieID  int64
+			revision MovieRevision
+		)
+
+		err = rows.Scan(
+			&movieID,
+			&revision.Version,
+			&revision.Title,
+			&revision.Year,
+			&revision.Runtime,
+			pq.Array(&revision.Genres),
+			&revision.RevisedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		revisions[movieID] = append(revisions[movieID], &revision)
+	}
+
+	return revisions, rows.Err()
+}
@@ -3,39 +3,179 @@ package data
 import (
 	"errors"
 	"fmt"
+	"math"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
+// Runtime is the length of a movie in minutes.
 type Runtime int32
 
 var ErrInvalidRuntimeForat = errors.New("invalid runtime format")
 
-func (r Runtime) MarshalJSON() ([]byte, error) {
-	jsonValue := fmt.Sprintf("%d mins", r)
-	quotedJSONValue := strconv.Quote(jsonValue)
+// The formats a runtime can be written in. RuntimeMinutes is the format of the
+// JSON encoding of Runtime, and RuntimeInteger is a JSON number.
+const (
+	RuntimeMinutes = "minutes" // "102 mins"
+	RuntimeHours   = "hours"   // "1h 42m"
+	RuntimeISO8601 = "iso8601" // "PT1H42M"
+	RuntimeInteger = "integer" // 102
+)
+
+var RuntimeFormats = []string{RuntimeMinutes, RuntimeHours, RuntimeISO8601, RuntimeInteger}
+
+var (
+	runtimeIntegerRx = regexp.MustCompile(`^([0-9]+)$`)
+	// the minutes and hours formats, and the variations of them partner feeds
+	// send, like "102 min", "1h42m" or "2h"
+	runtimeUnitsRx = regexp.MustCompile(`(?i)^(?:([0-9]+) ?h)? ?(?:([0-9]+) ?(?:m|min|mins))?$`)
+	// only the hours and minutes of ISO 8601 durations, a runtime has no days
+	// nor seconds
+	runtimeISO8601Rx = regexp.MustCompile(`(?i)^PT(?:([0-9]+)H)?(?:([0-9]+)M)?$`)
+)
+
+// ParseRuntime reads a runtime written in any of the RuntimeFormats, or in a
+// variation of them, like "102 min" or "2h". A leading minus sign makes the
+// runtime negative, which ValidateMovie refuses, but it's kept so that every
+// Runtime can be written and read back.
+func ParseRuntime(s string) (Runtime, error) {
+	s = strings.TrimSpace(s)
+
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	var hours, minutes string
+
+	if m := runtimeIntegerRx.FindStringSubmatch(s); m != nil {
+		minutes = m[1]
+	} else if m := runtimeISO8601Rx.FindStringSubmatch(s); m != nil {
+		hours, minutes = m[1], m[2]
+	} else if m := runtimeUnitsRx.FindStringSubmatch(s); m != nil {
+		hours, minutes = m[1], m[2]
+	}
+
+	if hours == "" && minutes == "" {
+		return 0, ErrInvalidRuntimeForat
+	}
 
-	return []byte(quotedJSONValue), nil
+	var total int64
+	for _, part := range []struct {
+		value string
+		scale int64
+	}{{hours, 60}, {minutes, 1}} {
+		if part.value == "" {
+			continue
+		}
+
+		// the parts are parsed as 32 bits, so adding them can't overflow
+		n, err := strconv.ParseUint(part.value, 10, 32)
+		if err != nil {
+			return 0, ErrInvalidRuntimeForat
+		}
+
+		total += int64(n) * part.scale
+	}
+
+	if negative {
+		total = -total
+	}
+
+	if total < math.MinInt32 || total > math.MaxInt32 {
+		return 0, ErrInvalidRuntimeForat
+	}
+
+	return Runtime(total), nil
 }
 
-func (r *Runtime) UnmarshalJSON(jsonValue []byte) error {
-	unquotedJSONValue, err := strconv.Unquote(string(jsonValue))
-	if err != nil {
-		return ErrInvalidRuntimeForat
+// Text returns the runtime written in the format, one of the RuntimeFormats.
+// Unknown formats are written like RuntimeMinutes.
+func (r Runtime) Text(format string) string {
+	minutes := int64(r)
+
+	sign := ""
+	if minutes < 0 {
+		sign, minutes = "-", -minutes
 	}
 
-	parts := strings.Split(unquotedJSONValue, " ")
+	hours, minutes := minutes/60, minutes%60
 
-	if len(parts) != 2 || parts[1] != "mins" {
-		return ErrInvalidRuntimeForat
+	switch format {
+	case RuntimeInteger:
+		return strconv.FormatInt(int64(r), 10)
+	case RuntimeHours:
+		switch {
+		case hours == 0:
+			return fmt.Sprintf("%s%dm", sign, minutes)
+		case minutes == 0:
+			return fmt.Sprintf("%s%dh", sign, hours)
+		default:
+			return fmt.Sprintf("%s%dh %dm", sign, hours, minutes)
+		}
+	case RuntimeISO8601:
+		switch {
+		case hours == 0:
+			return fmt.Sprintf("%sPT%dM", sign, minutes)
+		case minutes == 0:
+			return fmt.Sprintf("%sPT%dH", sign, hours)
+		default:
+			return fmt.Sprintf("%sPT%dH%dM", sign, hours, minutes)
+		}
+	default:
+		return fmt.Sprintf("%d mins", r)
+	}
+}
+
+// In returns the runtime to be encoded to JSON in the format.
+func (r Runtime) In(format string) FormattedRuntime {
+	return FormattedRuntime{Runtime: r, Format: format}
+}
+
+func (r Runtime) MarshalJSON() ([]byte, error) {
+	return r.In(RuntimeMinutes).MarshalJSON()
+}
+
+// UnmarshalJSON reads a runtime from a JSON number of minutes or from a string
+// in any of the formats ParseRuntime reads.
+func (r *Runtime) UnmarshalJSON(jsonValue []byte) error {
+	if !strings.HasPrefix(string(jsonValue), `"`) {
+		i, err := strconv.ParseInt(string(jsonValue), 10, 32)
+		if err != nil {
+			return ErrInvalidRuntimeForat
+		}
+
+		*r = Runtime(i)
+		return nil
 	}
 
-	i, err := strconv.ParseInt(parts[0], 10, 32)
+	unquotedJSONValue, err := strconv.Unquote(string(jsonValue))
 	if err != nil {
 		return ErrInvalidRuntimeForat
 	}
 
-	*r = Runtime(i)
+	runtime, err := ParseRuntime(unquotedJSONValue)
+	if err != nil {
+		return err
+	}
+
+	*r = runtime
 
 	return nil
 }
+
+// FormattedRuntime is a runtime that is encoded to JSON in Format instead of
+// the format of Runtime.
+type FormattedRuntime struct {
+	Runtime Runtime
+	Format  string
+}
+
+func (r FormattedRuntime) MarshalJSON() ([]byte, error) {
+	text := r.Runtime.Text(r.Format)
+
+	if r.Format == RuntimeInteger {
+		return []byte(text), nil
+	}
+
+	return []byte(strconv.Quote(text)), nil
+}
@@ -0,0 +1,199 @@
+package data
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRuntime(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected Runtime
+	}{
+		{"102", 102},
+		{"102 mins", 102},
+		{"102 min", 102},
+		{"102min", 102},
+		{"102m", 102},
+		{"1h 42m", 102},
+		{"1h42m", 102},
+		{"1H 42MIN", 102},
+		{"2h", 120},
+		{"PT1H42M", 102},
+		{"pt1h42m", 102},
+		{"PT102M", 102},
+		{"PT2H", 120},
+		{" 0 mins ", 0},
+		{"-5 mins", -5},
+		{"-PT1H", -60},
+		{"35791394h 7m", math.MaxInt32},
+		{"-35791394h 8m", math.MinInt32},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			runtime, err := ParseRuntime(tc.input)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, runtime)
+		})
+	}
+}
+
+func TestParseRuntimeInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"mins",
+		"h",
+		"PT",
+		"P1D",
+		"PT1H42M30S",
+		"1.5h",
+		"42m 1h",
+		"102 minutes",
+		"--5",
+		"+5",
+		"0x66",
+		"2147483648",
+		"35791394h 8m",
+		"99999999999h",
+	} {
+		t.Run(input, func(t *testing.T) {
+			_, err := ParseRuntime(input)
+			require.ErrorIs(t, err, ErrInvalidRuntimeForat)
+		})
+	}
+}
+
+func TestRuntimeText(t *testing.T) {
+	testCases := []struct {
+		runtime  Runtime
+		format   string
+		expected string
+	}{
+		{102, RuntimeMinutes, "102 mins"},
+		{102, RuntimeHours, "1h 42m"},
+		{102, RuntimeISO8601, "PT1H42M"},
+		{102, RuntimeInteger, "102"},
+		{42, RuntimeHours, "42m"},
+		{120, RuntimeHours, "2h"},
+		{0, RuntimeHours, "0m"},
+		{42, RuntimeISO8601, "PT42M"},
+		{120, RuntimeISO8601, "PT2H"},
+		{0, RuntimeISO8601, "PT0M"},
+		{-102, RuntimeHours, "-1h 42m"},
+		{-102, RuntimeISO8601, "-PT1H42M"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.runtime.Text(tc.format))
+		})
+	}
+}
+
+func TestRuntimeJSON(t *testing.T) {
+	var movie struct {
+		Runtime Runtime `json:"runtime"`
+	}
+
+	for _, js := range []string{`{"runtime": 102}`, `{"runtime": "102 mins"}`, `{"runtime": "1h 42m"}`, `{"runtime": "PT1H42M"}`} {
+		err := json.Unmarshal([]byte(js), &movie)
+		require.NoError(t, err)
+		require.Equal(t, Runtime(102), movie.Runtime)
+	}
+
+	for _, js := range []string{`{"runtime": 1.5}`, `{"runtime": "soon"}`, `{"runtime": true}`, `{"runtime": null}`} {
+		err := json.Unmarshal([]byte(js), &movie)
+		require.Error(t, err, js)
+	}
+
+	encoded, err := json.Marshal(movie)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"runtime": "102 mins"}`, string(encoded))
+
+	encoded, err = json.Marshal(Runtime(102).In(RuntimeInteger))
+	require.NoError(t, err)
+	require.Equal(t, `102`, string(encoded))
+}
+
+// every runtime written in a format is read back as the same runtime, whether
+// it's parsed or decoded from its JSON encoding
+func TestRuntimeRoundTrip(t *testing.T) {
+	for _, format := range RuntimeFormats {
+		t.Run(format, func(t *testing.T) {
+			roundTrip := func(n int32) bool {
+				runtime := Runtime(n)
+
+				parsed, err := ParseRuntime(runtime.Text(format))
+				if err != nil || parsed != runtime {
+					return false
+				}
+
+				js, err := json.Marshal(runtime.In(format))
+				if err != nil {
+					return false
+				}
+
+				var decoded Runtime
+				err = json.Unmarshal(js, &decoded)
+				return err == nil && decoded == runtime
+			}
+
+			err := quick.Check(roundTrip, &quick.Config{MaxCount: 2000})
+			require.NoError(t, err)
+
+			for _, n := range []int32{0, 1, 59, 60, 61, math.MaxInt32, math.MinInt32} {
+				require.True(t, roundTrip(n), "%d", n)
+			}
+		})
+	}
+}
+
+// any text that parses is a runtime that is written back in every format in a
+// way that parses to the same runtime
+func FuzzParseRuntime(f *testing.F) {
+	for _, seed := range []string{"102", "102 mins", "102 min", "1h 42m", "PT1H42M", "-PT2H", "0m", "2147483647", "ten"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		runtime, err := ParseRuntime(input)
+		if err != nil {
+			require.ErrorIs(t, err, ErrInvalidRuntimeForat)
+			return
+		}
+
+		for _, format := range RuntimeFormats {
+			parsed, err := ParseRuntime(runtime.Text(format))
+			require.NoError(t, err, format)
+			require.Equal(t, runtime, parsed, format)
+		}
+	})
+}
+
+// decoding never panics, and whatever decodes is encoded to a value that
+// decodes to the same runtime
+func FuzzRuntimeUnmarshalJSON(f *testing.F) {
+	for _, seed := range []string{`102`, `"102 mins"`, `"1h 42m"`, `"PT1H42M"`, `-1`, `"1h"`, `1e3`, `"`} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, js []byte) {
+		var runtime Runtime
+		if err := runtime.UnmarshalJSON(js); err != nil {
+			return
+		}
+
+		encoded, err := json.Marshal(runtime)
+		require.NoError(t, err)
+
+		var decoded Runtime
+		err = json.Unmarshal(encoded, &decoded)
+		require.NoError(t, err)
+		require.Equal(t, runtime, decoded)
+	})
+}
@@ -0,0 +1,159 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type YearCount struct {
+	Year  int32 `json:"year"`
+	Count int   `json:"count"`
+}
+
+type CatalogStats struct {
+	TotalMovies     int          `json:"total_movies"`
+	ByGenre         []FacetCount `json:"by_genre"`
+	ByYear          []YearCount  `json:"by_year"`
+	AverageRuntime  float64      `json:"average_runtime"`
+	MedianRuntime   float64      `json:"median_runtime"`
+	RecentAdditions []*Movie     `json:"recent_additions"`
+	GeneratedAt     time.Time    `json:"generated_at"`
+}
+
+var recentAdditionsLimit = 5
+
+// GetStats computes the catalog statistics. All the aggregation is done by
+// PostgreSQL, the queries run inside a read only transaction (unless the model
+// is already in one) so every number comes from the same snapshot of the movies
+// table.
+func (m MovieModel) GetStats() (*CatalogStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx := m.conn()
+	if m.tx == nil {
+		readTx, err := m.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead})
+		if err != nil {
+			return nil, err
+		}
+
+		defer readTx.Rollback()
+		tx = readTx
+	}
+
+	stats := CatalogStats{
+		ByGenre:         []FacetCount{},
+		ByYear:          []YearCount{},
+		RecentAdditions: []*Movie{},
+	}
+
+	// percentile_cont(0.5) interpolates between the two middle values when the
+	// number of movies is even, which is the usual definition of the median
+	query := `
+	SELECT count(*), now(),
+		COALESCE(avg(runtime), 0),
+		COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY runtime), 0)
+	FROM movies`
+
+	err := tx.QueryRowContext(ctx, query).Scan(
+		&stats.TotalMovies,
+		&stats.GeneratedAt,
+		&stats.AverageRuntime,
+		&stats.MedianRuntime,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+	SELECT genre, count(*)
+	FROM movies, unnest(genres) AS genre
+	GROUP BY genre
+	ORDER BY count(*) DESC, genre`
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var count FacetCount
+		if err = rows.Scan(&count.Value, &count.Count); err != nil {
+			return nil, err
+		}
+
+		stats.ByGenre = append(stats.ByGenre, count)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `
+	SELECT year, count(*)
+	FROM movies
+	GROUP BY year
+	ORDER BY year`
+
+	rows, err = tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var count YearCount
+		if err = rows.Scan(&count.Year, &count.Count); err != nil {
+			return nil, err
+		}
+
+		stats.ByYear = append(stats.ByYear, count)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `
+	SELECT id, created_at, title, year, runtime, genres, version
+	FROM movies
+	ORDER BY created_at DESC, id DESC
+	LIMIT $1`
+
+	rows, err = tx.QueryContext(ctx, query, recentAdditionsLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var movie Movie
+		err = rows.Scan(
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		stats.RecentAdditions = append(stats.RecentAdditions, &movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
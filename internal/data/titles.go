@@ -0,0 +1,139 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/validator"
+	"github.com/lib/pq"
+)
+
+var (
+	SqlErrMovieTitleFK = `pq: insert or update on table "movie_titles" violates foreign key constraint "movie_titles_movie_id_fkey"`
+)
+
+// MovieTitle is the title of a movie in another language. Language is a BCP 47
+// tag, like "pt-BR".
+type MovieTitle struct {
+	MovieID  int64  `json:"-"`
+	Language string `json:"language"`
+	Title    string `json:"title"`
+}
+
+// LanguageTagRx matches the well-formed BCP 47 tags made of a language subtag
+// followed by optional script, region and variant subtags.
+var LanguageTagRx = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{1,8})*$`)
+
+func ValidateMovieTitle(v *validator.Validator, title *MovieTitle) {
+	v.Check(title.Language != "", "language", "must be provided")
+	v.Check(len(title.Language) <= 35, "language", "must not be more than 35 bytes long")
+	v.Check(validator.Matches(title.Language, LanguageTagRx), "language", "must be a valid BCP 47 language tag")
+
+	v.Check(title.Title != "", "title", "must be provided")
+	v.Check(len(title.Title) <= maxBytesTitle, "title", fmt.Sprintf("must not be more than %d bytes long", maxBytesTitle))
+}
+
+// CanonicalLanguageTag returns the tag with the conventional casing of BCP 47:
+// lowercase language, titlecase script and uppercase region, like "zh-Hant-TW".
+// Tags differing only in case are the same language.
+func CanonicalLanguageTag(tag string) string {
+	subtags := strings.Split(strings.ToLower(tag), "-")
+
+	for i := 1; i < len(subtags); i++ {
+		switch len(subtags[i]) {
+		case 2:
+			subtags[i] = strings.ToUpper(subtags[i])
+		case 4:
+			subtags[i] = strings.ToUpper(subtags[i][:1]) + subtags[i][1:]
+		}
+	}
+
+	return strings.Join(subtags, "-")
+}
+
+// GetTitles returns the localized titles of each of the movies, sorted by
+// language. Movies without localized titles are not in the returned map.
+func (m MovieModel) GetTitles(movieIDs []int64) (map[int64][]*MovieTitle, error) {
+	query := `
+	SELECT movie_id, language, title
+	FROM movie_titles
+	WHERE movie_id = ANY($1)
+	ORDER BY movie_id, language`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.conn().QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	titles := make(map[int64][]*MovieTitle)
+	for rows.Next() {
+		var title MovieTitle
+
+		err = rows.Scan(&title.MovieID, &title.Language, &title.Title)
+		if err != nil {
+			return nil, err
+		}
+
+		titles[title.MovieID] = append(titles[title.MovieID], &title)
+	}
+
+	return titles, rows.Err()
+}
+
+// SetTitle adds the localized title of the movie, replacing the one it had in
+// the same language. ErrRecordNotFound is returned when the movie doesn't exist.
+func (m MovieModel) SetTitle(title *MovieTitle) error {
+	query := `
+	INSERT INTO movie_titles (movie_id, language, title)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (movie_id, language) DO UPDATE
+	SET title = EXCLUDED.title`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := m.conn().ExecContext(ctx, query, title.MovieID, title.Language, title.Title)
+	if err != nil {
+		switch {
+		case err.Error() == SqlErrMovieTitleFK:
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m MovieModel) DeleteTitle(movieID int64, language string) error {
+	query := `
+	DELETE FROM movie_titles
+	WHERE movie_id = $1 AND language = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := m.conn().ExecContext(ctx, query, movieID, language)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
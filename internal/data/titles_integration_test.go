@@ -0,0 +1,58 @@
+//go:build integration
+// +build integration
+
+package data
+
+import (
+	"testing"
+
+	"github.com/djudju12/greenlight/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMovieTitles(t *testing.T) {
+	movie := randomMovie()
+	newMovie(t, &movie)
+
+	word := util.RandomString(12)
+
+	err := testModels.Movies.SetTitle(&MovieTitle{MovieID: movie.ID, Language: "de", Title: "Der " + word})
+	require.NoError(t, err)
+
+	err = testModels.Movies.SetTitle(&MovieTitle{MovieID: movie.ID, Language: "en", Title: "Running " + word})
+	require.NoError(t, err)
+
+	// a second title in the same language replaces the first one
+	err = testModels.Movies.SetTitle(&MovieTitle{MovieID: movie.ID, Language: "de", Title: "Die " + word})
+	require.NoError(t, err)
+
+	titles, err := testModels.Movies.GetTitles([]int64{movie.ID})
+	require.NoError(t, err)
+	require.Len(t, titles[movie.ID], 2)
+	require.Equal(t, "de", titles[movie.ID][0].Language)
+	require.Equal(t, "Die "+word, titles[movie.ID][0].Title)
+
+	f := Filters{Page: 1, PageSize: 5, Sort: "relevance", SortSafelist: []string{"relevance"}}
+
+	// the localized titles are searched, stemmed in their language
+	for _, search := range []string{word, "run " + word} {
+		movies, _, err := testModels.Movies.GetAll(MovieFilters{SearchMode: SearchFullText, Title: search}, f)
+		require.NoError(t, err)
+		require.Len(t, movies, 1)
+		require.Equal(t, movie.ID, movies[0].ID)
+		require.NotZero(t, movies[0].Score)
+	}
+
+	movies, _, err := testModels.Movies.GetAll(MovieFilters{SearchMode: SearchFuzzy, Title: word}, f)
+	require.NoError(t, err)
+	require.Len(t, movies, 1)
+
+	err = testModels.Movies.SetTitle(&MovieTitle{MovieID: movie.ID + 1000000, Language: "de", Title: word})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	err = testModels.Movies.DeleteTitle(movie.ID, "en")
+	require.NoError(t, err)
+
+	err = testModels.Movies.DeleteTitle(movie.ID, "en")
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
@@ -0,0 +1,430 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/validator"
+	"github.com/lib/pq"
+)
+
+// WebhookEvents are the events a webhook can be registered for.
+var WebhookEvents = []string{"movie.created", "movie.updated", "movie.deleted", "user.activated"}
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is an endpoint the events it's registered for are sent to. The
+// payloads are signed with its secret.
+type Webhook struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"-"`
+	Version   int32     `json:"version"`
+}
+
+var (
+	maxBytesWebhookURL    = 2048
+	minBytesWebhookSecret = 16
+	maxBytesWebhookSecret = 255
+)
+
+func ValidateWebhook(v *validator.Validator, webhook *Webhook) {
+	v.Check(webhook.URL != "", "url", "must be provided")
+	v.Check(len(webhook.URL) <= maxBytesWebhookURL, "url", fmt.Sprintf("must not be more than %d bytes long", maxBytesWebhookURL))
+
+	u, err := url.Parse(webhook.URL)
+	v.Check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+		"url", "must be an absolute http or https URL")
+
+	v.Check(len(webhook.Events) > 0, "events", "must contain at least one event")
+	v.Check(validator.Unique(webhook.Events), "events", "must not contain duplicate values")
+	for _, event := range webhook.Events {
+		v.Check(validator.In(event, WebhookEvents...), "events", fmt.Sprintf("must only contain %v", WebhookEvents))
+	}
+
+	v.Check(len(webhook.Secret) >= minBytesWebhookSecret, "secret", fmt.Sprintf("must be at least %d bytes long", minBytesWebhookSecret))
+	v.Check(len(webhook.Secret) <= maxBytesWebhookSecret, "secret", fmt.Sprintf("must not be more than %d bytes long", maxBytesWebhookSecret))
+}
+
+// WebhookDelivery is an event queued to be sent to a webhook, along with the
+// outcome of the attempts to send it. The deliveries of movie events have no
+// payload until they are first sent, when it's built from the event with the
+// MovieEventID. The URL and Secret of the webhook are only filled on the
+// deliveries returned by ClaimDeliveries.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int64           `json:"webhook_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+
+	MovieEventID int64  `json:"-"`
+	URL          string `json:"-"`
+	Secret       string `json:"-"`
+}
+
+type WebhookQuerier interface {
+	Insert(webhook *Webhook) error
+	Get(id int64) (*Webhook, error)
+	GetAll() ([]*Webhook, error)
+	Delete(id int64) error
+	GetDeliveries(webhookID int64, f Filters) ([]*WebhookDelivery, Metadata, error)
+	GetDelivery(webhookID, id int64) (*WebhookDelivery, error)
+	Redeliver(delivery *WebhookDelivery) error
+	ClaimDeliveries(limit int, lease time.Duration) ([]*WebhookDelivery, error)
+	CompleteDelivery(delivery *WebhookDelivery) error
+}
+
+type WebhookModel struct {
+	DB *sql.DB
+}
+
+var _ WebhookQuerier = (*WebhookModel)(nil)
+
+func (m WebhookModel) Insert(webhook *Webhook) error {
+	query := `
+	INSERT INTO webhooks (url, events, secret)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at, version`
+
+	args := []any{webhook.URL, pq.Array(webhook.Events), webhook.Secret}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&webhook.ID, &webhook.CreatedAt, &webhook.Version)
+}
+
+func (m WebhookModel) Get(id int64) (*Webhook, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	SELECT id, created_at, url, events, secret, version
+	FROM webhooks
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var webhook Webhook
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&webhook.ID,
+		&webhook.CreatedAt,
+		&webhook.URL,
+		pq.Array(&webhook.Events),
+		&webhook.Secret,
+		&webhook.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &webhook, nil
+}
+
+func (m WebhookModel) GetAll() ([]*Webhook, error) {
+	query := `
+	SELECT id, created_at, url, events, secret, version
+	FROM webhooks
+	ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	webhooks := []*Webhook{}
+	for rows.Next() {
+		var webhook Webhook
+
+		err = rows.Scan(
+			&webhook.ID,
+			&webhook.CreatedAt,
+			&webhook.URL,
+			pq.Array(&webhook.Events),
+			&webhook.Secret,
+			&webhook.Version,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, &webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// Delete removes the webhook along with its deliveries.
+func (m WebhookModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+	DELETE FROM webhooks
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+const webhookDeliveryColumns = `d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at,
+	d.last_status_code, d.last_error, d.created_at, d.delivered_at, COALESCE(d.movie_event_id, 0)`
+
+func webhookDeliveryScanDest(delivery *WebhookDelivery, lastError *sql.NullString) []any {
+	return []any{
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.Event,
+		(*[]byte)(&delivery.Payload),
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&delivery.LastStatusCode,
+		lastError,
+		&delivery.CreatedAt,
+		&delivery.DeliveredAt,
+		&delivery.MovieEventID,
+	}
+}
+
+// GetDeliveries returns the deliveries of the webhook, newest first.
+func (m WebhookModel) GetDeliveries(webhookID int64, f Filters) ([]*WebhookDelivery, Metadata, error) {
+	query := `
+	SELECT count(*) OVER(), ` + webhookDeliveryColumns + `
+	FROM webhook_deliveries d
+	WHERE d.webhook_id = $1
+	ORDER BY d.id DESC
+	LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, webhookID, f.limit(), f.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	deliveries := []*WebhookDelivery{}
+
+	for rows.Next() {
+		var (
+			delivery  WebhookDelivery
+			lastError sql.NullString
+		)
+
+		dest := append([]any{&totalRecords}, webhookDeliveryScanDest(&delivery, &lastError)...)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, Metadata{}, err
+		}
+
+		delivery.LastError = lastError.String
+		deliveries = append(deliveries, delivery.withoutSchedule())
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return deliveries, calculateMetadata(totalRecords, f.Page, f.PageSize), nil
+}
+
+func (m WebhookModel) GetDelivery(webhookID, id int64) (*WebhookDelivery, error) {
+	if webhookID < 1 || id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	SELECT ` + webhookDeliveryColumns + `
+	FROM webhook_deliveries d
+	WHERE d.webhook_id = $1 AND d.id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var (
+		delivery  WebhookDelivery
+		lastError sql.NullString
+	)
+
+	err := m.DB.QueryRowContext(ctx, query, webhookID, id).Scan(webhookDeliveryScanDest(&delivery, &lastError)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	delivery.LastError = lastError.String
+	return delivery.withoutSchedule(), nil
+}
+
+// withoutSchedule drops the time of the next attempt of a delivery that is no
+// longer pending, where it means nothing.
+func (d *WebhookDelivery) withoutSchedule() *WebhookDelivery {
+	if d.Status != DeliveryPending {
+		d.NextAttemptAt = nil
+	}
+
+	return d
+}
+
+// Redeliver queues the payload of the delivery again, to be sent right away as
+// a new delivery. The delivery is replaced with the new one.
+func (m WebhookModel) Redeliver(delivery *WebhookDelivery) error {
+	query := `
+	INSERT INTO webhook_deliveries AS d (webhook_id, event, payload, movie_event_id)
+	SELECT s.webhook_id, s.event, s.payload, s.movie_event_id
+	FROM webhook_deliveries s
+	WHERE s.webhook_id = $1 AND s.id = $2
+	RETURNING ` + webhookDeliveryColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var lastError sql.NullString
+
+	err := m.DB.QueryRowContext(ctx, query, delivery.WebhookID, delivery.ID).
+		Scan(webhookDeliveryScanDest(delivery, &lastError)...)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	delivery.LastError = lastError.String
+	return nil
+}
+
+// ClaimDeliveries takes up to limit pending deliveries that are due, along with
+// the URL and secret of their webhooks, and counts the attempt. They are not
+// due again until the lease is over, so a worker that stops before completing
+// them doesn't lose them, and other workers don't take them meanwhile.
+func (m WebhookModel) ClaimDeliveries(limit int, lease time.Duration) ([]*WebhookDelivery, error) {
+	query := `
+	UPDATE webhook_deliveries d
+	SET attempts = d.attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2)
+	FROM webhooks w
+	WHERE w.id = d.webhook_id AND d.id IN (
+		SELECT id
+		FROM webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= NOW()
+		ORDER BY next_attempt_at, id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING ` + webhookDeliveryColumns + `, w.url, w.secret`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	deliveries := []*WebhookDelivery{}
+	for rows.Next() {
+		var (
+			delivery  WebhookDelivery
+			lastError sql.NullString
+		)
+
+		dest := append(webhookDeliveryScanDest(&delivery, &lastError), &delivery.URL, &delivery.Secret)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		delivery.LastError = lastError.String
+		deliveries = append(deliveries, &delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// CompleteDelivery stores the outcome of an attempt to send the delivery: its
+// status, the time of the next attempt, if it's still pending, and the last
+// response status code or error. The payload is stored too the first time, so
+// every attempt sends the same one.
+func (m WebhookModel) CompleteDelivery(delivery *WebhookDelivery) error {
+	query := `
+	UPDATE webhook_deliveries
+	SET status = $2, next_attempt_at = COALESCE($3, next_attempt_at), last_status_code = $4,
+		last_error = NULLIF($5, ''), delivered_at = $6, payload = COALESCE(payload, $7)
+	WHERE id = $1`
+
+	args := []any{
+		delivery.ID,
+		delivery.Status,
+		delivery.NextAttemptAt,
+		delivery.LastStatusCode,
+		delivery.LastError,
+		delivery.DeliveredAt,
+		[]byte(delivery.Payload),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	return err
+}
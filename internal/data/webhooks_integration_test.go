@@ -0,0 +1,112 @@
+//go:build integration
+// +build integration
+
+package data
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/djudju12/greenlight/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDeliveries(t *testing.T) {
+	webhook := &Webhook{
+		URL:    "https://example.com/" + util.RandomString(10),
+		Events: []string{"movie.created"},
+		Secret: util.RandomString(32),
+	}
+
+	err := testModels.Webhooks.Insert(webhook)
+	require.NoError(t, err)
+
+	stored, err := testModels.Webhooks.Get(webhook.ID)
+	require.NoError(t, err)
+	require.Equal(t, webhook.Secret, stored.Secret)
+	require.Equal(t, webhook.Events, stored.Events)
+
+	// creating a movie queues a delivery, updating it doesn't
+	movie := randomMovie()
+	newMovie(t, &movie)
+
+	err = testModels.Movies.Update(&movie)
+	require.NoError(t, err)
+
+	deliveries, metadata, err := testModels.Webhooks.GetDeliveries(webhook.ID, Filters{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, 1, metadata.TotalRecords)
+
+	delivery := deliveries[0]
+	require.Equal(t, "movie.created", delivery.Event)
+	require.Equal(t, DeliveryPending, delivery.Status)
+
+	// the payload is built from the movie event when the delivery is first sent
+	require.Nil(t, delivery.Payload)
+
+	event, err := testModels.MovieEvents.Get(delivery.MovieEventID)
+	require.NoError(t, err)
+	require.Equal(t, MovieCreated, event.Type)
+	require.Equal(t, movie.ID, event.Movie.ID)
+	require.Equal(t, movie.Runtime, event.Movie.Runtime)
+
+	claimed, err := testModels.Webhooks.ClaimDeliveries(1000, time.Minute)
+	require.NoError(t, err)
+
+	var ours *WebhookDelivery
+	for _, d := range claimed {
+		if d.ID == delivery.ID {
+			ours = d
+		}
+	}
+
+	require.NotNil(t, ours)
+	require.Equal(t, 1, ours.Attempts)
+	require.Equal(t, webhook.URL, ours.URL)
+	require.Equal(t, webhook.Secret, ours.Secret)
+
+	// a claimed delivery is not due until the lease is over
+	claimed, err = testModels.Webhooks.ClaimDeliveries(1000, time.Minute)
+	require.NoError(t, err)
+	for _, d := range claimed {
+		require.NotEqual(t, delivery.ID, d.ID)
+	}
+
+	status := http.StatusOK
+	now := time.Now().Truncate(time.Second)
+	ours.Status = DeliverySucceeded
+	ours.LastStatusCode = &status
+	ours.DeliveredAt = &now
+	ours.Payload = json.RawMessage(`{"event":"movie.created"}`)
+
+	err = testModels.Webhooks.CompleteDelivery(ours)
+	require.NoError(t, err)
+
+	completed, err := testModels.Webhooks.GetDelivery(webhook.ID, delivery.ID)
+	require.NoError(t, err)
+	require.Equal(t, DeliverySucceeded, completed.Status)
+	require.Equal(t, status, *completed.LastStatusCode)
+	require.Nil(t, completed.NextAttemptAt)
+	require.WithinDuration(t, now, *completed.DeliveredAt, time.Second)
+	require.JSONEq(t, string(ours.Payload), string(completed.Payload))
+
+	redelivery := &WebhookDelivery{ID: delivery.ID, WebhookID: webhook.ID}
+	err = testModels.Webhooks.Redeliver(redelivery)
+	require.NoError(t, err)
+	require.NotEqual(t, delivery.ID, redelivery.ID)
+	require.Equal(t, DeliveryPending, redelivery.Status)
+	require.JSONEq(t, string(ours.Payload), string(redelivery.Payload))
+	require.Equal(t, delivery.MovieEventID, redelivery.MovieEventID)
+
+	err = testModels.Webhooks.Redeliver(&WebhookDelivery{ID: delivery.ID, WebhookID: webhook.ID + 1})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	err = testModels.Webhooks.Delete(webhook.ID)
+	require.NoError(t, err)
+
+	_, err = testModels.Webhooks.GetDelivery(webhook.ID, delivery.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
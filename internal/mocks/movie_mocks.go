@@ -52,6 +52,78 @@ func (mr *MockMovieQuerierMockRecorder) Delete(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockMovieQuerier)(nil).Delete), arg0)
 }
 
+// DeleteExternalID mocks base method.
+func (m *MockMovieQuerier) DeleteExternalID(arg0 int64, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExternalID", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteExternalID indicates an expected call of DeleteExternalID.
+func (mr *MockMovieQuerierMockRecorder) DeleteExternalID(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExternalID", reflect.TypeOf((*MockMovieQuerier)(nil).DeleteExternalID), arg0, arg1)
+}
+
+// DeletePoster mocks base method.
+func (m *MockMovieQuerier) DeletePoster(arg0 int64) (*data.Poster, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePoster", arg0)
+	ret0, _ := ret[0].(*data.Poster)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeletePoster indicates an expected call of DeletePoster.
+func (mr *MockMovieQuerierMockRecorder) DeletePoster(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePoster", reflect.TypeOf((*MockMovieQuerier)(nil).DeletePoster), arg0)
+}
+
+// DeleteTitle mocks base method.
+func (m *MockMovieQuerier) DeleteTitle(arg0 int64, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTitle", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTitle indicates an expected call of DeleteTitle.
+func (mr *MockMovieQuerierMockRecorder) DeleteTitle(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTitle", reflect.TypeOf((*MockMovieQuerier)(nil).DeleteTitle), arg0, arg1)
+}
+
+// DeleteVersion mocks base method.
+func (m *MockMovieQuerier) DeleteVersion(arg0 int64, arg1 int32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteVersion", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteVersion indicates an expected call of DeleteVersion.
+func (mr *MockMovieQuerierMockRecorder) DeleteVersion(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVersion", reflect.TypeOf((*MockMovieQuerier)(nil).DeleteVersion), arg0, arg1)
+}
+
+// FindDuplicates mocks base method.
+func (m *MockMovieQuerier) FindDuplicates(arg0 *data.Movie) ([]*data.Movie, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDuplicates", arg0)
+	ret0, _ := ret[0].([]*data.Movie)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindDuplicates indicates an expected call of FindDuplicates.
+func (mr *MockMovieQuerierMockRecorder) FindDuplicates(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDuplicates", reflect.TypeOf((*MockMovieQuerier)(nil).FindDuplicates), arg0)
+}
+
 // Get mocks base method.
 func (m *MockMovieQuerier) Get(arg0 int64) (*data.Movie, error) {
 	m.ctrl.T.Helper()
@@ -68,9 +140,9 @@ func (mr *MockMovieQuerierMockRecorder) Get(arg0 any) *gomock.Call {
 }
 
 // GetAll mocks base method.
-func (m *MockMovieQuerier) GetAll(arg0 string, arg1 []string, arg2 data.Filters) ([]*data.Movie, data.Metadata, error) {
+func (m *MockMovieQuerier) GetAll(arg0 data.MovieFilters, arg1 data.Filters) ([]*data.Movie, data.Metadata, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "GetAll", arg0, arg1)
 	ret0, _ := ret[0].([]*data.Movie)
 	ret1, _ := ret[1].(data.Metadata)
 	ret2, _ := ret[2].(error)
@@ -78,9 +150,158 @@ func (m *MockMovieQuerier) GetAll(arg0 string, arg1 []string, arg2 data.Filters)
 }
 
 // GetAll indicates an expected call of GetAll.
-func (mr *MockMovieQuerierMockRecorder) GetAll(arg0, arg1, arg2 any) *gomock.Call {
+func (mr *MockMovieQuerierMockRecorder) GetAll(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockMovieQuerier)(nil).GetAll), arg0, arg1)
+}
+
+// GetExternalIDs mocks base method.
+func (m *MockMovieQuerier) GetExternalIDs(arg0 []int64) (map[int64][]*data.ExternalID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExternalIDs", arg0)
+	ret0, _ := ret[0].(map[int64][]*data.ExternalID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExternalIDs indicates an expected call of GetExternalIDs.
+func (mr *MockMovieQuerierMockRecorder) GetExternalIDs(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExternalIDs", reflect.TypeOf((*MockMovieQuerier)(nil).GetExternalIDs), arg0)
+}
+
+// GetFacets mocks base method.
+func (m *MockMovieQuerier) GetFacets(arg0 data.MovieFilters, arg1 []string) (data.Facets, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFacets", arg0, arg1)
+	ret0, _ := ret[0].(data.Facets)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFacets indicates an expected call of GetFacets.
+func (mr *MockMovieQuerierMockRecorder) GetFacets(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFacets", reflect.TypeOf((*MockMovieQuerier)(nil).GetFacets), arg0, arg1)
+}
+
+// GetFields mocks base method.
+func (m *MockMovieQuerier) GetFields(arg0 int64, arg1 []string) (*data.Movie, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFields", arg0, arg1)
+	ret0, _ := ret[0].(*data.Movie)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFields indicates an expected call of GetFields.
+func (mr *MockMovieQuerierMockRecorder) GetFields(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFields", reflect.TypeOf((*MockMovieQuerier)(nil).GetFields), arg0, arg1)
+}
+
+// GetPoster mocks base method.
+func (m *MockMovieQuerier) GetPoster(arg0 int64) (*data.Poster, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPoster", arg0)
+	ret0, _ := ret[0].(*data.Poster)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPoster indicates an expected call of GetPoster.
+func (mr *MockMovieQuerierMockRecorder) GetPoster(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPoster", reflect.TypeOf((*MockMovieQuerier)(nil).GetPoster), arg0)
+}
+
+// GetRecommendations mocks base method.
+func (m *MockMovieQuerier) GetRecommendations(arg0 int64, arg1 int) ([]*data.Movie, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRecommendations", arg0, arg1)
+	ret0, _ := ret[0].([]*data.Movie)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRecommendations indicates an expected call of GetRecommendations.
+func (mr *MockMovieQuerierMockRecorder) GetRecommendations(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRecommendations", reflect.TypeOf((*MockMovieQuerier)(nil).GetRecommendations), arg0, arg1)
+}
+
+// GetRevisions mocks base method.
+func (m *MockMovieQuerier) GetRevisions(arg0 []int64) (map[int64][]*data.MovieRevision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRevisions", arg0)
+	ret0, _ := ret[0].(map[int64][]*data.MovieRevision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRevisions indicates an expected call of GetRevisions.
+func (mr *MockMovieQuerierMockRecorder) GetRevisions(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRevisions", reflect.TypeOf((*MockMovieQuerier)(nil).GetRevisions), arg0)
+}
+
+// GetSimilar mocks base method.
+func (m *MockMovieQuerier) GetSimilar(arg0 int64, arg1 int) ([]*data.Movie, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSimilar", arg0, arg1)
+	ret0, _ := ret[0].([]*data.Movie)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSimilar indicates an expected call of GetSimilar.
+func (mr *MockMovieQuerierMockRecorder) GetSimilar(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSimilar", reflect.TypeOf((*MockMovieQuerier)(nil).GetSimilar), arg0, arg1)
+}
+
+// GetStats mocks base method.
+func (m *MockMovieQuerier) GetStats() (*data.CatalogStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStats")
+	ret0, _ := ret[0].(*data.CatalogStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStats indicates an expected call of GetStats.
+func (mr *MockMovieQuerierMockRecorder) GetStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStats", reflect.TypeOf((*MockMovieQuerier)(nil).GetStats))
+}
+
+// GetTitles mocks base method.
+func (m *MockMovieQuerier) GetTitles(arg0 []int64) (map[int64][]*data.MovieTitle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTitles", arg0)
+	ret0, _ := ret[0].(map[int64][]*data.MovieTitle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTitles indicates an expected call of GetTitles.
+func (mr *MockMovieQuerierMockRecorder) GetTitles(arg0 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockMovieQuerier)(nil).GetAll), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTitles", reflect.TypeOf((*MockMovieQuerier)(nil).GetTitles), arg0)
+}
+
+// InTx mocks base method.
+func (m *MockMovieQuerier) InTx(arg0 func(data.MovieQuerier) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InTx", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InTx indicates an expected call of InTx.
+func (mr *MockMovieQuerierMockRecorder) InTx(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InTx", reflect.TypeOf((*MockMovieQuerier)(nil).InTx), arg0)
 }
 
 // Insert mocks base method.
@@ -97,6 +318,120 @@ func (mr *MockMovieQuerierMockRecorder) Insert(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockMovieQuerier)(nil).Insert), arg0)
 }
 
+// InsertMany mocks base method.
+func (m *MockMovieQuerier) InsertMany(arg0 []*data.Movie, arg1 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertMany", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertMany indicates an expected call of InsertMany.
+func (mr *MockMovieQuerierMockRecorder) InsertMany(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertMany", reflect.TypeOf((*MockMovieQuerier)(nil).InsertMany), arg0, arg1)
+}
+
+// InsertWithID mocks base method.
+func (m *MockMovieQuerier) InsertWithID(arg0 *data.Movie) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertWithID", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertWithID indicates an expected call of InsertWithID.
+func (mr *MockMovieQuerierMockRecorder) InsertWithID(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertWithID", reflect.TypeOf((*MockMovieQuerier)(nil).InsertWithID), arg0)
+}
+
+// Merge mocks base method.
+func (m *MockMovieQuerier) Merge(arg0, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Merge", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Merge indicates an expected call of Merge.
+func (mr *MockMovieQuerierMockRecorder) Merge(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Merge", reflect.TypeOf((*MockMovieQuerier)(nil).Merge), arg0, arg1)
+}
+
+// SetExternalID mocks base method.
+func (m *MockMovieQuerier) SetExternalID(arg0 *data.ExternalID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetExternalID", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetExternalID indicates an expected call of SetExternalID.
+func (mr *MockMovieQuerierMockRecorder) SetExternalID(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetExternalID", reflect.TypeOf((*MockMovieQuerier)(nil).SetExternalID), arg0)
+}
+
+// SetPoster mocks base method.
+func (m *MockMovieQuerier) SetPoster(arg0 *data.Poster) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPoster", arg0)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetPoster indicates an expected call of SetPoster.
+func (mr *MockMovieQuerierMockRecorder) SetPoster(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPoster", reflect.TypeOf((*MockMovieQuerier)(nil).SetPoster), arg0)
+}
+
+// SetTitle mocks base method.
+func (m *MockMovieQuerier) SetTitle(arg0 *data.MovieTitle) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTitle", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTitle indicates an expected call of SetTitle.
+func (mr *MockMovieQuerierMockRecorder) SetTitle(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTitle", reflect.TypeOf((*MockMovieQuerier)(nil).SetTitle), arg0)
+}
+
+// Stream mocks base method.
+func (m *MockMovieQuerier) Stream(arg0 data.MovieFilters, arg1 data.Filters, arg2 func(*data.Movie) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stream", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Stream indicates an expected call of Stream.
+func (mr *MockMovieQuerierMockRecorder) Stream(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stream", reflect.TypeOf((*MockMovieQuerier)(nil).Stream), arg0, arg1, arg2)
+}
+
+// Suggest mocks base method.
+func (m *MockMovieQuerier) Suggest(arg0 string, arg1 int) ([]*data.MovieSuggestion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Suggest", arg0, arg1)
+	ret0, _ := ret[0].([]*data.MovieSuggestion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Suggest indicates an expected call of Suggest.
+func (mr *MockMovieQuerierMockRecorder) Suggest(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Suggest", reflect.TypeOf((*MockMovieQuerier)(nil).Suggest), arg0, arg1)
+}
+
 // Update mocks base method.
 func (m *MockMovieQuerier) Update(arg0 *data.Movie) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,442 @@
+// Package msgpack converts between JSON and MessagePack. Values are encoded to
+// MessagePack by converting their JSON encoding, so the json struct tags and
+// Marshaler implementations used for JSON responses apply to MessagePack too.
+package msgpack
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+var ErrInvalid = errors.New("msgpack: invalid data")
+
+// Marshal returns the MessagePack encoding of the JSON encoding of v.
+func Marshal(v any) ([]byte, error) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromJSON(js)
+}
+
+// FromJSON converts a JSON document to MessagePack. Integers are encoded in the
+// smallest signed integer format that holds them, other numbers as float 64,
+// and the order of the object keys is kept.
+func FromJSON(js []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+
+	var buf bytes.Buffer
+
+	err := fromJSON(dec, &buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("msgpack: JSON contains more than one value")
+	}
+
+	return buf.Bytes(), nil
+}
+
+func fromJSON(dec *json.Decoder, buf *bytes.Buffer) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t := token.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+
+	case bool:
+		if t {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+
+	case json.Number:
+		writeNumber(buf, t)
+
+	case string:
+		writeString(buf, t)
+
+	case json.Delim:
+		// the number of elements goes before them, so they are encoded apart
+		var elements bytes.Buffer
+		n := 0
+
+		for dec.More() {
+			if t == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+
+				writeString(&elements, key.(string))
+			}
+
+			if err := fromJSON(dec, &elements); err != nil {
+				return err
+			}
+
+			n++
+		}
+
+		// the closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+
+		if t == '{' {
+			writeHeader(buf, n, 0x80, 0xde, 0xdf)
+		} else {
+			writeHeader(buf, n, 0x90, 0xdc, 0xdd)
+		}
+
+		buf.Write(elements.Bytes())
+	}
+
+	return nil
+}
+
+func writeNumber(buf *bytes.Buffer, n json.Number) {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		writeInt(buf, i)
+		return
+	}
+
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		buf.WriteByte(0xcf)
+		buf.Write(binary.BigEndian.AppendUint64(nil, u))
+		return
+	}
+
+	f, _ := n.Float64()
+	buf.WriteByte(0xcb)
+	buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+}
+
+func writeInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		buf.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		buf.Write([]byte{0xd0, byte(int8(i))})
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		buf.WriteByte(0xd1)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(int16(i))))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(int32(i))))
+	default:
+		buf.WriteByte(0xd3)
+		buf.Write(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	}
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	n := len(s)
+
+	switch {
+	case n < 32:
+		buf.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		buf.Write([]byte{0xd9, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xda)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		buf.WriteByte(0xdb)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+
+	buf.WriteString(s)
+}
+
+// writeHeader writes the header of an array or map of n elements, given the
+// prefixes of its fix, 16 and 32 bits formats.
+func writeHeader(buf *bytes.Buffer, n int, fix, b16, b32 byte) {
+	switch {
+	case n < 16:
+		buf.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(b16)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		buf.WriteByte(b32)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+}
+
+// ToJSON converts a MessagePack document to JSON. Map keys must be strings,
+// binary data is converted to a base64 string, like encoding/json does with
+// []byte, and extension types are not supported.
+func ToJSON(mp []byte) ([]byte, error) {
+	d := decoder{data: mp}
+
+	var buf bytes.Buffer
+
+	err := d.toJSON(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("%w: trailing data after the value", ErrInvalid)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// maxDepth is how deeply arrays and maps can be nested, the same limit
+// encoding/json has.
+const maxDepth = 10000
+
+type decoder struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+func (d *decoder) next(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrInvalid)
+	}
+
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+
+	return b, nil
+}
+
+func (d *decoder) uint(size int) (uint64, error) {
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+func (d *decoder) toJSON(buf *bytes.Buffer) error {
+	b, err := d.next(1)
+	if err != nil {
+		return err
+	}
+
+	c := b[0]
+
+	switch {
+	case c <= 0x7f:
+		buf.WriteString(strconv.Itoa(int(c)))
+		return nil
+	case c >= 0xe0:
+		buf.WriteString(strconv.Itoa(int(int8(c))))
+		return nil
+	case c&0xe0 == 0xa0:
+		return d.str(buf, int(c&0x1f))
+	case c&0xf0 == 0x90:
+		return d.array(buf, int(c&0x0f))
+	case c&0xf0 == 0x80:
+		return d.object(buf, int(c&0x0f))
+	}
+
+	switch c {
+	case 0xc0:
+		buf.WriteString("null")
+	case 0xc2:
+		buf.WriteString("false")
+	case 0xc3:
+		buf.WriteString("true")
+
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.uint(1 << (c - 0xcc))
+		if err != nil {
+			return err
+		}
+
+		buf.WriteString(strconv.FormatUint(u, 10))
+
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+
+		u, err := d.uint(size)
+		if err != nil {
+			return err
+		}
+
+		// sign extend from the size of the value
+		shift := 64 - 8*size
+		buf.WriteString(strconv.FormatInt(int64(u<<shift)>>shift, 10))
+
+	case 0xca:
+		u, err := d.uint(4)
+		if err != nil {
+			return err
+		}
+
+		return writeFloat(buf, float64(math.Float32frombits(uint32(u))))
+
+	case 0xcb:
+		u, err := d.uint(8)
+		if err != nil {
+			return err
+		}
+
+		return writeFloat(buf, math.Float64frombits(u))
+
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return err
+		}
+
+		return d.str(buf, int(n))
+
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (c - 0xc4))
+		if err != nil {
+			return err
+		}
+
+		bin, err := d.next(int(n))
+		if err != nil {
+			return err
+		}
+
+		js, _ := json.Marshal(base64.StdEncoding.EncodeToString(bin))
+		buf.Write(js)
+
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return err
+		}
+
+		return d.array(buf, int(n))
+
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return err
+		}
+
+		return d.object(buf, int(n))
+
+	default:
+		return fmt.Errorf("%w: unsupported format 0x%02x", ErrInvalid, c)
+	}
+
+	return nil
+}
+
+func (d *decoder) str(buf *bytes.Buffer, n int) error {
+	s, err := d.next(n)
+	if err != nil {
+		return err
+	}
+
+	js, err := json.Marshal(string(s))
+	if err != nil {
+		return err
+	}
+
+	buf.Write(js)
+	return nil
+}
+
+func (d *decoder) enter() error {
+	d.depth++
+	if d.depth > maxDepth {
+		return fmt.Errorf("%w: exceeded max depth", ErrInvalid)
+	}
+
+	return nil
+}
+
+func (d *decoder) array(buf *bytes.Buffer, n int) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer func() { d.depth-- }()
+
+	buf.WriteByte('[')
+
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		if err := d.toJSON(buf); err != nil {
+			return err
+		}
+	}
+
+	buf.WriteByte(']')
+	return nil
+}
+
+func (d *decoder) object(buf *bytes.Buffer, n int) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer func() { d.depth-- }()
+
+	buf.WriteByte('{')
+
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		// keys are checked to be strings by looking at the JSON written for them
+		start := buf.Len()
+		if err := d.toJSON(buf); err != nil {
+			return err
+		}
+
+		if buf.Bytes()[start] != '"' {
+			return fmt.Errorf("%w: map keys must be strings", ErrInvalid)
+		}
+
+		buf.WriteByte(':')
+
+		if err := d.toJSON(buf); err != nil {
+			return err
+		}
+	}
+
+	buf.WriteByte('}')
+	return nil
+}
+
+func writeFloat(buf *bytes.Buffer, f float64) error {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return fmt.Errorf("%w: %v can't be represented in JSON", ErrInvalid, f)
+	}
+
+	buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	return nil
+}
@@ -0,0 +1,77 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromJSON(t *testing.T) {
+	testCases := []struct {
+		name     string
+		json     string
+		expected []byte
+	}{
+		{"nil", `null`, []byte{0xc0}},
+		{"bool", `[true,false]`, []byte{0x92, 0xc3, 0xc2}},
+		{"positive fixint", `127`, []byte{0x7f}},
+		{"negative fixint", `-32`, []byte{0xe0}},
+		{"int 8", `-33`, []byte{0xd0, 0xdf}},
+		{"int 16", `1000`, []byte{0xd1, 0x03, 0xe8}},
+		{"int 32", `-100000`, []byte{0xd2, 0xff, 0xfe, 0x79, 0x60}},
+		{"uint 64", `18446744073709551615`, []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"float", `1.5`, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"fixstr", `"abc"`, []byte{0xa3, 'a', 'b', 'c'}},
+		{"fixmap keeps key order", `{"b":1,"a":[]}`, []byte{0x82, 0xa1, 'b', 0x01, 0xa1, 'a', 0x90}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mp, err := FromJSON([]byte(tc.json))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, mp)
+
+			js, err := ToJSON(mp)
+			require.NoError(t, err)
+			require.JSONEq(t, tc.json, string(js))
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	long := bytes.Repeat([]byte("x"), 70000)
+
+	js := []byte(`{"movie":{"id":9007199254740993,"title":"` + string(long) + `","genres":["drama","romance"],` +
+		`"runtime":"102 mins","score":-0.25,"nested":[[[{"ok":null}]]]}}`)
+
+	mp, err := FromJSON(js)
+	require.NoError(t, err)
+
+	back, err := ToJSON(mp)
+	require.NoError(t, err)
+	require.Equal(t, string(js), string(back))
+}
+
+func TestToJSONInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		mp   []byte
+	}{
+		{"empty", []byte{}},
+		{"truncated string", []byte{0xa3, 'a'}},
+		{"truncated array", []byte{0x92, 0x01}},
+		{"non string key", []byte{0x81, 0x01, 0x02}},
+		{"extension", []byte{0xd4, 0x01, 0x02}},
+		{"trailing data", []byte{0x01, 0x02}},
+		{"too deep", bytes.Repeat([]byte{0x91}, maxDepth+1)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ToJSON(tc.mp)
+			require.True(t, errors.Is(err, ErrInvalid), "got %v", err)
+		})
+	}
+}
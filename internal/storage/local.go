@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Local stores the files in a directory of the local filesystem, each key being
+// the path of its file relative to that directory.
+type Local struct {
+	dir string
+}
+
+var _ Storage = Local{}
+
+func NewLocal(dir string) Local {
+	return Local{dir: dir}
+}
+
+// path returns the path of the file of the key, refusing the keys that would
+// point outside of the directory.
+func (s Local) path(key string) (string, error) {
+	if key == "" || key == "." || path.IsAbs(key) || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+func (s Local) Put(key string, r io.Reader) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(name), 0o755)
+	if err != nil {
+		return err
+	}
+
+	// the file is written next to its final name and then renamed over it, as
+	// renaming is atomic
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	// a no-op once the file is renamed
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+func (s Local) Open(key string) (*File, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &File{ReadSeekCloser: f, ModTime: info.ModTime()}, nil
+}
+
+func (s Local) Delete(key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (s Local) DeleteAll(prefix string) error {
+	name, err := s.path(prefix)
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(name)
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal(t *testing.T) {
+	s := NewLocal(t.TempDir())
+
+	err := s.Put("posters/1/original", strings.NewReader("first"))
+	require.NoError(t, err)
+
+	err = s.Put("posters/1/original", strings.NewReader("second"))
+	require.NoError(t, err)
+
+	f, err := s.Open("posters/1/original")
+	require.NoError(t, err)
+
+	contents, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, "second", string(contents))
+	require.False(t, f.ModTime.IsZero())
+	require.NoError(t, f.Close())
+
+	err = s.Delete("posters/1/original")
+	require.NoError(t, err)
+
+	_, err = s.Open("posters/1/original")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// deleting a missing file is not an error
+	err = s.Delete("posters/1/original")
+	require.NoError(t, err)
+}
+
+func TestLocalDeleteAll(t *testing.T) {
+	s := NewLocal(t.TempDir())
+
+	for _, key := range []string{"posters/1/a/original", "posters/1/b/small", "posters/12/a/original"} {
+		err := s.Put(key, strings.NewReader("contents"))
+		require.NoError(t, err)
+	}
+
+	err := s.DeleteAll("posters/1")
+	require.NoError(t, err)
+
+	for _, key := range []string{"posters/1/a/original", "posters/1/b/small"} {
+		_, err = s.Open(key)
+		require.ErrorIs(t, err, ErrNotFound, key)
+	}
+
+	// only the keys under the prefix are deleted
+	f, err := s.Open("posters/12/a/original")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// deleting a missing prefix is not an error
+	err = s.DeleteAll("posters/1")
+	require.NoError(t, err)
+}
+
+func TestLocalInvalidKeys(t *testing.T) {
+	s := NewLocal(t.TempDir())
+
+	for _, key := range []string{"", "/etc/passwd", "../outside", "posters/../../outside", "posters//1", "..", "."} {
+		err := s.Put(key, strings.NewReader("contents"))
+		require.Error(t, err, key)
+
+		_, err = s.Open(key)
+		require.Error(t, err, key)
+		require.NotErrorIs(t, err, ErrNotFound, key)
+
+		err = s.DeleteAll(key)
+		require.Error(t, err, key)
+	}
+}
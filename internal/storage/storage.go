@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrNotFound = errors.New("file not found")
+
+// File is a stored file opened for reading. It must be closed once read.
+type File struct {
+	io.ReadSeekCloser
+	ModTime time.Time
+}
+
+// Storage keeps files under keys made of slash separated names, like
+// "posters/12/original".
+type Storage interface {
+	// Put stores the contents of r under the key, replacing the file stored
+	// under it before. Readers never see a partially written file.
+	Put(key string, r io.Reader) error
+	// Open returns the file stored under the key, or ErrNotFound.
+	Open(key string) (*File, error)
+	// Delete removes the file stored under the key. Missing files are ignored.
+	Delete(key string) error
+	// DeleteAll removes every file stored under a key that starts with the
+	// prefix followed by a slash, like "posters/12" for "posters/12/original".
+	DeleteAll(prefix string) error
+}
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS movies_titles_trgm_idx;
\ No newline at end of file
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS movie_revisions (
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    version integer NOT NULL,
+    title text NOT NULL,
+    year integer NOT NULL,
+    runtime integer NOT NULL,
+    genres text[] NOT NULL,
+    revised_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (movie_id, version)
+);
+
+-- every update keeps the previous version of the movie as a revision
+CREATE OR REPLACE FUNCTION record_movie_revision() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO movie_revisions (movie_id, version, title, year, runtime, genres)
+    VALUES (OLD.id, OLD.version, OLD.title, OLD.year, OLD.runtime, OLD.genres)
+    ON CONFLICT (movie_id, version) DO NOTHING;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER movies_record_revision
+AFTER UPDATE ON movies
+FOR EACH ROW
+WHEN (OLD.version IS DISTINCT FROM NEW.version)
+EXECUTE FUNCTION record_movie_revision();
\ No newline at end of file
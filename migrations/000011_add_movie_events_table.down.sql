@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS movies_record_event ON movies;
+DROP FUNCTION IF EXISTS record_movie_event();
+DROP TABLE IF EXISTS movie_events;
\ No newline at end of file
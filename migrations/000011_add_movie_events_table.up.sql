@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS movie_events (
+    id bigserial PRIMARY KEY,
+    type text NOT NULL,
+    movie_id bigint NOT NULL,
+    version integer NOT NULL,
+    title text NOT NULL,
+    year integer NOT NULL,
+    runtime integer NOT NULL,
+    genres text[] NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS movie_events_created_at_idx ON movie_events (created_at);
+
+-- every change to a movie is logged with the movie as it is after the change,
+-- or as it was when deleted, and the id of the event is sent to the listeners
+-- of the movie_events channel once the transaction commits
+CREATE OR REPLACE FUNCTION record_movie_event() RETURNS trigger AS $$
+DECLARE
+    movie movies%ROWTYPE;
+    event_type text;
+    event_id bigint;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        movie := OLD;
+        event_type := 'deleted';
+    ELSIF TG_OP = 'INSERT' THEN
+        movie := NEW;
+        event_type := 'created';
+    ELSE
+        movie := NEW;
+        event_type := 'updated';
+    END IF;
+
+    INSERT INTO movie_events (type, movie_id, version, title, year, runtime, genres)
+    VALUES (event_type, movie.id, movie.version, movie.title, movie.year, movie.runtime, movie.genres)
+    RETURNING id INTO event_id;
+
+    PERFORM pg_notify('movie_events', event_id::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER movies_record_event
+AFTER INSERT OR UPDATE OR DELETE ON movies
+FOR EACH ROW
+EXECUTE FUNCTION record_movie_event();
\ No newline at end of file
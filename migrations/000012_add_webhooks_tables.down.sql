@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS users_queue_webhook_deliveries ON users;
+DROP FUNCTION IF EXISTS queue_user_webhook_deliveries();
+DROP TRIGGER IF EXISTS movie_events_queue_webhook_deliveries ON movie_events;
+DROP FUNCTION IF EXISTS queue_movie_webhook_deliveries();
+DROP FUNCTION IF EXISTS queue_webhook_deliveries(text, jsonb);
+DELETE FROM permissions WHERE code = 'webhooks:manage';
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
\ No newline at end of file
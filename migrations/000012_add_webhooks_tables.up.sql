@@ -0,0 +1,80 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    url text NOT NULL,
+    events text[] NOT NULL,
+    secret text NOT NULL,
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id bigserial PRIMARY KEY,
+    webhook_id bigint NOT NULL REFERENCES webhooks ON DELETE CASCADE,
+    event text NOT NULL,
+    payload jsonb NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    last_status_code integer,
+    last_error text,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    delivered_at timestamp(0) with time zone
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, id);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+INSERT INTO permissions (code)
+VALUES ('webhooks:manage');
+
+-- the deliveries are queued in the same transaction as the change, for every
+-- webhook subscribed to the event. The payloads are built here, so they must
+-- be kept the same as the JSON of the movies and users sent by the API.
+CREATE OR REPLACE FUNCTION queue_webhook_deliveries(event text, data jsonb) RETURNS void AS $$
+BEGIN
+    INSERT INTO webhook_deliveries (webhook_id, event, payload)
+    SELECT id, event, jsonb_build_object('event', event, 'created_at', NOW(), 'data', data)
+    FROM webhooks
+    WHERE event = ANY(events);
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION queue_movie_webhook_deliveries() RETURNS trigger AS $$
+BEGIN
+    PERFORM queue_webhook_deliveries('movie.' || NEW.type, jsonb_build_object('movie', jsonb_build_object(
+        'id', NEW.movie_id,
+        'title', NEW.title,
+        'year', NEW.year,
+        'runtime', NEW.runtime || ' mins',
+        'genres', NEW.genres,
+        'version', NEW.version
+    )));
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER movie_events_queue_webhook_deliveries
+AFTER INSERT ON movie_events
+FOR EACH ROW
+EXECUTE FUNCTION queue_movie_webhook_deliveries();
+
+CREATE OR REPLACE FUNCTION queue_user_webhook_deliveries() RETURNS trigger AS $$
+BEGIN
+    PERFORM queue_webhook_deliveries('user.activated', jsonb_build_object('user', jsonb_build_object(
+        'id', NEW.id,
+        'created_at', NEW.created_at,
+        'name', NEW.name,
+        'email', NEW.email,
+        'activated', NEW.activated
+    )));
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_queue_webhook_deliveries
+AFTER UPDATE ON users
+FOR EACH ROW
+WHEN (NOT OLD.activated AND NEW.activated)
+EXECUTE FUNCTION queue_user_webhook_deliveries();
\ No newline at end of file
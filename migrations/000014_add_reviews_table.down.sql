@@ -0,0 +1,15 @@
+DROP TRIGGER IF EXISTS movies_record_update_event ON movies;
+DROP TRIGGER IF EXISTS movies_record_event ON movies;
+
+CREATE TRIGGER movies_record_event
+AFTER INSERT OR UPDATE OR DELETE ON movies
+FOR EACH ROW
+EXECUTE FUNCTION record_movie_event();
+
+DROP TABLE IF EXISTS reviews;
+DROP FUNCTION IF EXISTS update_movie_rating();
+
+DROP INDEX IF EXISTS movies_rating_idx;
+ALTER TABLE movies DROP COLUMN IF EXISTS rating;
+ALTER TABLE movies DROP COLUMN IF EXISTS rating_total;
+ALTER TABLE movies DROP COLUMN IF EXISTS votes;
\ No newline at end of file
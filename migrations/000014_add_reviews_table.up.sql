@@ -0,0 +1,62 @@
+ALTER TABLE movies ADD COLUMN votes integer NOT NULL DEFAULT 0;
+ALTER TABLE movies ADD COLUMN rating_total bigint NOT NULL DEFAULT 0;
+ALTER TABLE movies ADD COLUMN rating numeric(4, 2) NOT NULL
+    GENERATED ALWAYS AS (CASE WHEN votes = 0 THEN 0 ELSE round(rating_total::numeric / votes, 2) END) STORED;
+
+CREATE INDEX IF NOT EXISTS movies_rating_idx ON movies (rating);
+
+CREATE TABLE IF NOT EXISTS reviews (
+    id bigserial PRIMARY KEY,
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    score smallint NOT NULL CHECK (score BETWEEN 1 AND 10),
+    body text NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1,
+    UNIQUE (movie_id, user_id)
+);
+
+-- the votes and the total of the scores of a movie are kept by adding the
+-- difference each review makes. The update locks the row of the movie, so
+-- concurrent reviews of a movie are applied one after the other and the
+-- aggregates never miss a vote.
+CREATE OR REPLACE FUNCTION update_movie_rating() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE movies
+        SET votes = votes + 1, rating_total = rating_total + NEW.score
+        WHERE id = NEW.movie_id;
+    ELSIF TG_OP = 'UPDATE' THEN
+        UPDATE movies
+        SET rating_total = rating_total + NEW.score - OLD.score
+        WHERE id = NEW.movie_id;
+    ELSE
+        UPDATE movies
+        SET votes = votes - 1, rating_total = rating_total - OLD.score
+        WHERE id = OLD.movie_id;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER reviews_update_movie_rating
+AFTER INSERT OR DELETE OR UPDATE OF score ON reviews
+FOR EACH ROW
+EXECUTE FUNCTION update_movie_rating();
+
+-- the votes are not an edit of the movie, so only the updates that change its
+-- version are logged as events
+DROP TRIGGER IF EXISTS movies_record_event ON movies;
+
+CREATE TRIGGER movies_record_event
+AFTER INSERT OR DELETE ON movies
+FOR EACH ROW
+EXECUTE FUNCTION record_movie_event();
+
+CREATE TRIGGER movies_record_update_event
+AFTER UPDATE ON movies
+FOR EACH ROW
+WHEN (OLD.version IS DISTINCT FROM NEW.version)
+EXECUTE FUNCTION record_movie_event();
\ No newline at end of file
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS list_items_close_gap ON list_items;
+DROP FUNCTION IF EXISTS close_list_item_gap();
+DROP TABLE IF EXISTS list_items;
+DROP TABLE IF EXISTS lists;
+
+DELETE FROM permissions WHERE code = 'lists:write';
\ No newline at end of file
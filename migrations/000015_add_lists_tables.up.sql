@@ -0,0 +1,54 @@
+CREATE TABLE IF NOT EXISTS lists (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    name text NOT NULL,
+    description text NOT NULL DEFAULT '',
+    visibility text NOT NULL DEFAULT 'private' CHECK (visibility IN ('private', 'unlisted', 'public')),
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS lists_user_id_idx ON lists (user_id);
+
+-- the positions are kept contiguous, starting at 1. Moving an item shifts the
+-- ones in between in a single update, so the uniqueness is only checked when
+-- the transaction commits.
+CREATE TABLE IF NOT EXISTS list_items (
+    list_id bigint NOT NULL REFERENCES lists ON DELETE CASCADE,
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    position integer NOT NULL CHECK (position > 0),
+    watched boolean NOT NULL DEFAULT false,
+    watched_at timestamp(0) with time zone,
+    added_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (list_id, movie_id),
+    UNIQUE (list_id, position) DEFERRABLE INITIALLY DEFERRED
+);
+
+CREATE INDEX IF NOT EXISTS list_items_movie_id_idx ON list_items (movie_id);
+
+-- closes the gap an item leaves in its list, whether it was removed from the
+-- list or its movie was deleted
+CREATE OR REPLACE FUNCTION close_list_item_gap() RETURNS trigger AS $$
+BEGIN
+    UPDATE list_items
+    SET position = position - 1
+    WHERE list_id = OLD.list_id AND position > OLD.position;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER list_items_close_gap
+AFTER DELETE ON list_items
+FOR EACH ROW
+EXECUTE FUNCTION close_list_item_gap();
+
+INSERT INTO permissions (code)
+VALUES ('lists:write');
+
+-- the users that registered before lists existed get the permission too
+INSERT INTO users_permissions
+SELECT up.user_id, (SELECT id FROM permissions WHERE code = 'lists:write')
+FROM users_permissions up
+JOIN permissions p ON p.id = up.permission_id
+WHERE p.code = 'movies:read';
\ No newline at end of file
@@ -0,0 +1,7 @@
+DROP FUNCTION IF EXISTS normalize_genres(text[]);
+DROP FUNCTION IF EXISTS resolve_genres(text[]);
+DROP FUNCTION IF EXISTS resolve_genre(text);
+DROP FUNCTION IF EXISTS genre_key(text);
+DELETE FROM permissions WHERE code = 'genres:manage';
+DROP TABLE IF EXISTS genre_aliases;
+DROP TABLE IF EXISTS genres;
\ No newline at end of file
@@ -0,0 +1,73 @@
+CREATE TABLE IF NOT EXISTS genres (
+    slug text PRIMARY KEY,
+    name text NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS genre_aliases (
+    alias text PRIMARY KEY,
+    slug text NOT NULL REFERENCES genres ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS genre_aliases_slug_idx ON genre_aliases (slug);
+
+INSERT INTO permissions (code)
+VALUES ('genres:manage');
+
+-- the key of a genre name ignores case, spacing and punctuation, so 'Sci-Fi',
+-- 'sci fi' and 'SCI_FI' all have the key 'sci-fi'. Slugs and aliases are
+-- stored as keys.
+CREATE OR REPLACE FUNCTION genre_key(name text) RETURNS text AS $$
+    SELECT trim(both '-' FROM regexp_replace(lower(name), '[^[:alnum:]]+', '-', 'g'))
+$$ LANGUAGE sql IMMUTABLE;
+
+-- the slug of the genre the name is for, or NULL when it's not in the catalog
+CREATE OR REPLACE FUNCTION resolve_genre(name text) RETURNS text AS $$
+    SELECT coalesce(
+        (SELECT slug FROM genres WHERE slug = genre_key(name)),
+        (SELECT slug FROM genre_aliases WHERE alias = genre_key(name)))
+$$ LANGUAGE sql STABLE;
+
+-- resolves the genres given to filter the movies. The names not in the catalog
+-- are kept as keys, which match no movie.
+CREATE OR REPLACE FUNCTION resolve_genres(names text[]) RETURNS text[] AS $$
+    SELECT coalesce(array_agg(coalesce(resolve_genre(name), genre_key(name))), '{}')
+    FROM unnest(names) AS name
+$$ LANGUAGE sql STABLE;
+
+-- resolves the genres of a movie, keeping their order and dropping the ones
+-- that end up repeated
+CREATE OR REPLACE FUNCTION normalize_genres(names text[]) RETURNS text[] AS $$
+    SELECT array_agg(genre ORDER BY position)
+    FROM (
+        SELECT DISTINCT ON (genre) genre, position
+        FROM (
+            SELECT coalesce(resolve_genre(name), name) AS genre, position
+            FROM unnest(names) WITH ORDINALITY AS t (name, position)
+        ) resolved
+        ORDER BY genre, position
+    ) deduplicated
+$$ LANGUAGE sql STABLE;
+
+-- every spelling already in use becomes a genre of the catalog, named after its
+-- most used spelling
+INSERT INTO genres (slug, name)
+SELECT key, (array_agg(genre ORDER BY uses DESC, genre))[1]
+FROM (
+    SELECT genre_key(genre) AS key, genre, count(*) AS uses
+    FROM movies, unnest(genres) AS genre
+    WHERE genre_key(genre) <> ''
+    GROUP BY 1, 2
+) spellings
+GROUP BY key
+ON CONFLICT (slug) DO NOTHING;
+
+-- the version is kept, so this rewrite logs no revision or event and sends no
+-- webhook: the genres only change how they are spelled, once, as the catalog is
+-- created, and bumping the version would also make every pending batch update
+-- or PUT with the version of one of these movies fail with an edit conflict.
+-- The genre merges that come later change the genres of the movies, and bump
+-- their versions.
+UPDATE movies
+SET genres = normalize_genres(genres)
+WHERE genres IS DISTINCT FROM normalize_genres(genres);
\ No newline at end of file
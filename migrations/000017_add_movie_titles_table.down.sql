@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS movie_titles;
+DROP FUNCTION IF EXISTS title_search_config(text);
\ No newline at end of file
@@ -0,0 +1,39 @@
+-- the text search configuration that stems words in the language, or 'simple'
+-- for the languages PostgreSQL has no stemmer for
+CREATE OR REPLACE FUNCTION title_search_config(language text) RETURNS regconfig AS $$
+    SELECT CASE split_part(lower(language), '-', 1)
+        WHEN 'da' THEN 'danish'
+        WHEN 'de' THEN 'german'
+        WHEN 'en' THEN 'english'
+        WHEN 'es' THEN 'spanish'
+        WHEN 'fi' THEN 'finnish'
+        WHEN 'fr' THEN 'french'
+        WHEN 'hu' THEN 'hungarian'
+        WHEN 'it' THEN 'italian'
+        WHEN 'nl' THEN 'dutch'
+        WHEN 'no' THEN 'norwegian'
+        WHEN 'nb' THEN 'norwegian'
+        WHEN 'nn' THEN 'norwegian'
+        WHEN 'pt' THEN 'portuguese'
+        WHEN 'ro' THEN 'romanian'
+        WHEN 'ru' THEN 'russian'
+        WHEN 'sv' THEN 'swedish'
+        WHEN 'tr' THEN 'turkish'
+        ELSE 'simple'
+    END::regconfig
+$$ LANGUAGE sql IMMUTABLE;
+
+-- language is a BCP 47 tag, like 'pt-BR'. The search vector holds the words of
+-- the title both as they are and stemmed in its language, so that a search
+-- matches the exact words and their variations.
+CREATE TABLE IF NOT EXISTS movie_titles (
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    language text NOT NULL,
+    title text NOT NULL,
+    search tsvector GENERATED ALWAYS AS (
+        to_tsvector('simple', title) || to_tsvector(title_search_config(language), title)) STORED,
+    PRIMARY KEY (movie_id, language)
+);
+
+CREATE INDEX IF NOT EXISTS movie_titles_search_idx ON movie_titles USING GIN (search);
+CREATE INDEX IF NOT EXISTS movie_titles_trgm_idx ON movie_titles USING GIN (title gin_trgm_ops);
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- the files of the posters are kept in the storage of the API, this table only
+-- holds what is needed to serve them
+CREATE TABLE IF NOT EXISTS movie_posters (
+    movie_id bigint PRIMARY KEY REFERENCES movies ON DELETE CASCADE,
+    content_type text NOT NULL,
+    width integer NOT NULL,
+    height integer NOT NULL,
+    size bigint NOT NULL,
+    checksum text NOT NULL,
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
\ No newline at end of file
@@ -0,0 +1,20 @@
+-- an external ID belongs to a single movie, and a movie has at most one ID of
+-- each provider
+CREATE TABLE IF NOT EXISTS movie_external_ids (
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    provider text NOT NULL CHECK (provider IN ('imdb', 'tmdb')),
+    external_id text NOT NULL,
+    PRIMARY KEY (provider, external_id),
+    UNIQUE (movie_id, provider)
+);
+
+-- the normalized title ignores case, punctuation, spacing and a leading
+-- article, so 'The Matrix' and 'matrix' are the same title. Movies with the
+-- same normalized title and year are likely duplicates.
+CREATE OR REPLACE FUNCTION normalize_title(title text) RETURNS text AS $$
+    SELECT regexp_replace(
+        trim(regexp_replace(lower(title), '[^[:alnum:]]+', ' ', 'g')),
+        '^(the|a|an) ', '')
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE INDEX IF NOT EXISTS movies_normalized_title_idx ON movies (normalize_title(title), year);
\ No newline at end of file
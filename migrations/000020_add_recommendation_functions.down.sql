@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS reviews_user_id_idx;
+DROP FUNCTION IF EXISTS genre_jaccard(text[], text[]);
\ No newline at end of file
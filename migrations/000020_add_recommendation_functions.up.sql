@@ -0,0 +1,13 @@
+-- genre_jaccard is the share of the genres of two movies that they have in
+-- common: the size of the intersection of their genres over the size of their
+-- union. Movies without genres share none.
+CREATE OR REPLACE FUNCTION genre_jaccard(a text[], b text[]) RETURNS double precision AS $$
+    SELECT CASE WHEN n_all = 0 THEN 0 ELSE n_shared::double precision / n_all END
+    FROM (SELECT
+        (SELECT count(*) FROM (SELECT unnest(a) INTERSECT SELECT unnest(b)) shared_genres) AS n_shared,
+        (SELECT count(*) FROM (SELECT unnest(a) UNION SELECT unnest(b)) all_genres) AS n_all) counts
+$$ LANGUAGE sql IMMUTABLE;
+
+-- the co-ratings go from the reviews of a movie to the other reviews of their
+-- authors
+CREATE INDEX IF NOT EXISTS reviews_user_id_idx ON reviews (user_id);
\ No newline at end of file
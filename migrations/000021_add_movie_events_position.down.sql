@@ -0,0 +1,31 @@
+CREATE OR REPLACE FUNCTION record_movie_event() RETURNS trigger AS $$
+DECLARE
+    movie movies%ROWTYPE;
+    event_type text;
+    event_id bigint;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        movie := OLD;
+        event_type := 'deleted';
+    ELSIF TG_OP = 'INSERT' THEN
+        movie := NEW;
+        event_type := 'created';
+    ELSE
+        movie := NEW;
+        event_type := 'updated';
+    END IF;
+
+    INSERT INTO movie_events (type, movie_id, version, title, year, runtime, genres)
+    VALUES (event_type, movie.id, movie.version, movie.title, movie.year, movie.runtime, movie.genres)
+    RETURNING id INTO event_id;
+
+    PERFORM pg_notify('movie_events', event_id::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP FUNCTION IF EXISTS sequence_movie_events();
+DROP INDEX IF EXISTS movie_events_position_idx;
+ALTER TABLE movie_events DROP COLUMN IF EXISTS position;
+DROP SEQUENCE IF EXISTS movie_events_position_seq;
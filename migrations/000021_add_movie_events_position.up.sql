@@ -0,0 +1,58 @@
+-- the ids of the events follow the order they were logged in, not the one
+-- their transactions committed in, so an event can commit after another with a
+-- greater id. The events are given a position once they are committed, in the
+-- order they become visible, and are read back by it.
+CREATE SEQUENCE IF NOT EXISTS movie_events_position_seq;
+
+ALTER TABLE movie_events ADD COLUMN IF NOT EXISTS position bigint;
+
+CREATE UNIQUE INDEX IF NOT EXISTS movie_events_position_idx ON movie_events (position);
+
+-- positions the committed events that have none yet. The lock makes the calls
+-- run one after the other, so the positions are committed in order too.
+CREATE OR REPLACE FUNCTION sequence_movie_events() RETURNS bigint AS $$
+DECLARE
+    event_id bigint;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('sequence_movie_events'));
+
+    FOR event_id IN
+        SELECT id FROM movie_events WHERE position IS NULL ORDER BY id
+    LOOP
+        UPDATE movie_events
+        SET position = nextval('movie_events_position_seq')
+        WHERE id = event_id;
+    END LOOP;
+
+    RETURN (SELECT CASE WHEN is_called THEN last_value ELSE 0 END FROM movie_events_position_seq);
+END;
+$$ LANGUAGE plpgsql;
+
+SELECT sequence_movie_events();
+
+-- the notifications with the same payload are sent once per transaction, so a
+-- transaction that changes many movies, like an import, notifies only once
+CREATE OR REPLACE FUNCTION record_movie_event() RETURNS trigger AS $$
+DECLARE
+    movie movies%ROWTYPE;
+    event_type text;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        movie := OLD;
+        event_type := 'deleted';
+    ELSIF TG_OP = 'INSERT' THEN
+        movie := NEW;
+        event_type := 'created';
+    ELSE
+        movie := NEW;
+        event_type := 'updated';
+    END IF;
+
+    INSERT INTO movie_events (type, movie_id, version, title, year, runtime, genres)
+    VALUES (event_type, movie.id, movie.version, movie.title, movie.year, movie.runtime, movie.genres);
+
+    PERFORM pg_notify('movie_events', '');
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
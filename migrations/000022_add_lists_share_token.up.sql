@@ -0,0 +1,8 @@
+-- the ids of the lists are sequential, so an unlisted list is only seen by
+-- those with its share token. The lists that were already unlisted get one
+-- here, and the new ones get it from the API.
+ALTER TABLE lists ADD COLUMN IF NOT EXISTS share_token text UNIQUE;
+
+UPDATE lists
+SET share_token = replace(gen_random_uuid()::text, '-', '')
+WHERE visibility = 'unlisted';
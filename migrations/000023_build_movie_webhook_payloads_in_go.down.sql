@@ -0,0 +1,49 @@
+CREATE OR REPLACE FUNCTION queue_movie_webhook_deliveries() RETURNS trigger AS $$
+BEGIN
+    PERFORM queue_webhook_deliveries('movie.' || NEW.type, jsonb_build_object('movie', jsonb_build_object(
+        'id', NEW.movie_id,
+        'title', NEW.title,
+        'year', NEW.year,
+        'runtime', NEW.runtime || ' mins',
+        'genres', NEW.genres,
+        'version', NEW.version
+    )));
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- the deliveries that were never sent have no payload to go back to
+DELETE FROM webhook_deliveries WHERE payload IS NULL;
+
+DROP INDEX IF EXISTS webhook_deliveries_movie_event_id_idx;
+ALTER TABLE webhook_deliveries ALTER COLUMN payload SET NOT NULL;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS movie_event_id;
+
+CREATE OR REPLACE FUNCTION record_movie_event() RETURNS trigger AS $$
+DECLARE
+    movie movies%ROWTYPE;
+    event_type text;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        movie := OLD;
+        event_type := 'deleted';
+    ELSIF TG_OP = 'INSERT' THEN
+        movie := NEW;
+        event_type := 'created';
+    ELSE
+        movie := NEW;
+        event_type := 'updated';
+    END IF;
+
+    INSERT INTO movie_events (type, movie_id, version, title, year, runtime, genres)
+    VALUES (event_type, movie.id, movie.version, movie.title, movie.year, movie.runtime, movie.genres);
+
+    PERFORM pg_notify('movie_events', '');
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE movie_events DROP COLUMN IF EXISTS votes;
+ALTER TABLE movie_events DROP COLUMN IF EXISTS rating;
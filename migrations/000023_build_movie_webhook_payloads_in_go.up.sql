@@ -0,0 +1,48 @@
+-- the events keep the rating and votes of the movie too, so they have every
+-- field the API sends
+ALTER TABLE movie_events ADD COLUMN IF NOT EXISTS rating numeric(4, 2) NOT NULL DEFAULT 0;
+ALTER TABLE movie_events ADD COLUMN IF NOT EXISTS votes integer NOT NULL DEFAULT 0;
+
+CREATE OR REPLACE FUNCTION record_movie_event() RETURNS trigger AS $$
+DECLARE
+    movie movies%ROWTYPE;
+    event_type text;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        movie := OLD;
+        event_type := 'deleted';
+    ELSIF TG_OP = 'INSERT' THEN
+        movie := NEW;
+        event_type := 'created';
+    ELSE
+        movie := NEW;
+        event_type := 'updated';
+    END IF;
+
+    INSERT INTO movie_events (type, movie_id, version, title, year, runtime, genres, rating, votes)
+    VALUES (event_type, movie.id, movie.version, movie.title, movie.year, movie.runtime, movie.genres, movie.rating, movie.votes);
+
+    PERFORM pg_notify('movie_events', '');
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- the deliveries of the movie events only point to the event, and the API
+-- builds their payloads from it when they are first sent, with the movie as
+-- it sends it everywhere else
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS movie_event_id bigint REFERENCES movie_events ON DELETE SET NULL;
+ALTER TABLE webhook_deliveries ALTER COLUMN payload DROP NOT NULL;
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_movie_event_id_idx ON webhook_deliveries (movie_event_id);
+
+CREATE OR REPLACE FUNCTION queue_movie_webhook_deliveries() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO webhook_deliveries (webhook_id, event, movie_event_id)
+    SELECT id, 'movie.' || NEW.type, NEW.id
+    FROM webhooks
+    WHERE 'movie.' || NEW.type = ANY(events);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -36,6 +36,7 @@ PGPASSWORD=$DB_PASSWORD psql --host=localhost --port=$DB_PORT --username=$DB_USE
 >&2 echo "creating extensions"
 PGPASSWORD=$DB_PASSWORD psql --host=localhost --port=$DB_PORT --username=$DB_USERNAME -d $DB_NAME \
                              --command="CREATE EXTENSION IF NOT EXISTS citext"                    \
+                             --command="CREATE EXTENSION IF NOT EXISTS pg_trgm"                   \
 
 >&2 echo "creating default user"
 read -p "Enter password for greenlight DB user: " DB_USER_PASSWORD
@@ -56,6 +56,7 @@ PGPASSWORD=$DB_PASSWORD psql --host=localhost -q                               \
                              --username=$DB_USERNAME                           \
                              -d $DB_NAME                                       \
                              --command="CREATE EXTENSION IF NOT EXISTS citext" \
+                             --command="CREATE EXTENSION IF NOT EXISTS pg_trgm" \
                              --quiet
 
 